@@ -40,7 +40,7 @@ func main() {
 	}
 	if false {
 		fmt.Println("Start------------")
-		fmt.Println(web3.Miner.Start(context.Background()))
+		fmt.Println(web3.Miner.Start(context.Background(), nil))
 		fmt.Println("GetHashrate------------")
 		fmt.Println(web3.Miner.GetHashrate(context.Background()))
 	}
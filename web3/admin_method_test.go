@@ -0,0 +1,88 @@
+package web3_test
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+func TestAdminDevNode(t *testing.T) {
+	node := web3test.New(t)
+	w := node.Web3
+	ctx := context.Background()
+	node.SendTx(t, &node.Account, nil, nil)
+	node.Commit(t)
+
+	info, err := w.Admin.NodeInfo(ctx)
+	if err != nil {
+		t.Fatalf("NodeInfo: %v", err)
+	}
+	if info.Enode == "" || info.ID != node.Stack.Server().NodeInfo().ID {
+		t.Errorf("NodeInfo = %+v", info)
+	}
+	peers, err := w.Admin.Peers(ctx)
+	if err != nil || len(peers) != 0 {
+		t.Errorf("Peers = %v %v, want none", peers, err)
+	}
+	datadir, err := w.Admin.Datadir(ctx)
+	if err != nil || datadir != node.Stack.DataDir() {
+		t.Errorf("Datadir = %q %v, want %q", datadir, err, node.Stack.DataDir())
+	}
+
+	// a local peer that does not exist, the node never reaches it
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer := enode.NewV4(&key.PublicKey, net.IPv4(127, 0, 0, 1), 30399, 30399).URLv4()
+	for name, call := range map[string]func(context.Context, string) (bool, error){
+		"AddPeer":           w.Admin.AddPeer,
+		"AddTrustedPeer":    w.Admin.AddTrustedPeer,
+		"RemoveTrustedPeer": w.Admin.RemoveTrustedPeer,
+		"RemovePeer":        w.Admin.RemovePeer,
+	} {
+		if ok, err := call(ctx, peer); err != nil || !ok {
+			t.Errorf("%s = %v %v", name, ok, err)
+		}
+		if _, err := call(ctx, "enode://invalid"); err == nil {
+			t.Errorf("%s accepted an invalid enode", name)
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "chain.rlp")
+	if ok, err := w.Admin.ExportChain(ctx, file, nil, nil); err != nil || !ok {
+		t.Fatalf("ExportChain = %v %v", ok, err)
+	}
+	if _, err := w.Admin.ExportChain(ctx, file, nil, nil); err == nil {
+		t.Error("ExportChain overwrote an existing file")
+	}
+	if ok, err := w.Admin.ImportChain(ctx, file); err != nil || !ok {
+		t.Errorf("ImportChain = %v %v", ok, err)
+	}
+
+	host, port := "127.0.0.1", 0
+	if ok, err := w.Admin.StartHTTP(ctx, &host, &port, nil, nil, nil); err != nil || !ok {
+		t.Fatalf("StartHTTP = %v %v", ok, err)
+	}
+	if ok, err := w.Admin.StopHTTP(ctx); err != nil || !ok {
+		t.Errorf("StopHTTP = %v %v", ok, err)
+	}
+	if ok, err := w.Admin.StartRPC(ctx, &host, &port, nil, nil, nil); err != nil || !ok {
+		t.Fatalf("StartRPC = %v %v", ok, err)
+	}
+	if ok, err := w.Admin.StopRPC(ctx); err != nil || !ok {
+		t.Errorf("StopRPC = %v %v", ok, err)
+	}
+	if ok, err := w.Admin.StartWS(ctx, &host, &port, nil, nil); err != nil || !ok {
+		t.Fatalf("StartWS = %v %v", ok, err)
+	}
+	if ok, err := w.Admin.StopWS(ctx); err != nil || !ok {
+		t.Errorf("StopWS = %v %v", ok, err)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 
 	"github.com/ethereum/go-ethereum/p2p"
-	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // clearHistory
@@ -12,15 +11,22 @@ import (
 // sleepBlocks
 
 type Admin struct {
-	c *rpc.Client
+	c    Client
+	gate *capabilityGate
 }
 
-func NewAdmin(c *rpc.Client) *Admin {
+func NewAdmin(c Client) *Admin {
 	admin := &Admin{}
-	admin.c = c
+	admin.c = withCallErrors(c)
 	return admin
 }
 
+// Available reports whether the node serves the admin namespace. It is true
+// until Web3.Capabilities has probed the node.
+func (admin *Admin) Available() bool {
+	return admin.gate.available("admin")
+}
+
 // NodeInfo retrieves all the information we know about the host node at the
 // protocol granularity.
 // from adminAPI
@@ -0,0 +1,104 @@
+package web3
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// SetBalance sets the balance of an account.
+// from anvil
+// method
+func (a *Anvil) SetBalance(ctx context.Context, address common.Address, balance *hexutil.Big) error {
+	err := a.c.CallContext(ctx, nil, "anvil_setBalance", address, balance)
+	return err
+}
+
+// SetCode sets the code of an account.
+// from anvil
+// method
+func (a *Anvil) SetCode(ctx context.Context, address common.Address, code hexutil.Bytes) error {
+	err := a.c.CallContext(ctx, nil, "anvil_setCode", address, code)
+	return err
+}
+
+// SetStorageAt writes a single storage slot of an account.
+// from anvil
+// method
+func (a *Anvil) SetStorageAt(ctx context.Context, address common.Address, slot common.Hash, value common.Hash) (bool, error) {
+	var result bool
+	err := a.c.CallContext(ctx, &result, "anvil_setStorageAt", address, (*hexutil.Big)(slot.Big()), value)
+	return result, err
+}
+
+// SetNonce sets the nonce of an account.
+// from anvil
+// method
+func (a *Anvil) SetNonce(ctx context.Context, address common.Address, nonce hexutil.Uint64) error {
+	err := a.c.CallContext(ctx, nil, "anvil_setNonce", address, nonce)
+	return err
+}
+
+// ImpersonateAccount lets eth_sendTransaction send transactions from the
+// address without its key, until StopImpersonatingAccount is called.
+// from anvil
+// method
+func (a *Anvil) ImpersonateAccount(ctx context.Context, address common.Address) error {
+	err := a.c.CallContext(ctx, nil, "anvil_impersonateAccount", address)
+	return err
+}
+
+// StopImpersonatingAccount ends the impersonation started by ImpersonateAccount.
+// from anvil
+// method
+func (a *Anvil) StopImpersonatingAccount(ctx context.Context, address common.Address) error {
+	err := a.c.CallContext(ctx, nil, "anvil_stopImpersonatingAccount", address)
+	return err
+}
+
+// ForkConfig selects the chain and block a dev node forks from.
+type ForkConfig struct {
+	JSONRPCURL  string  `json:"jsonRpcUrl,omitempty"`
+	BlockNumber *uint64 `json:"blockNumber,omitempty"`
+}
+
+// ResetParams are the parameters of anvil_reset and hardhat_reset.
+type ResetParams struct {
+	Forking *ForkConfig `json:"forking,omitempty"`
+}
+
+// Reset resets the chain. With a nil fork the node starts a fresh local chain,
+// otherwise it forks the given chain again, from the latest block if
+// fork.BlockNumber is nil and from the fork URL of the node if fork.JSONRPCURL
+// is empty.
+// from anvil
+// method
+func (a *Anvil) Reset(ctx context.Context, fork *ForkConfig) error {
+	var err error
+	if fork == nil {
+		err = a.c.CallContext(ctx, nil, "anvil_reset")
+	} else {
+		err = a.c.CallContext(ctx, nil, "anvil_reset", ResetParams{Forking: fork})
+	}
+	return err
+}
+
+// DumpState returns the whole chain state as an opaque, gzip compressed blob
+// that LoadState accepts.
+// from anvil
+// method
+func (a *Anvil) DumpState(ctx context.Context) (hexutil.Bytes, error) {
+	var result hexutil.Bytes
+	err := a.c.CallContext(ctx, &result, "anvil_dumpState")
+	return result, err
+}
+
+// LoadState merges a state returned by DumpState into the chain.
+// from anvil
+// method
+func (a *Anvil) LoadState(ctx context.Context, state hexutil.Bytes) (bool, error) {
+	var result bool
+	err := a.c.CallContext(ctx, &result, "anvil_loadState", state)
+	return result, err
+}
@@ -0,0 +1,290 @@
+package web3_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+// devChain is the state a stub Anvil keeps: a few balances and the calls it
+// received.
+type devChain struct {
+	mu        sync.Mutex
+	balances  map[common.Address]*big.Int
+	snapshots []map[common.Address]*big.Int
+	offset    uint64
+	mined     []*hexutil.Uint64
+	calls     map[string]json.RawMessage
+}
+
+func (c *devChain) copyBalances() map[common.Address]*big.Int {
+	copied := make(map[common.Address]*big.Int, len(c.balances))
+	for addr, balance := range c.balances {
+		copied[addr] = new(big.Int).Set(balance)
+	}
+	return copied
+}
+
+func (c *devChain) call(method string, params ...interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls[method], _ = json.Marshal(params)
+}
+
+type evmService struct{ chain *devChain }
+
+func (s *evmService) Snapshot() hexutil.Uint64 {
+	s.chain.mu.Lock()
+	defer s.chain.mu.Unlock()
+	s.chain.snapshots = append(s.chain.snapshots, s.chain.copyBalances())
+	return hexutil.Uint64(len(s.chain.snapshots))
+}
+
+func (s *evmService) Revert(id hexutil.Uint64) bool {
+	s.chain.mu.Lock()
+	defer s.chain.mu.Unlock()
+	if id == 0 || int(id) > len(s.chain.snapshots) {
+		return false
+	}
+	s.chain.balances = s.chain.snapshots[id-1]
+	s.chain.snapshots = s.chain.snapshots[:id-1]
+	return true
+}
+
+func (s *evmService) Mine(timestamp *hexutil.Uint64) string {
+	s.chain.mu.Lock()
+	defer s.chain.mu.Unlock()
+	s.chain.mined = append(s.chain.mined, timestamp)
+	return "0x0"
+}
+
+// IncreaseTime answers with a plain number, like Hardhat does.
+func (s *evmService) IncreaseTime(seconds hexutil.Uint64) uint64 {
+	s.chain.mu.Lock()
+	defer s.chain.mu.Unlock()
+	s.chain.offset += uint64(seconds)
+	return s.chain.offset
+}
+
+type anvilService struct{ chain *devChain }
+
+func (s *anvilService) SetBalance(addr common.Address, balance *hexutil.Big) {
+	s.chain.mu.Lock()
+	defer s.chain.mu.Unlock()
+	s.chain.balances[addr] = balance.ToInt()
+}
+
+func (s *anvilService) SetCode(addr common.Address, code hexutil.Bytes) {
+	s.chain.call("anvil_setCode", addr, code)
+}
+
+func (s *anvilService) SetStorageAt(addr common.Address, slot string, value common.Hash) bool {
+	s.chain.call("anvil_setStorageAt", addr, slot, value)
+	return true
+}
+
+func (s *anvilService) SetNonce(addr common.Address, nonce hexutil.Uint64) {
+	s.chain.call("anvil_setNonce", addr, nonce)
+}
+
+func (s *anvilService) ImpersonateAccount(addr common.Address) {
+	s.chain.call("anvil_impersonateAccount", addr)
+}
+
+func (s *anvilService) StopImpersonatingAccount(addr common.Address) {
+	s.chain.call("anvil_stopImpersonatingAccount", addr)
+}
+
+func (s *anvilService) Reset(params *json.RawMessage) {
+	s.chain.call("anvil_reset", params)
+}
+
+func (s *anvilService) DumpState() (hexutil.Bytes, error) {
+	s.chain.mu.Lock()
+	defer s.chain.mu.Unlock()
+	return json.Marshal(s.chain.balances)
+}
+
+func (s *anvilService) LoadState(state hexutil.Bytes) (bool, error) {
+	s.chain.mu.Lock()
+	defer s.chain.mu.Unlock()
+	return true, json.Unmarshal(state, &s.chain.balances)
+}
+
+func newStubAnvil(t *testing.T) (*web3.Web3, *devChain) {
+	t.Helper()
+	chain := &devChain{balances: make(map[common.Address]*big.Int), calls: make(map[string]json.RawMessage)}
+	server := rpc.NewServer()
+	if err := server.RegisterName("evm", &evmService{chain}); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("anvil", &anvilService{chain}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	client := rpc.DialInProc(server)
+	t.Cleanup(client.Close)
+	return web3.NewWeb3(client), chain
+}
+
+func TestAnvilCheatCodes(t *testing.T) {
+	w, chain := newStubAnvil(t)
+	ctx := context.Background()
+	addr := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+	if err := w.Anvil.SetBalance(ctx, addr, (*hexutil.Big)(big.NewInt(100))); err != nil {
+		t.Fatalf("SetBalance: %v", err)
+	}
+	if chain.balances[addr].Int64() != 100 {
+		t.Errorf("balance = %v, want 100", chain.balances[addr])
+	}
+	if err := w.Anvil.SetCode(ctx, addr, hexutil.Bytes{0x60, 0x00}); err != nil {
+		t.Errorf("SetCode: %v", err)
+	}
+	if ok, err := w.Anvil.SetStorageAt(ctx, addr, common.HexToHash("0x01"), common.HexToHash("0x2a")); err != nil || !ok {
+		t.Errorf("SetStorageAt = %v %v", ok, err)
+	}
+	if err := w.Anvil.SetNonce(ctx, addr, 7); err != nil {
+		t.Errorf("SetNonce: %v", err)
+	}
+	if err := w.Anvil.ImpersonateAccount(ctx, addr); err != nil {
+		t.Errorf("ImpersonateAccount: %v", err)
+	}
+	if err := w.Anvil.StopImpersonatingAccount(ctx, addr); err != nil {
+		t.Errorf("StopImpersonatingAccount: %v", err)
+	}
+	block := uint64(19_000_000)
+	if err := w.Anvil.Reset(ctx, &web3.ForkConfig{JSONRPCURL: "https://eth.example", BlockNumber: &block}); err != nil {
+		t.Errorf("Reset: %v", err)
+	}
+	// storage slots are quantities without leading zeroes, as Hardhat requires
+	for method, want := range map[string]string{
+		"anvil_setCode":                  `["0x00000000000000000000000000000000000000aa","0x6000"]`,
+		"anvil_setStorageAt":             `["0x00000000000000000000000000000000000000aa","0x1","0x000000000000000000000000000000000000000000000000000000000000002a"]`,
+		"anvil_setNonce":                 `["0x00000000000000000000000000000000000000aa","0x7"]`,
+		"anvil_impersonateAccount":       `["0x00000000000000000000000000000000000000aa"]`,
+		"anvil_stopImpersonatingAccount": `["0x00000000000000000000000000000000000000aa"]`,
+		"anvil_reset":                    `[{"forking":{"jsonRpcUrl":"https://eth.example","blockNumber":19000000}}]`,
+	} {
+		if got := string(chain.calls[method]); got != want {
+			t.Errorf("%s params = %s, want %s", method, got, want)
+		}
+	}
+	if err := w.Anvil.Reset(ctx, nil); err != nil || string(chain.calls["anvil_reset"]) != "[null]" {
+		t.Errorf("Reset(nil) = %v, params %s", err, chain.calls["anvil_reset"])
+	}
+
+	state, err := w.Anvil.DumpState(ctx)
+	if err != nil {
+		t.Fatalf("DumpState: %v", err)
+	}
+	chain.balances = make(map[common.Address]*big.Int)
+	if ok, err := w.Anvil.LoadState(ctx, state); err != nil || !ok || chain.balances[addr].Int64() != 100 {
+		t.Errorf("LoadState = %v %v, balance %v", ok, err, chain.balances[addr])
+	}
+
+	timestamp := hexutil.Uint64(1_700_000_000)
+	if err := w.Evm.Mine(ctx, nil); err != nil {
+		t.Errorf("Mine: %v", err)
+	}
+	if err := w.Evm.Mine(ctx, &timestamp); err != nil {
+		t.Errorf("Mine at timestamp: %v", err)
+	}
+	if len(chain.mined) != 2 || chain.mined[0] != nil || *chain.mined[1] != timestamp {
+		t.Errorf("mined = %v", chain.mined)
+	}
+	if _, err := w.Evm.IncreaseTime(ctx, 60); err != nil {
+		t.Fatalf("IncreaseTime: %v", err)
+	}
+	if total, err := w.Evm.IncreaseTime(ctx, 30); err != nil || total != 90 {
+		t.Errorf("IncreaseTime = %d %v, want 90", total, err)
+	}
+}
+
+func TestEvmWithSnapshot(t *testing.T) {
+	w, chain := newStubAnvil(t)
+	ctx := context.Background()
+	addr := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	if err := w.Anvil.SetBalance(ctx, addr, (*hexutil.Big)(big.NewInt(1))); err != nil {
+		t.Fatal(err)
+	}
+	spend := func() {
+		if err := w.Anvil.SetBalance(ctx, addr, (*hexutil.Big)(big.NewInt(0))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	errFailed := errors.New("failed")
+	err := w.Evm.WithSnapshot(ctx, func() error {
+		spend()
+		return errFailed
+	})
+	if err != errFailed || chain.balances[addr].Int64() != 1 {
+		t.Errorf("WithSnapshot = %v, balance %v, want the error of fn and a reverted balance", err, chain.balances[addr])
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("WithSnapshot swallowed a panic")
+			}
+		}()
+		w.Evm.WithSnapshot(ctx, func() error {
+			spend()
+			panic("boom")
+		})
+	}()
+	if chain.balances[addr].Int64() != 1 || len(chain.snapshots) != 0 {
+		t.Errorf("balance after panic = %v, %d snapshots left", chain.balances[addr], len(chain.snapshots))
+	}
+
+	web3test.WithSnapshot(t, w, spend)
+	if chain.balances[addr].Int64() != 1 {
+		t.Errorf("balance after web3test.WithSnapshot = %v, want 1", chain.balances[addr])
+	}
+}
+
+// paramsClient records the parameters of every request without sending it.
+type paramsClient struct {
+	params [][]interface{}
+}
+
+func (c *paramsClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	c.params = append(c.params, args)
+	return nil
+}
+
+func (c *paramsClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	return errors.New("batches not supported")
+}
+
+func TestHardhatMineParams(t *testing.T) {
+	c := &paramsClient{}
+	h := web3.NewHardhat(c)
+	ctx := context.Background()
+	blocks, interval := hexutil.Uint64(10), hexutil.Uint64(12)
+	for _, args := range [][2]*hexutil.Uint64{{nil, nil}, {&blocks, nil}, {nil, &interval}, {&blocks, &interval}} {
+		if err := h.Mine(ctx, args[0], args[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []string
+	for _, params := range c.params {
+		encoded, _ := json.Marshal(append([]interface{}{}, params...))
+		got = append(got, string(encoded))
+	}
+	want := []string{`[]`, `["0xa"]`, `[null,"0xc"]`, `["0xa","0xc"]`}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("hardhat_mine params = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,18 @@
+package web3
+
+type Anvil struct {
+	c    Client
+	gate *capabilityGate
+}
+
+func NewAnvil(c Client) *Anvil {
+	e := &Anvil{}
+	e.c = withCallErrors(c)
+	return e
+}
+
+// Available reports whether the node serves the anvil namespace. It is true
+// until Web3.Capabilities has probed the node.
+func (a *Anvil) Available() bool {
+	return a.gate.available("anvil")
+}
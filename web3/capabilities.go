@@ -0,0 +1,245 @@
+package web3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrNamespaceUnavailable is returned, before anything is sent, by the methods
+// of a namespace that Web3.Capabilities found to be unavailable on the node.
+var ErrNamespaceUnavailable = errors.New("namespace unavailable")
+
+// NamespaceError reports a request that was not sent because its namespace is
+// unavailable. It matches ErrNamespaceUnavailable with errors.Is.
+type NamespaceError struct {
+	Namespace string
+	Reason    error
+}
+
+func (e *NamespaceError) Error() string {
+	if e.Reason == nil {
+		return fmt.Sprintf("%s: %v", e.Namespace, ErrNamespaceUnavailable)
+	}
+	return fmt.Sprintf("%s: %v: %v", e.Namespace, ErrNamespaceUnavailable, e.Reason)
+}
+
+func (e *NamespaceError) Unwrap() error {
+	return ErrNamespaceUnavailable
+}
+
+// namespaceProbe is a cheap, side effect free request used to check that a
+// namespace is actually served. Namespaces without one are judged by
+// rpc_modules alone.
+type namespaceProbe struct {
+	method string
+	args   []interface{}
+}
+
+var namespaceProbes = map[string]*namespaceProbe{
+	"admin":    {method: "admin_datadir"},
+	"anvil":    {method: "anvil_nodeInfo"},
+	"clique":   {method: "clique_proposals"},
+	"debug":    {method: "debug_getRawHeader", args: []interface{}{rpc.LatestBlockNumber}},
+	"eth":      {method: "eth_chainId"},
+	"evm":      nil,
+	"hardhat":  {method: "hardhat_metadata"},
+	"miner":    nil,
+	"net":      {method: "net_version"},
+	"ots":      {method: "ots_getApiLevel"},
+	"personal": {method: "personal_listAccounts"},
+	"rpc":      {method: "rpc_modules"},
+	"trace":    nil,
+	"txpool":   {method: "txpool_status"},
+}
+
+// Capabilities describes what a node can serve.
+type Capabilities struct {
+	ClientVersion string
+	// Modules is the result of rpc_modules, nil if the node does not serve it.
+	Modules map[string]string
+	// Namespaces reports for every namespace known to Web3 whether it can be
+	// used. Unavailable namespaces have their reason in Errors.
+	Namespaces map[string]bool
+	Errors     map[string]error
+}
+
+// Has reports whether the namespace is available.
+func (c *Capabilities) Has(namespace string) bool {
+	return c.Namespaces[namespace]
+}
+
+// capabilityGate holds the capabilities of a node once probed and rejects
+// requests to namespaces it knows to be unavailable.
+type capabilityGate struct {
+	mu   sync.RWMutex
+	caps *Capabilities
+}
+
+func newCapabilityGate() *capabilityGate {
+	return &capabilityGate{}
+}
+
+// check returns a *NamespaceError for methods of unavailable namespaces.
+// Everything is allowed until the node has been probed.
+func (g *capabilityGate) check(method string) error {
+	namespace, _, ok := strings.Cut(method, "_")
+	if !ok {
+		return nil
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.caps == nil {
+		return nil
+	}
+	available, known := g.caps.Namespaces[namespace]
+	if !known || available {
+		return nil
+	}
+	return &NamespaceError{Namespace: namespace, Reason: g.caps.Errors[namespace]}
+}
+
+// available reports whether a namespace may be used. A nil gate or a node
+// that has not been probed yet counts as available.
+func (g *capabilityGate) available(namespace string) bool {
+	if g == nil {
+		return true
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.caps == nil {
+		return true
+	}
+	available, known := g.caps.Namespaces[namespace]
+	return !known || available
+}
+
+func (g *capabilityGate) set(caps *Capabilities) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.caps = caps
+}
+
+// gatedClient refuses requests to namespaces its gate knows to be unavailable.
+type gatedClient struct {
+	Client
+	gate *capabilityGate
+}
+
+func (c *gatedClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if err := c.gate.check(method); err != nil {
+		return err
+	}
+	return c.Client.CallContext(ctx, result, method, args...)
+}
+
+func (c *gatedClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	var pass []rpc.BatchElem
+	var index []int
+	for i := range b {
+		if err := c.gate.check(b[i].Method); err != nil {
+			b[i].Error = err
+			continue
+		}
+		pass = append(pass, b[i])
+		index = append(index, i)
+	}
+	if len(pass) == 0 {
+		return nil
+	}
+	err := c.Client.BatchCallContext(ctx, pass)
+	for j, i := range index {
+		b[i].Error = pass[j].Error
+	}
+	return err
+}
+
+// isUnavailable reports whether a probe error means the namespace is not
+// served, as opposed to a failure of the request itself.
+func isUnavailable(err error) bool {
+	if errors.Is(ClassifyError(err), ErrMethodNotFound) {
+		return true
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusForbidden || httpErr.StatusCode == http.StatusUnauthorized
+	}
+	return false
+}
+
+// callEach sends the elements of b one at a time, for providers that reject
+// batches. It stops at the first error that did not come from the node.
+func callEach(ctx context.Context, c Client, b []rpc.BatchElem) error {
+	for i := range b {
+		err := c.CallContext(ctx, b[i].Result, b[i].Method, b[i].Args...)
+		var (
+			rpcErr  rpc.Error
+			httpErr rpc.HTTPError
+		)
+		if err != nil && !errors.As(err, &rpcErr) && !errors.As(err, &httpErr) {
+			return err
+		}
+		b[i].Error = err
+	}
+	return nil
+}
+
+// Capabilities probes rpc_modules, web3_clientVersion and a cheap method of
+// every namespace, then makes the namespaces of w report the result through
+// Available and fail fast with ErrNamespaceUnavailable where the node would
+// reject the request. The probes are batched, or sent one by one if the node
+// rejects the batch.
+func (w *Web3) Capabilities(ctx context.Context) (*Capabilities, error) {
+	caps := &Capabilities{
+		Namespaces: make(map[string]bool, len(namespaceProbes)),
+		Errors:     make(map[string]error),
+	}
+	batch := []rpc.BatchElem{
+		{Method: "rpc_modules", Result: &caps.Modules},
+		{Method: "web3_clientVersion", Result: &caps.ClientVersion},
+	}
+	var namespaces []string
+	for namespace, probe := range namespaceProbes {
+		if probe == nil {
+			continue
+		}
+		namespaces = append(namespaces, namespace)
+		batch = append(batch, rpc.BatchElem{Method: probe.method, Args: probe.args, Result: new(interface{})})
+	}
+	if err := w.c.BatchCallContext(ctx, batch); err != nil {
+		if err := callEach(ctx, w.c, batch); err != nil {
+			return nil, err
+		}
+	}
+	if batch[0].Error != nil {
+		caps.Modules = nil
+	}
+	probeErrs := make(map[string]error, len(namespaces))
+	for i, namespace := range namespaces {
+		probeErrs[namespace] = batch[2+i].Error
+	}
+	for namespace, probe := range namespaceProbes {
+		_, listed := caps.Modules[namespace]
+		switch {
+		case probe != nil && probeErrs[namespace] != nil && isUnavailable(probeErrs[namespace]):
+			caps.Namespaces[namespace] = false
+			caps.Errors[namespace] = probeErrs[namespace]
+		case probe != nil && probeErrs[namespace] == nil:
+			caps.Namespaces[namespace] = true
+		case caps.Modules != nil && !listed:
+			caps.Namespaces[namespace] = false
+			caps.Errors[namespace] = fmt.Errorf("not listed in rpc_modules")
+		default:
+			// the probe failed for another reason, or there is no probe and no
+			// module list to go by: give the namespace the benefit of the doubt
+			caps.Namespaces[namespace] = true
+		}
+	}
+	w.gate.set(caps)
+	return caps, nil
+}
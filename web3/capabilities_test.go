@@ -0,0 +1,83 @@
+package web3_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+)
+
+type clientVersionService struct{}
+
+func (clientVersionService) ClientVersion() string { return "stub/v1.0.0" }
+
+// noBatchClient rejects batches like some hosted providers do.
+type noBatchClient struct {
+	*rpc.Client
+	batches int
+}
+
+func (c *noBatchClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	c.batches++
+	return rpc.HTTPError{StatusCode: 400, Status: "400 Bad Request", Body: []byte("batch requests are not supported")}
+}
+
+// newCapabilitiesNode starts a stub node serving eth, web3 and rpc only.
+func newCapabilitiesNode(t *testing.T) *rpc.Server {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &endpointService{id: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("web3", clientVersionService{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	return server
+}
+
+func TestCapabilities(t *testing.T) {
+	server := newCapabilitiesNode(t)
+	ctx := context.Background()
+
+	batched := web3.NewWeb3(rpc.DialInProc(server))
+	want, err := batched.Capabilities(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want.ClientVersion != "stub/v1.0.0" || want.Modules["eth"] == "" {
+		t.Errorf("capabilities = %q %v", want.ClientVersion, want.Modules)
+	}
+	for namespace, available := range want.Namespaces {
+		if wantAvailable := namespace == "eth" || namespace == "rpc"; available != wantAvailable {
+			t.Errorf("%s available = %v: %v", namespace, available, want.Errors[namespace])
+		}
+	}
+
+	// the probes are sent one by one when the batch is rejected
+	c := &noBatchClient{Client: rpc.DialInProc(server)}
+	defer c.Close()
+	w := web3.NewWeb3(c)
+	caps, err := w.Capabilities(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.batches != 1 {
+		t.Errorf("%d batches sent", c.batches)
+	}
+	if caps.ClientVersion != want.ClientVersion || !reflect.DeepEqual(caps.Modules, want.Modules) || !reflect.DeepEqual(caps.Namespaces, want.Namespaces) {
+		t.Errorf("capabilities without batches = %+v, want %+v", caps, want)
+	}
+	if _, err := w.Admin.Datadir(ctx); !errors.Is(err, web3.ErrNamespaceUnavailable) {
+		t.Errorf("Datadir error = %v, want %v", err, web3.ErrNamespaceUnavailable)
+	}
+
+	// a node that cannot be reached is an error, not a missing namespace
+	server.Stop()
+	if _, err := w.Capabilities(ctx); err == nil {
+		t.Error("Capabilities of a stopped node succeeded")
+	}
+}
@@ -0,0 +1,220 @@
+package web3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrOffchainLookup is returned when an EIP-3668 offchain lookup fails.
+var ErrOffchainLookup = errors.New("offchain lookup failed")
+
+// offchainLookupSelector is the selector of the EIP-3668 error
+// OffchainLookup(address,string[],bytes,bytes4,bytes).
+var offchainLookupSelector = []byte{0x55, 0x6f, 0x18, 0x30}
+
+// maxOffchainLookups is the number of nested lookups CallOffchain follows.
+const maxOffchainLookups = 4
+
+// maxOffchainResponse bounds the size of a gateway response.
+const maxOffchainResponse = 4 << 20
+
+var (
+	offchainLookupArgs   abi.Arguments
+	offchainCallbackArgs abi.Arguments
+)
+
+func init() {
+	arg := func(t string) abi.Argument {
+		typ, err := abi.NewType(t, "", nil)
+		if err != nil {
+			panic(err)
+		}
+		return abi.Argument{Type: typ}
+	}
+	offchainLookupArgs = abi.Arguments{arg("address"), arg("string[]"), arg("bytes"), arg("bytes4"), arg("bytes")}
+	offchainCallbackArgs = abi.Arguments{arg("bytes"), arg("bytes")}
+}
+
+// OffchainLookup is the EIP-3668 revert of a contract that asks its caller to
+// fetch data from a gateway and call back with it.
+type OffchainLookup struct {
+	Sender           common.Address
+	URLs             []string
+	CallData         []byte
+	CallbackFunction [4]byte
+	ExtraData        []byte
+}
+
+// DecodeOffchainLookup decodes the revert data of a call. It is nil if the
+// call did not revert with OffchainLookup.
+func DecodeOffchainLookup(revert []byte) (*OffchainLookup, error) {
+	if len(revert) < 4 || !bytes.Equal(revert[:4], offchainLookupSelector) {
+		return nil, nil
+	}
+	values, err := offchainLookupArgs.Unpack(revert[4:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOffchainLookup, err)
+	}
+	return &OffchainLookup{
+		Sender:           values[0].(common.Address),
+		URLs:             values[1].([]string),
+		CallData:         values[2].([]byte),
+		CallbackFunction: values[3].([4]byte),
+		ExtraData:        values[4].([]byte),
+	}, nil
+}
+
+// OffchainFetcher performs the gateway requests of offchain lookups.
+// *http.Client implements it, tests can stub it with OffchainFetcherFunc.
+type OffchainFetcher interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// OffchainFetcherFunc adapts a function to an OffchainFetcher.
+type OffchainFetcherFunc func(req *http.Request) (*http.Response, error)
+
+func (f OffchainFetcherFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Fetch asks the gateways of the lookup in turn, until one answers, and
+// returns its response. URLs with a {data} parameter are requested with GET,
+// the others with a JSON POST. A 4xx status stops the lookup, other failures
+// move on to the next gateway. A nil fetcher is http.DefaultClient.
+func (l *OffchainLookup) Fetch(ctx context.Context, fetcher OffchainFetcher) ([]byte, error) {
+	if fetcher == nil {
+		fetcher = http.DefaultClient
+	}
+	sender := strings.ToLower(l.Sender.Hex())
+	data := hexutil.Encode(l.CallData)
+	var failures []string
+	for _, url := range l.URLs {
+		url = strings.ReplaceAll(url, "{sender}", sender)
+		var req *http.Request
+		var err error
+		if strings.Contains(url, "{data}") {
+			req, err = http.NewRequestWithContext(ctx, http.MethodGet, strings.ReplaceAll(url, "{data}", data), nil)
+		} else {
+			body, _ := json.Marshal(map[string]string{"data": data, "sender": sender})
+			req, err = http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+			if err == nil {
+				req.Header.Set("Content-Type", "application/json")
+			}
+		}
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		status, result, err := l.request(fetcher, req)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", req.URL.Redacted(), err))
+		if status >= 400 && status < 500 {
+			break
+		}
+	}
+	if len(failures) == 0 {
+		return nil, fmt.Errorf("%w: no gateway", ErrOffchainLookup)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrOffchainLookup, strings.Join(failures, "; "))
+}
+
+func (l *OffchainLookup) request(fetcher OffchainFetcher, req *http.Request) (int, []byte, error) {
+	resp, err := fetcher.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOffchainResponse))
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var gatewayErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &gatewayErr) == nil && gatewayErr.Message != "" {
+			return resp.StatusCode, nil, fmt.Errorf("status %d: %s", resp.StatusCode, gatewayErr.Message)
+		}
+		return resp.StatusCode, nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	var result struct {
+		Data *hexutil.Bytes `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("invalid response: %v", err)
+	}
+	if result.Data == nil {
+		return resp.StatusCode, nil, errors.New("response without data")
+	}
+	return resp.StatusCode, *result.Data, nil
+}
+
+// callbackInput returns the input of the callback call for a gateway
+// response.
+func (l *OffchainLookup) callbackInput(response []byte) (hexutil.Bytes, error) {
+	encoded, err := offchainCallbackArgs.Pack(response, l.ExtraData)
+	if err != nil {
+		return nil, err
+	}
+	return append(l.CallbackFunction[:], encoded...), nil
+}
+
+// CallOffchain is Call following the EIP-3668 offchain lookups of the called
+// contract: while it reverts with OffchainLookup, the gateways are asked with
+// fetcher and the contract is called back with their response. A nil fetcher
+// is http.DefaultClient.
+func (e *Eth) CallOffchain(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, fetcher OffchainFetcher) (hexutil.Bytes, error) {
+	if args.To == nil {
+		return e.Call(ctx, args, blockNrOrHash, nil, nil)
+	}
+	to := *args.To
+	for lookups := 0; ; lookups++ {
+		result, err := e.Call(ctx, args, blockNrOrHash, nil, nil)
+		if err == nil {
+			return result, nil
+		}
+		revert, ok := RevertData(err)
+		if !ok {
+			return nil, err
+		}
+		lookup, lookupErr := DecodeOffchainLookup(revert)
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+		if lookup == nil {
+			return nil, err
+		}
+		if lookup.Sender != to {
+			return nil, fmt.Errorf("%w: sender %s is not the called contract %s", ErrOffchainLookup, lookup.Sender.Hex(), to.Hex())
+		}
+		if lookups == maxOffchainLookups {
+			return nil, fmt.Errorf("%w: more than %d lookups", ErrOffchainLookup, maxOffchainLookups)
+		}
+		response, err := lookup.Fetch(ctx, fetcher)
+		if err != nil {
+			return nil, err
+		}
+		input, err := lookup.callbackInput(response)
+		if err != nil {
+			return nil, err
+		}
+		args.Input = &input
+		args.Data = nil
+	}
+}
@@ -0,0 +1,58 @@
+package web3_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/moonfdd/web3-go/web3"
+)
+
+func TestOffchainLookupFetch(t *testing.T) {
+	ctx := context.Background()
+	lookup := &web3.OffchainLookup{
+		Sender:   common.HexToAddress("0x00000000000000000000000000000000000000Ab"),
+		URLs:     []string{"https://a.example/{sender}/{data}", "https://b.example/", "https://c.example/"},
+		CallData: []byte{0x12, 0x34},
+	}
+	var urls []string
+	reply := func(status int, body string) web3.OffchainFetcher {
+		return web3.OffchainFetcherFunc(func(req *http.Request) (*http.Response, error) {
+			urls = append(urls, req.Method+" "+req.URL.String())
+			recorder := httptest.NewRecorder()
+			if req.URL.Host == "a.example" {
+				recorder.WriteHeader(http.StatusServiceUnavailable)
+			} else {
+				recorder.WriteHeader(status)
+				recorder.WriteString(body)
+			}
+			return recorder.Result(), nil
+		})
+	}
+
+	data, err := lookup.Fetch(ctx, reply(http.StatusOK, `{"data":"0xabcd"}`))
+	if err != nil || common.Bytes2Hex(data) != "abcd" {
+		t.Errorf("Fetch = %x %v", data, err)
+	}
+	if want := "GET https://a.example/0x00000000000000000000000000000000000000ab/0x1234"; len(urls) != 2 || urls[0] != want {
+		t.Errorf("requests = %v, want %s first", urls, want)
+	}
+
+	// a client error stops the lookup
+	urls = nil
+	if _, err := lookup.Fetch(ctx, reply(http.StatusNotFound, `{"message":"unknown name"}`)); !errors.Is(err, web3.ErrOffchainLookup) || len(urls) != 2 {
+		t.Errorf("Fetch with a client error = %v after %v", err, urls)
+	}
+	urls = nil
+	if _, err := lookup.Fetch(ctx, reply(http.StatusOK, `{}`)); !errors.Is(err, web3.ErrOffchainLookup) || len(urls) != 3 {
+		t.Errorf("Fetch without data = %v after %v", err, urls)
+	}
+
+	if decoded, err := web3.DecodeOffchainLookup([]byte{1, 2, 3, 4}); decoded != nil || err != nil {
+		t.Errorf("DecodeOffchainLookup of another revert = %v %v, want nil", decoded, err)
+	}
+}
@@ -0,0 +1,119 @@
+package web3
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// List returns the accounts the user allowed to be listed.
+// from SignerAPI
+// from clef
+// method
+func (c *Clef) List(ctx context.Context) ([]common.Address, error) {
+	var result []common.Address
+	err := c.c.CallContext(ctx, &result, "account_list")
+	return result, err
+}
+
+// New creates a password protected account in the keystore of Clef.
+// from SignerAPI
+// from clef
+// method
+func (c *Clef) New(ctx context.Context) (common.Address, error) {
+	var result common.Address
+	err := c.c.CallContext(ctx, &result, "account_new")
+	return result, err
+}
+
+// SignTransaction signs a transaction once the user approved it. Clef does not
+// fill in missing fields: From, Gas, Nonce, Value and the fees must be set, as
+// Eth.WithSigner does. Blob transactions are not supported.
+// from SignerAPI
+// from clef
+// method
+func (c *Clef) SignTransaction(ctx context.Context, args TransactionArgs) (*SignTransactionResult, error) {
+	send, err := clefTxArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	var result *SignTransactionResult
+	err = c.c.CallContext(ctx, &result, "account_signTransaction", send)
+	return result, err
+}
+
+// clefTxArgs converts args to the arguments of account_signTransaction, with
+// checksummed addresses: Clef warns about the others and refuses to sign
+// unless it runs with --advanced.
+func clefTxArgs(args TransactionArgs) (*apitypes.SendTxArgs, error) {
+	if args.From == nil || args.Gas == nil || args.Nonce == nil || args.Value == nil {
+		return nil, errors.New("clef needs the from, gas, nonce and value of the transaction")
+	}
+	if len(args.BlobHashes) > 0 || len(args.Blobs) > 0 {
+		return nil, errors.New("clef cannot sign blob transactions")
+	}
+	send := &apitypes.SendTxArgs{
+		From:                 common.NewMixedcaseAddress(*args.From),
+		Gas:                  *args.Gas,
+		GasPrice:             args.GasPrice,
+		MaxFeePerGas:         args.MaxFeePerGas,
+		MaxPriorityFeePerGas: args.MaxPriorityFeePerGas,
+		Value:                *args.Value,
+		Nonce:                *args.Nonce,
+		Data:                 args.Data,
+		Input:                args.Input,
+		AccessList:           args.AccessList,
+		ChainID:              args.ChainID,
+	}
+	if args.To != nil {
+		to := common.NewMixedcaseAddress(*args.To)
+		send.To = &to
+	}
+	return send, nil
+}
+
+// SignData signs data of the given content type, one of the
+// accounts.Mimetype* constants, once the user approved it. Text is signed
+// with the personal_sign prefix.
+// from SignerAPI
+// from clef
+// method
+func (c *Clef) SignData(ctx context.Context, contentType string, address common.Address, data interface{}) (hexutil.Bytes, error) {
+	var result hexutil.Bytes
+	err := c.c.CallContext(ctx, &result, "account_signData", contentType, common.NewMixedcaseAddress(address), data)
+	return result, err
+}
+
+// SignTypedData signs EIP-712 typed data once the user approved it.
+// from SignerAPI
+// from clef
+// method
+func (c *Clef) SignTypedData(ctx context.Context, address common.Address, typedData *TypedData) (hexutil.Bytes, error) {
+	var result hexutil.Bytes
+	err := c.c.CallContext(ctx, &result, "account_signTypedData", common.NewMixedcaseAddress(address), typedData)
+	return result, err
+}
+
+// EcRecover returns the address that signed data with the personal_sign
+// prefix.
+// from SignerAPI
+// from clef
+// method
+func (c *Clef) EcRecover(ctx context.Context, data hexutil.Bytes, sig hexutil.Bytes) (common.Address, error) {
+	var result common.Address
+	err := c.c.CallContext(ctx, &result, "account_ecRecover", data, sig)
+	return result, err
+}
+
+// Version returns the version of the external API of Clef.
+// from SignerAPI
+// from clef
+// property
+func (c *Clef) Version(ctx context.Context) (string, error) {
+	var result string
+	err := c.c.CallContext(ctx, &result, "account_version")
+	return result, err
+}
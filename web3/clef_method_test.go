@@ -0,0 +1,172 @@
+package web3_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"github.com/moonfdd/web3-go/web3"
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+// clefService is a stub Clef approving every request with a single key.
+type clefService struct {
+	key *ecdsa.PrivateKey
+
+	mu       sync.Mutex
+	requests []apitypes.SendTxArgs
+}
+
+func (s *clefService) List() []common.Address {
+	return []common.Address{crypto.PubkeyToAddress(s.key.PublicKey)}
+}
+
+func (s *clefService) New() common.Address {
+	return common.HexToAddress("0x00000000000000000000000000000000000000cc")
+}
+
+func (s *clefService) Version() string { return "6.0.0" }
+
+func (s *clefService) SignTransaction(args apitypes.SendTxArgs, methodSelector *string) (map[string]interface{}, error) {
+	if !args.From.ValidChecksum() || (args.To != nil && !args.To.ValidChecksum()) {
+		return nil, errors.New("invalid checksum")
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, args)
+	s.mu.Unlock()
+	tx, err := types.SignTx(args.ToTransaction(), types.LatestSignerForChainID(args.ChainID.ToInt()), s.key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"raw": hexutil.Bytes(raw), "tx": tx}, nil
+}
+
+func (s *clefService) SignData(contentType string, addr common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error) {
+	if contentType != accounts.MimetypeTextPlain || !addr.ValidChecksum() {
+		return nil, errors.New("unsupported request")
+	}
+	sig, err := crypto.Sign(accounts.TextHash(data), s.key)
+	if err != nil {
+		return nil, err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig, nil
+}
+
+func (s *clefService) EcRecover(data, sig hexutil.Bytes) (common.Address, error) {
+	sig = common.CopyBytes(sig)
+	sig[crypto.RecoveryIDOffset] -= 27
+	pub, err := crypto.SigToPub(accounts.TextHash(data), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+func newStubClef(t *testing.T) (*web3.Clef, *clefService) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := &clefService{key: key}
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", service); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	client := rpc.DialInProc(server)
+	t.Cleanup(client.Close)
+	return web3.NewClef(client), service
+}
+
+func TestClef(t *testing.T) {
+	clef, service := newStubClef(t)
+	ctx := context.Background()
+	addr := crypto.PubkeyToAddress(service.key.PublicKey)
+
+	if version, err := clef.Version(ctx); err != nil || version != "6.0.0" {
+		t.Errorf("Version = %q %v", version, err)
+	}
+	if list, err := clef.List(ctx); err != nil || len(list) != 1 || list[0] != addr {
+		t.Errorf("List = %v %v, want [%v]", list, err, addr)
+	}
+	if created, err := clef.New(ctx); err != nil || created != common.HexToAddress("0xcc") {
+		t.Errorf("New = %v %v", created, err)
+	}
+	message := hexutil.Bytes("hello clef")
+	sig, err := clef.SignData(ctx, accounts.MimetypeTextPlain, addr, message)
+	if err != nil {
+		t.Fatalf("SignData: %v", err)
+	}
+	if signer, err := clef.EcRecover(ctx, message, sig); err != nil || signer != addr {
+		t.Errorf("EcRecover = %v %v, want %v", signer, err, addr)
+	}
+	if _, err := clef.SignTransaction(ctx, web3.TransactionArgs{From: &addr}); err == nil {
+		t.Error("SignTransaction of incomplete arguments succeeded")
+	}
+}
+
+func TestEthWithClefDevNode(t *testing.T) {
+	clef, service := newStubClef(t)
+	addr := crypto.PubkeyToAddress(service.key.PublicKey)
+	funds := new(big.Int).Mul(big.NewInt(10), big.NewInt(params.Ether))
+	node := web3test.New(t, web3test.WithAlloc(types.GenesisAlloc{addr: {Balance: funds}}))
+	ctx := context.Background()
+	to := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	args := web3.TransactionArgs{From: &addr, To: &to, Value: (*hexutil.Big)(big.NewInt(params.Ether))}
+
+	if _, err := node.Web3.Eth.SendTransaction(ctx, args); err == nil {
+		t.Fatal("the node signed for an account it does not have")
+	}
+	eth := node.Web3.Eth.WithSigner(clef)
+	if node.Web3.Eth.Signer() != nil || eth.Signer() != clef {
+		t.Fatal("WithSigner changed the original namespace")
+	}
+	signed, err := eth.SignTransaction(ctx, args)
+	if err != nil {
+		t.Fatalf("SignTransaction: %v", err)
+	}
+	if sender, err := types.Sender(types.LatestSignerForChainID(signed.Tx.ChainId()), signed.Tx); err != nil || sender != addr {
+		t.Errorf("SignTransaction sender = %v %v, want %v", sender, err, addr)
+	}
+	hash, err := eth.SendTransaction(ctx, args)
+	if err != nil {
+		t.Fatalf("SendTransaction: %v", err)
+	}
+	if hash != signed.Tx.Hash() {
+		t.Errorf("SendTransaction = %v, want %v", hash, signed.Tx.Hash())
+	}
+	request := service.requests[len(service.requests)-1]
+	if request.Nonce != 0 || request.Gas != hexutil.Uint64(params.TxGas) || request.ChainID.ToInt().Uint64() != params.AllDevChainProtocolChanges.ChainID.Uint64() {
+		t.Errorf("filled request = nonce %d gas %d chain %v", request.Nonce, request.Gas, request.ChainID)
+	}
+	if request.MaxFeePerGas == nil || request.MaxPriorityFeePerGas == nil || request.GasPrice != nil {
+		t.Errorf("filled request fees = cap %v tip %v price %v, want dynamic fees", request.MaxFeePerGas, request.MaxPriorityFeePerGas, request.GasPrice)
+	}
+
+	number := node.Commit(t)
+	block, err := node.Web3.Eth.GetBlockByNumber(ctx, rpc.BlockNumber(number), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txs, _ := block["transactions"].([]interface{}); len(txs) != 1 || txs[0] != hash.Hex() {
+		t.Errorf("block %d transactions = %v, want [%v]", number, block["transactions"], hash)
+	}
+}
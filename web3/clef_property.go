@@ -0,0 +1,41 @@
+package web3
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Clef is a client of the account namespace of the Clef external signer.
+// Clef runs apart from the node, so it is not part of Web3: dial it with
+// DialClef and hand it to Eth.WithSigner to sign transactions through it.
+type Clef struct {
+	c     Client
+	close func()
+}
+
+func NewClef(c Client) *Clef {
+	e := &Clef{}
+	e.c = withCallErrors(c)
+	return e
+}
+
+// DialClef connects to Clef at endpoint, the path of its IPC socket or the URL
+// of its HTTP server.
+func DialClef(ctx context.Context, endpoint string) (*Clef, error) {
+	c, err := rpc.DialContext(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	e := NewClef(c)
+	e.close = c.Close
+	return e, nil
+}
+
+// Close closes the connection opened by DialClef. It does nothing on a Clef
+// created with NewClef.
+func (c *Clef) Close() {
+	if c.close != nil {
+		c.close()
+	}
+}
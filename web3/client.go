@@ -0,0 +1,17 @@
+package web3
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Client is the part of *rpc.Client the namespaces send their requests
+// through. Any value implementing it, such as a *MultiClient, can be handed to
+// NewWeb3 or to the individual namespace constructors.
+type Client interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+	BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
+}
+
+var _ Client = (*rpc.Client)(nil)
@@ -0,0 +1,211 @@
+package web3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ProposalStatus is the state of a clique authorization proposal as seen in a
+// snapshot.
+type ProposalStatus struct {
+	Address   common.Address
+	Authorize bool
+	Block     uint64 // block number of the snapshot
+
+	// Valid is false if the proposal would not change the signer set, e.g.
+	// authorizing an address that already is a signer. Clique ignores such
+	// votes.
+	Valid bool
+
+	Signers  int              // number of authorized signers
+	Required int              // votes the proposal needs to pass
+	Votes    int              // votes cast for the proposal so far
+	Needed   int              // votes still missing
+	Voted    []common.Address // signers that already voted for the proposal
+	Pending  []common.Address // signers that have not voted for it yet
+}
+
+// NewProposalStatus computes how far a proposal got from a clique snapshot. A
+// proposal passes once more than half of the signers voted for it.
+func NewProposalStatus(snap *clique.Snapshot, address common.Address, authorize bool) *ProposalStatus {
+	_, signer := snap.Signers[address]
+	status := &ProposalStatus{
+		Address:   address,
+		Authorize: authorize,
+		Block:     snap.Number,
+		Valid:     (signer && !authorize) || (!signer && authorize),
+		Signers:   len(snap.Signers),
+		Required:  len(snap.Signers)/2 + 1,
+	}
+	if tally, ok := snap.Tally[address]; ok && tally.Authorize == authorize {
+		status.Votes = tally.Votes
+	}
+	if status.Valid && status.Votes < status.Required {
+		status.Needed = status.Required - status.Votes
+	}
+	voted := make(map[common.Address]bool)
+	for _, vote := range snap.Votes {
+		if vote.Address == address && vote.Authorize == authorize {
+			voted[vote.Signer] = true
+		}
+	}
+	for s := range snap.Signers {
+		if voted[s] {
+			status.Voted = append(status.Voted, s)
+		} else {
+			status.Pending = append(status.Pending, s)
+		}
+	}
+	sortAddresses(status.Voted)
+	sortAddresses(status.Pending)
+	return status
+}
+
+func sortAddresses(addrs []common.Address) {
+	sort.Slice(addrs, func(i, j int) bool {
+		return addrs[i].Cmp(addrs[j]) < 0
+	})
+}
+
+// ProposalStatus reads the snapshot at the given block, the latest if nil,
+// and reports how many more signer votes the proposal needs.
+func (c *Clique) ProposalStatus(ctx context.Context, address common.Address, authorize bool, number *rpc.BlockNumber) (*ProposalStatus, error) {
+	snap, err := c.GetSnapshot(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if snap == nil {
+		return nil, fmt.Errorf("clique snapshot not found")
+	}
+	return NewProposalStatus(snap, address, authorize), nil
+}
+
+// Governance coordinates signer votes across the signer nodes of a clique
+// network. Every *Clique must be connected to a different signer node.
+type Governance struct {
+	signers []*Clique
+
+	// PollInterval is how often Wait checks the signer set, one second by
+	// default.
+	PollInterval time.Duration
+}
+
+func NewGovernance(signers ...*Clique) *Governance {
+	g := &Governance{}
+	g.signers = signers
+	g.PollInterval = time.Second
+	return g
+}
+
+// Propose makes every signer node vote for the proposal. The errors of the
+// nodes that failed are joined, the others keep voting.
+func (g *Governance) Propose(ctx context.Context, address common.Address, authorize bool) error {
+	var errs []error
+	for i, s := range g.signers {
+		if err := s.Propose(ctx, address, authorize); err != nil {
+			errs = append(errs, fmt.Errorf("signer node %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Discard makes every signer node drop its proposal for address.
+func (g *Governance) Discard(ctx context.Context, address common.Address) error {
+	var errs []error
+	for i, s := range g.signers {
+		if err := s.Discard(ctx, address); err != nil {
+			errs = append(errs, fmt.Errorf("signer node %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Proposals returns the proposals of every signer node, in node order.
+func (g *Governance) Proposals(ctx context.Context) ([]map[common.Address]bool, error) {
+	result := make([]map[common.Address]bool, len(g.signers))
+	for i, s := range g.signers {
+		proposals, err := s.Proposals(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("signer node %d: %w", i, err)
+		}
+		result[i] = proposals
+	}
+	return result, nil
+}
+
+// Status reports the progress of a proposal at the latest block.
+func (g *Governance) Status(ctx context.Context, address common.Address, authorize bool) (*ProposalStatus, error) {
+	if len(g.signers) == 0 {
+		return nil, fmt.Errorf("no signer nodes")
+	}
+	latest := rpc.LatestBlockNumber
+	return g.signers[0].ProposalStatus(ctx, address, authorize, &latest)
+}
+
+// Wait polls the signer set until it reflects the proposal and returns the
+// number of the snapshot it was first seen in. That is the head at the time
+// of the poll, which can be later than the block where the change took
+// effect.
+func (g *Governance) Wait(ctx context.Context, address common.Address, authorize bool) (uint64, error) {
+	if len(g.signers) == 0 {
+		return 0, fmt.Errorf("no signer nodes")
+	}
+	ticker := time.NewTicker(g.PollInterval)
+	defer ticker.Stop()
+	latest := rpc.LatestBlockNumber
+	for {
+		snap, err := g.signers[0].GetSnapshot(ctx, &latest)
+		if err != nil {
+			return 0, err
+		}
+		if snap != nil {
+			if _, signer := snap.Signers[address]; signer == authorize {
+				return snap.Number, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Apply proposes the change on every signer node, waits until the signer set
+// reflects it and then discards the proposal again, so that the nodes do not
+// keep voting on it later. The proposal is discarded as well when proposing
+// or waiting fails, also after ctx is done. It returns the block number Wait
+// returns.
+func (g *Governance) Apply(ctx context.Context, address common.Address, authorize bool) (uint64, error) {
+	status, err := g.Status(ctx, address, authorize)
+	if err != nil {
+		return 0, err
+	}
+	if !status.Valid {
+		return status.Block, nil
+	}
+	number, err := g.proposeAndWait(ctx, address, authorize)
+	discardCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), discardTimeout)
+	defer cancel()
+	if discardErr := g.Discard(discardCtx, address); err != nil || discardErr != nil {
+		return 0, errors.Join(err, discardErr)
+	}
+	return number, nil
+}
+
+// discardTimeout bounds the clean up of Apply once its context is done.
+const discardTimeout = 10 * time.Second
+
+func (g *Governance) proposeAndWait(ctx context.Context, address common.Address, authorize bool) (uint64, error) {
+	if err := g.Propose(ctx, address, authorize); err != nil {
+		return 0, err
+	}
+	return g.Wait(ctx, address, authorize)
+}
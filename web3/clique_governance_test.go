@@ -0,0 +1,165 @@
+package web3_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+)
+
+// governanceChain is the clique chain behind the stub signer nodes. Every
+// snapshot request advances it by a block. The candidate joins the signers
+// at block passAt, never if zero.
+type governanceChain struct {
+	mu        sync.Mutex
+	number    uint64
+	signers   []common.Address
+	candidate common.Address
+	passAt    uint64
+}
+
+// governanceService is a stub signer node of a governanceChain.
+type governanceService struct {
+	chain *governanceChain
+	fail  error
+
+	mu        sync.Mutex
+	proposals map[common.Address]bool
+	proposed  int
+}
+
+func (s *governanceService) Propose(address common.Address, auth bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.proposed++
+	if s.fail != nil {
+		return s.fail
+	}
+	s.proposals[address] = auth
+	return nil
+}
+
+func (s *governanceService) Discard(address common.Address) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.proposals, address)
+}
+
+func (s *governanceService) Proposals() map[common.Address]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	proposals := make(map[common.Address]bool, len(s.proposals))
+	for address, auth := range s.proposals {
+		proposals[address] = auth
+	}
+	return proposals
+}
+
+func (s *governanceService) GetSnapshot(number *rpc.BlockNumber) *clique.Snapshot {
+	c := s.chain
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.number++
+	snap := &clique.Snapshot{
+		Number:  c.number,
+		Signers: make(map[common.Address]struct{}),
+		Tally:   make(map[common.Address]clique.Tally),
+	}
+	for _, signer := range c.signers {
+		snap.Signers[signer] = struct{}{}
+	}
+	if c.passAt != 0 && c.number >= c.passAt {
+		snap.Signers[c.candidate] = struct{}{}
+	}
+	return snap
+}
+
+func newGovernance(t *testing.T, chain *governanceChain, n int) ([]*governanceService, *web3.Governance) {
+	var (
+		services []*governanceService
+		signers  []*web3.Clique
+	)
+	for i := 0; i < n; i++ {
+		s := &governanceService{chain: chain, proposals: make(map[common.Address]bool)}
+		server := rpc.NewServer()
+		if err := server.RegisterName("clique", s); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(server.Stop)
+		client := rpc.DialInProc(server)
+		t.Cleanup(client.Close)
+		services = append(services, s)
+		signers = append(signers, web3.NewWeb3(client).Clique)
+	}
+	g := web3.NewGovernance(signers...)
+	g.PollInterval = time.Millisecond
+	return services, g
+}
+
+func TestGovernanceApply(t *testing.T) {
+	signer := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	candidate := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	ctx := context.Background()
+
+	chain := &governanceChain{signers: []common.Address{signer}, candidate: candidate, passAt: 5}
+	services, g := newGovernance(t, chain, 2)
+	status, err := g.Status(ctx, candidate, true)
+	if err != nil || !status.Valid || status.Signers != 1 || status.Needed != 1 {
+		t.Fatalf("Status = %+v %v", status, err)
+	}
+	number, err := g.Apply(ctx, candidate, true)
+	// Status took snapshot 1, Wait polled snapshots 2 to 5
+	if err != nil || number != 5 {
+		t.Errorf("Apply = %d %v, want 5", number, err)
+	}
+	for i, s := range services {
+		if s.proposed != 1 || len(s.Proposals()) != 0 {
+			t.Errorf("signer node %d proposed %d times, proposals left %v", i, s.proposed, s.Proposals())
+		}
+	}
+
+	// a change already in effect is not proposed again
+	number, err = g.Apply(ctx, candidate, true)
+	if err != nil || number != 6 || services[0].proposed != 1 {
+		t.Errorf("Apply of an applied change = %d %v, proposed %d times", number, err, services[0].proposed)
+	}
+}
+
+func TestGovernanceApplyDiscardsOnFailure(t *testing.T) {
+	signer := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	candidate := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+
+	t.Run("wait", func(t *testing.T) {
+		chain := &governanceChain{signers: []common.Address{signer}, candidate: candidate}
+		services, g := newGovernance(t, chain, 2)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := g.Apply(ctx, candidate, true); err == nil {
+			t.Error("Apply succeeded without the change taking effect")
+		}
+		for i, s := range services {
+			if s.proposed != 1 || len(s.Proposals()) != 0 {
+				t.Errorf("signer node %d proposed %d times, proposals left %v", i, s.proposed, s.Proposals())
+			}
+		}
+	})
+
+	t.Run("propose", func(t *testing.T) {
+		chain := &governanceChain{signers: []common.Address{signer}, candidate: candidate}
+		services, g := newGovernance(t, chain, 2)
+		services[1].fail = errors.New("unlocked account required")
+		if _, err := g.Apply(context.Background(), candidate, true); err == nil || err.Error() != "signer node 1: clique_propose: unlocked account required" {
+			t.Errorf("Apply error = %v", err)
+		}
+		if len(services[0].Proposals()) != 0 {
+			t.Errorf("proposals left on the signer node that voted: %v", services[0].Proposals())
+		}
+	})
+}
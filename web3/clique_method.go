@@ -15,7 +15,7 @@ import (
 // from web3 console clique
 // method
 func (c *Clique) Discard(ctx context.Context, address common.Address) error {
-	err := c.c.CallContext(ctx, nil, "clique_discard")
+	err := c.c.CallContext(ctx, nil, "clique_discard", address)
 	return err
 }
 
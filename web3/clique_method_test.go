@@ -0,0 +1,120 @@
+package web3_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+func TestCliqueDevNode(t *testing.T) {
+	node := web3test.New(t, web3test.WithClique(0))
+	w := node.Web3
+	ctx := context.Background()
+	candidate := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	stale := common.HexToAddress("0x00000000000000000000000000000000000000dd")
+
+	// clique_status fails on chains shorter than two blocks
+	node.SendTx(t, &candidate, nil, nil)
+	node.Commit(t)
+	node.SendTx(t, &candidate, nil, nil)
+	node.Commit(t)
+	cliqueStatus, err := w.Clique.Status(ctx)
+	if err != nil || cliqueStatus.NumBlocks != 1 || cliqueStatus.SigningStatus[node.Account] != 1 || cliqueStatus.InturnPercent != 100 {
+		t.Errorf("Status = %+v %v", cliqueStatus, err)
+	}
+
+	genesis := rpc.BlockNumber(0)
+	signers, err := w.Clique.GetSigners(ctx, &genesis)
+	if err != nil || len(signers) != 1 || signers[0] != node.Account {
+		t.Fatalf("GetSigners = %v %v, want [%v]", signers, err, node.Account)
+	}
+	if err := w.Clique.Propose(ctx, candidate, true); err != nil {
+		t.Fatalf("Propose: %v", err)
+	}
+	if err := w.Clique.Propose(ctx, stale, true); err != nil {
+		t.Fatalf("Propose: %v", err)
+	}
+	if err := w.Clique.Discard(ctx, stale); err != nil {
+		t.Fatalf("Discard: %v", err)
+	}
+	proposals, err := w.Clique.Proposals(ctx)
+	if err != nil || len(proposals) != 1 || !proposals[candidate] {
+		t.Fatalf("Proposals = %v %v, want only %v", proposals, err, candidate)
+	}
+	status, err := w.Clique.ProposalStatus(ctx, candidate, true, nil)
+	if err != nil || !status.Valid || status.Needed != 1 {
+		t.Fatalf("ProposalStatus = %+v %v", status, err)
+	}
+
+	// a single signer passes its own proposal with the vote in the next block
+	node.SendTx(t, &candidate, nil, nil)
+	number := node.Commit(t)
+	block := rpc.BlockNumber(number)
+	signers, err = w.Clique.GetSigners(ctx, &block)
+	if err != nil || len(signers) != 2 || !containsAddress(signers, candidate) {
+		t.Fatalf("GetSigners after vote = %v %v", signers, err)
+	}
+	hash := node.Eth.BlockChain().CurrentBlock().Hash()
+	if atHash, err := w.Clique.GetSignersAtHash(ctx, hash); err != nil || len(atHash) != 2 {
+		t.Errorf("GetSignersAtHash = %v %v", atHash, err)
+	}
+	snap, err := w.Clique.GetSnapshot(ctx, &block)
+	if err != nil || snap.Number != number || snap.Hash != hash {
+		t.Fatalf("GetSnapshot = %+v %v", snap, err)
+	}
+	if atHash, err := w.Clique.GetSnapshotAtHash(ctx, hash); err != nil || atHash.Number != number {
+		t.Errorf("GetSnapshotAtHash = %+v %v", atHash, err)
+	}
+	byNumber := rpc.BlockNumberOrHashWithNumber(block)
+	if sealer, err := w.Clique.GetSigner(ctx, &web3.BlockNumberOrHashOrRLP{BlockNumberOrHash: &byNumber}); err != nil || sealer != node.Account {
+		t.Errorf("GetSigner = %v %v, want %v", sealer, err, node.Account)
+	}
+	if proposals, err := w.Clique.Proposals(ctx); err != nil || len(proposals) != 1 {
+		t.Errorf("Proposals after vote = %v %v", proposals, err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	gov := web3.NewGovernance(w.Clique)
+	if applied, err := gov.Wait(waitCtx, candidate, true); err != nil || applied != number {
+		t.Errorf("Governance.Wait = %d %v, want %d", applied, err, number)
+	}
+
+	monitor := web3.NewSealerMonitor(w, web3.SealerMonitorConfig{})
+	if err := monitor.Process(ctx, number); err != nil {
+		t.Fatalf("SealerMonitor.Process: %v", err)
+	}
+	report := monitor.Report()
+	if len(report) != 1 || report[0].Signer != node.Account || report[0].Sealed != 1 {
+		t.Errorf("SealerMonitor.Report = %+v", report)
+	}
+}
+
+func TestCliqueUnavailableOnPoS(t *testing.T) {
+	w := web3test.NewWeb3(t)
+	ctx := context.Background()
+	if _, err := w.Clique.Proposals(ctx); !errors.Is(err, web3.ErrMethodNotFound) {
+		t.Errorf("Proposals error = %v, want ErrMethodNotFound", err)
+	}
+	caps, err := w.Capabilities(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps.Has("clique") || w.Clique.Available() {
+		t.Error("clique reported available on a proof-of-stake node")
+	}
+	for _, namespace := range []string{"admin", "debug", "eth", "miner", "net", "personal", "rpc", "txpool"} {
+		if !caps.Has(namespace) {
+			t.Errorf("%s unavailable: %v", namespace, caps.Errors[namespace])
+		}
+	}
+	if _, err := w.Clique.Proposals(ctx); !errors.Is(err, web3.ErrNamespaceUnavailable) {
+		t.Errorf("Proposals error after probing = %v, want ErrNamespaceUnavailable", err)
+	}
+}
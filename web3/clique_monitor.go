@@ -0,0 +1,307 @@
+package web3
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Clique block difficulties, see EIP-225.
+var (
+	diffInTurn = big.NewInt(2)
+	diffNoTurn = big.NewInt(1)
+)
+
+// SealerAlertKind classifies a SealerAlert.
+type SealerAlertKind string
+
+const (
+	// AlertMissedInTurn is raised for the in-turn signer when a block was
+	// sealed by somebody else.
+	AlertMissedInTurn SealerAlertKind = "missed-inturn"
+	// AlertSilent is raised when a signer sealed nothing for
+	// SealerMonitorConfig.SilentBlocks blocks.
+	AlertSilent SealerAlertKind = "silent"
+	// AlertOutOfTurnDominance is raised when a single signer sealed more than
+	// SealerMonitorConfig.DominanceThreshold of the out-of-turn blocks in the
+	// window.
+	AlertOutOfTurnDominance SealerAlertKind = "out-of-turn-dominance"
+	// AlertDifficultyAnomaly is raised when a block difficulty is neither 1
+	// nor 2, or does not match whether its sealer was in turn.
+	AlertDifficultyAnomaly SealerAlertKind = "difficulty-anomaly"
+)
+
+// SealerAlert is a structured event emitted by a SealerMonitor.
+type SealerAlert struct {
+	Kind    SealerAlertKind `json:"kind"`
+	Block   uint64          `json:"block"`
+	Signer  common.Address  `json:"signer"`
+	Message string          `json:"message"`
+	Time    time.Time       `json:"time"`
+}
+
+// SignerReport is the activity of one signer over the monitor window.
+type SignerReport struct {
+	Signer       common.Address `json:"signer"`
+	Sealed       int            `json:"sealed"`
+	InTurn       int            `json:"inTurn"`
+	OutOfTurn    int            `json:"outOfTurn"`
+	MissedInTurn int            `json:"missedInTurn"`
+	LastSealed   uint64         `json:"lastSealed"`
+	Silent       bool           `json:"silent"`
+}
+
+// SealerMonitorConfig tunes a SealerMonitor. Zero values get defaults.
+type SealerMonitorConfig struct {
+	// Window is the number of recent blocks the report and the dominance
+	// check cover, 64 by default.
+	Window int
+	// SilentBlocks is the number of blocks without a seal after which a
+	// signer is reported silent, twice the number of signers by default.
+	SilentBlocks uint64
+	// DominanceThreshold is the share of out-of-turn blocks above which a
+	// single signer is reported, 0.5 by default.
+	DominanceThreshold float64
+	// MinOutOfTurn is the number of out-of-turn blocks in the window needed
+	// before dominance is judged at all, 4 by default.
+	MinOutOfTurn int
+	// PollInterval is how often Run looks for new blocks, one second by
+	// default.
+	PollInterval time.Duration
+}
+
+// sealedBlock is what the monitor remembers about a block.
+type sealedBlock struct {
+	number   uint64
+	sealer   common.Address
+	inturn   common.Address
+	expected bool
+}
+
+// SealerMonitor follows new blocks of a clique network, resolves their
+// sealer with Clique.GetSigner and reports signers that miss their in-turn
+// slots, go silent or dominate the out-of-turn blocks, as well as difficulty
+// anomalies.
+type SealerMonitor struct {
+	clique *Clique
+	eth    *Eth
+	config SealerMonitorConfig
+
+	alerts chan SealerAlert
+
+	mu        sync.Mutex
+	window    []sealedBlock
+	signers   []common.Address
+	last      uint64
+	lastSeal  map[common.Address]uint64
+	silent    map[common.Address]bool
+	dominant  map[common.Address]bool
+	firstSeen uint64
+	started   bool
+}
+
+func NewSealerMonitor(w *Web3, config SealerMonitorConfig) *SealerMonitor {
+	if config.Window == 0 {
+		config.Window = 64
+	}
+	if config.DominanceThreshold == 0 {
+		config.DominanceThreshold = 0.5
+	}
+	if config.MinOutOfTurn == 0 {
+		config.MinOutOfTurn = 4
+	}
+	if config.PollInterval == 0 {
+		config.PollInterval = time.Second
+	}
+	m := &SealerMonitor{}
+	m.clique = w.Clique
+	m.eth = w.Eth
+	m.config = config
+	m.alerts = make(chan SealerAlert, 256)
+	m.lastSeal = make(map[common.Address]uint64)
+	m.silent = make(map[common.Address]bool)
+	m.dominant = make(map[common.Address]bool)
+	return m
+}
+
+// Alerts returns the channel alerts are delivered on. Alerts are dropped if
+// the channel is not drained.
+func (m *SealerMonitor) Alerts() <-chan SealerAlert {
+	return m.alerts
+}
+
+func (m *SealerMonitor) emit(kind SealerAlertKind, block uint64, signer common.Address, format string, args ...interface{}) {
+	alert := SealerAlert{
+		Kind:    kind,
+		Block:   block,
+		Signer:  signer,
+		Message: fmt.Sprintf(format, args...),
+		Time:    time.Now(),
+	}
+	select {
+	case m.alerts <- alert:
+	default:
+	}
+}
+
+// Run processes every new block until ctx is cancelled. It starts at the
+// current head.
+func (m *SealerMonitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		head, err := m.eth.BlockNumber(ctx)
+		if err != nil {
+			return err
+		}
+		m.mu.Lock()
+		if !m.started {
+			m.started = true
+			if head > 0 {
+				m.last = head - 1
+			}
+		}
+		last := m.last
+		m.mu.Unlock()
+		for n := last + 1; n <= head; n++ {
+			if err := m.Process(ctx, n); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process checks a single block. Blocks are expected in ascending order.
+func (m *SealerMonitor) Process(ctx context.Context, number uint64) error {
+	if number == 0 {
+		return nil
+	}
+	parent := rpc.BlockNumber(number - 1)
+	signers, err := m.clique.GetSigners(ctx, &parent)
+	if err != nil {
+		return err
+	}
+	sortAddresses(signers)
+	if len(signers) == 0 {
+		return fmt.Errorf("no signers at block %d", number-1)
+	}
+	bn := rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(number))
+	sealer, err := m.clique.GetSigner(ctx, &BlockNumberOrHashOrRLP{BlockNumberOrHash: &bn})
+	if err != nil {
+		return err
+	}
+	header, err := m.eth.GetHeaderByNumber(ctx, rpc.BlockNumber(number))
+	if err != nil {
+		return err
+	}
+	var difficulty *big.Int
+	if s, ok := header["difficulty"].(string); ok {
+		difficulty, _ = hexutil.DecodeBig(s)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.firstSeen == 0 {
+		m.firstSeen = number
+	}
+	m.last = number
+	m.signers = signers
+	inturn := signers[number%uint64(len(signers))]
+	block := sealedBlock{number: number, sealer: sealer, inturn: inturn, expected: sealer == inturn}
+	m.window = append(m.window, block)
+	if len(m.window) > m.config.Window {
+		m.window = m.window[len(m.window)-m.config.Window:]
+	}
+	m.lastSeal[sealer] = number
+	m.silent[sealer] = false
+
+	if !block.expected {
+		m.emit(AlertMissedInTurn, number, inturn, "in-turn signer %s missed block %d, sealed out of turn by %s", inturn.Hex(), number, sealer.Hex())
+	}
+	switch {
+	case difficulty == nil:
+		m.emit(AlertDifficultyAnomaly, number, sealer, "block %d has no difficulty", number)
+	case difficulty.Cmp(diffInTurn) != 0 && difficulty.Cmp(diffNoTurn) != 0:
+		m.emit(AlertDifficultyAnomaly, number, sealer, "block %d has difficulty %v, want 1 or 2", number, difficulty)
+	case block.expected && difficulty.Cmp(diffInTurn) != 0:
+		m.emit(AlertDifficultyAnomaly, number, sealer, "block %d sealed in turn by %s with difficulty %v", number, sealer.Hex(), difficulty)
+	case !block.expected && difficulty.Cmp(diffNoTurn) != 0:
+		m.emit(AlertDifficultyAnomaly, number, sealer, "block %d sealed out of turn by %s with difficulty %v", number, sealer.Hex(), difficulty)
+	}
+
+	silentBlocks := m.config.SilentBlocks
+	if silentBlocks == 0 {
+		silentBlocks = 2 * uint64(len(signers))
+	}
+	for _, s := range signers {
+		since, ok := m.lastSeal[s]
+		if !ok {
+			since = m.firstSeen - 1
+		}
+		if number-since >= silentBlocks && !m.silent[s] {
+			m.silent[s] = true
+			m.emit(AlertSilent, number, s, "signer %s sealed nothing in the last %d blocks", s.Hex(), number-since)
+		}
+	}
+
+	outOfTurn := make(map[common.Address]int)
+	total := 0
+	for _, b := range m.window {
+		if !b.expected {
+			outOfTurn[b.sealer]++
+			total++
+		}
+	}
+	for _, s := range signers {
+		share := 0.0
+		if total > 0 {
+			share = float64(outOfTurn[s]) / float64(total)
+		}
+		dominant := total >= m.config.MinOutOfTurn && share > m.config.DominanceThreshold
+		if dominant && !m.dominant[s] {
+			m.emit(AlertOutOfTurnDominance, number, s, "signer %s sealed %d of the last %d out-of-turn blocks", s.Hex(), outOfTurn[s], total)
+		}
+		m.dominant[s] = dominant
+	}
+	return nil
+}
+
+// Report returns the activity of every current signer over the window. It
+// may be called while Run is going.
+func (m *SealerMonitor) Report() []SignerReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reports := make(map[common.Address]*SignerReport, len(m.signers))
+	for _, s := range m.signers {
+		reports[s] = &SignerReport{Signer: s, LastSealed: m.lastSeal[s], Silent: m.silent[s]}
+	}
+	for _, b := range m.window {
+		if r, ok := reports[b.sealer]; ok {
+			r.Sealed++
+			if b.expected {
+				r.InTurn++
+			} else {
+				r.OutOfTurn++
+			}
+		}
+		if r, ok := reports[b.inturn]; ok && !b.expected {
+			r.MissedInTurn++
+		}
+	}
+	result := make([]SignerReport, 0, len(m.signers))
+	for _, s := range m.signers {
+		result = append(result, *reports[s])
+	}
+	return result
+}
@@ -0,0 +1,156 @@
+package web3_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+)
+
+// sealedStub is a block of a sealerService.
+type sealedStub struct {
+	sealer     common.Address
+	difficulty int64
+}
+
+// sealerService is a stub clique node with a fixed signer set.
+type sealerService struct {
+	signers []common.Address
+	blocks  map[uint64]sealedStub
+}
+
+func (s *sealerService) GetSigners(number *rpc.BlockNumber) []common.Address {
+	return s.signers
+}
+
+func (s *sealerService) GetSigner(block rpc.BlockNumberOrHash) (common.Address, error) {
+	number, ok := block.Number()
+	if !ok {
+		return common.Address{}, fmt.Errorf("want a block number")
+	}
+	return s.blocks[uint64(number)].sealer, nil
+}
+
+func (s *sealerService) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(len(s.blocks))
+}
+
+func (s *sealerService) GetHeaderByNumber(number rpc.BlockNumber) map[string]interface{} {
+	return map[string]interface{}{"difficulty": (*hexutil.Big)(big.NewInt(s.blocks[uint64(number)].difficulty))}
+}
+
+var (
+	sealerA = common.HexToAddress("0x000000000000000000000000000000000000000a")
+	sealerB = common.HexToAddress("0x000000000000000000000000000000000000000b")
+	sealerC = common.HexToAddress("0x000000000000000000000000000000000000000c")
+)
+
+// newSealerNode starts a stub clique node with blocks 1 to 6, block n being
+// in turn for signer n%3 of [a b c].
+func newSealerNode(t *testing.T) *web3.Web3 {
+	a, b, c := sealerA, sealerB, sealerC
+	s := &sealerService{signers: []common.Address{c, a, b}, blocks: map[uint64]sealedStub{
+		1: {b, 2},
+		2: {a, 1}, // c missed
+		3: {a, 2},
+		4: {a, 1}, // b missed
+		5: {a, 2}, // c missed, out of turn with the in-turn difficulty
+		6: {a, 3},
+	}}
+	server := rpc.NewServer()
+	for _, namespace := range []string{"clique", "eth"} {
+		if err := server.RegisterName(namespace, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(server.Stop)
+	client := rpc.DialInProc(server)
+	t.Cleanup(client.Close)
+	return web3.NewWeb3(client)
+}
+
+func TestSealerMonitorProcess(t *testing.T) {
+	a, b, c := sealerA, sealerB, sealerC
+	monitor := web3.NewSealerMonitor(newSealerNode(t), web3.SealerMonitorConfig{
+		Window:       4,
+		SilentBlocks: 4,
+		MinOutOfTurn: 3,
+	})
+	type alert struct {
+		kind   web3.SealerAlertKind
+		block  uint64
+		signer common.Address
+	}
+	want := []alert{
+		{web3.AlertMissedInTurn, 2, c},
+		{web3.AlertMissedInTurn, 4, b},
+		{web3.AlertSilent, 4, c},
+		{web3.AlertMissedInTurn, 5, c},
+		{web3.AlertDifficultyAnomaly, 5, a},
+		{web3.AlertSilent, 5, b},
+		{web3.AlertOutOfTurnDominance, 5, a},
+		{web3.AlertDifficultyAnomaly, 6, a},
+	}
+	var got []alert
+	ctx := context.Background()
+	for n := uint64(0); n <= 6; n++ {
+		if err := monitor.Process(ctx, n); err != nil {
+			t.Fatalf("Process(%d): %v", n, err)
+		}
+	drain:
+		for {
+			select {
+			case a := <-monitor.Alerts():
+				got = append(got, alert{a.Kind, a.Block, a.Signer})
+			default:
+				break drain
+			}
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("alerts = %v\nwant %v", got, want)
+	}
+
+	// the report covers the window of blocks 3 to 6
+	report := []web3.SignerReport{
+		{Signer: a, Sealed: 4, InTurn: 2, OutOfTurn: 2, LastSealed: 6},
+		{Signer: b, MissedInTurn: 1, LastSealed: 1, Silent: true},
+		{Signer: c, MissedInTurn: 1, Silent: true},
+	}
+	if got := monitor.Report(); !reflect.DeepEqual(got, report) {
+		t.Errorf("Report = %+v\nwant %+v", got, report)
+	}
+}
+
+func TestSealerMonitorReportDuringRun(t *testing.T) {
+	monitor := web3.NewSealerMonitor(newSealerNode(t), web3.SealerMonitorConfig{PollInterval: time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- monitor.Run(ctx) }()
+
+	// Run starts at the head, block 6 sealed by a
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		report := monitor.Report()
+		if len(report) == 3 && report[0].LastSealed == 6 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Report = %+v", report)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run = %v, want %v", err, context.Canceled)
+	}
+}
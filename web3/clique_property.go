@@ -4,21 +4,27 @@ import (
 	"context"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // backtraceAt
 
 type Clique struct {
-	c *rpc.Client
+	c    Client
+	gate *capabilityGate
 }
 
-func NewClique(c *rpc.Client) *Clique {
+func NewClique(c Client) *Clique {
 	admin := &Clique{}
-	admin.c = c
+	admin.c = withCallErrors(c)
 	return admin
 }
 
+// Available reports whether the node serves the clique namespace. It is true
+// until Web3.Capabilities has probed the node.
+func (c *Clique) Available() bool {
+	return c.gate.available("clique")
+}
+
 // Proposals returns the current proposals the node tries to uphold and vote on.
 // from API
 // from web3 console clique
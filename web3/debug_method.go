@@ -203,7 +203,7 @@ func (d *Debug) GcStats(ctx context.Context) (*debug.GCStats, error) {
 // from web3ext.go
 // method
 func (d *Debug) CpuProfile(ctx context.Context, file string, nsec uint) error {
-	err := d.c.CallContext(ctx, nil, "debug_cpuProfile", file)
+	err := d.c.CallContext(ctx, nil, "debug_cpuProfile", file, nsec)
 	return err
 }
 
@@ -0,0 +1,262 @@
+package web3_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+func TestDebugChainDevNode(t *testing.T) {
+	node := web3test.New(t)
+	w := node.Web3
+	ctx := context.Background()
+	value := common.HexToHash("0x2a")
+	contract, call := deployStore(t, node, value)
+	head := node.Eth.BlockChain().CurrentBlock()
+	one := rpc.BlockNumberOrHashWithNumber(1)
+	node.SendTx(t, &contract, nil, common.HexToHash("0x2b").Bytes())
+	node.Commit(t)
+
+	rawHeader, err := w.Debug.GetRawHeader(ctx, one)
+	if err != nil {
+		t.Fatalf("GetRawHeader: %v", err)
+	}
+	var header types.Header
+	if err := rlp.DecodeBytes(rawHeader, &header); err != nil || header.Hash() != head.Hash() {
+		t.Errorf("GetRawHeader decodes to %v %v, want %v", header.Hash(), err, head.Hash())
+	}
+	rawBlock, err := w.Debug.GetRawBlock(ctx, rpc.BlockNumberOrHashWithHash(head.Hash(), true))
+	if err != nil {
+		t.Fatalf("GetRawBlock: %v", err)
+	}
+	var block types.Block
+	if err := rlp.DecodeBytes(rawBlock, &block); err != nil || len(block.Transactions()) != 2 {
+		t.Errorf("GetRawBlock decodes to %d transactions %v", len(block.Transactions()), err)
+	}
+	if receipts, err := w.Debug.GetRawReceipts(ctx, one); err != nil || len(receipts) != 2 {
+		t.Errorf("GetRawReceipts = %d %v", len(receipts), err)
+	}
+	want, _ := call.MarshalBinary()
+	if raw, err := w.Debug.GetRawTransaction(ctx, call.Hash()); err != nil || hexutil.Encode(raw) != hexutil.Encode(want) {
+		t.Errorf("GetRawTransaction = %x %v", raw, err)
+	}
+	if printed, err := w.Debug.PrintBlock(ctx, 1); err != nil || !strings.Contains(printed, head.Hash().Hex()[2:]) {
+		t.Errorf("PrintBlock = %q %v", printed, err)
+	}
+
+	dump, err := w.Debug.DumpBlock(ctx, 1)
+	if err != nil {
+		t.Fatalf("DumpBlock: %v", err)
+	}
+	if account, ok := dump.Accounts[contract.Hex()]; !ok || account.Storage[common.Hash{}] != "2a" {
+		t.Errorf("DumpBlock account %v = %+v", contract, account)
+	}
+	if accounts, err := w.Debug.AccountRange(ctx, one, nil, 10, true, true, false); err != nil || len(accounts.Accounts) == 0 {
+		t.Errorf("AccountRange = %d accounts %v", len(accounts.Accounts), err)
+	}
+	// the state before the first transaction of block 2
+	storage, err := w.Debug.StorageRangeAt(ctx, rpc.BlockNumberOrHashWithNumber(2), 0, contract, nil, 10)
+	if err != nil || len(storage.Storage) != 1 {
+		t.Errorf("StorageRangeAt = %+v %v", storage, err)
+	}
+	modified, err := w.Debug.GetModifiedAccountsByNumber(ctx, 1, nil)
+	if err != nil || !containsAddress(modified, contract) {
+		t.Errorf("GetModifiedAccountsByNumber = %v %v", modified, err)
+	}
+	genesis := node.Eth.BlockChain().Genesis().Hash()
+	headHash := head.Hash()
+	if modified, err := w.Debug.GetModifiedAccountsByHash(ctx, genesis, &headHash); err != nil || !containsAddress(modified, contract) {
+		t.Errorf("GetModifiedAccountsByHash = %v %v", modified, err)
+	}
+	if number, err := w.Debug.GetAccessibleState(ctx, 0, 1); err != nil || number != 0 {
+		t.Errorf("GetAccessibleState = %d %v", number, err)
+	}
+	if _, err := w.Debug.Preimage(ctx, common.Hash{}); err == nil {
+		t.Error("Preimage of an unknown hash succeeded")
+	}
+	if bad, err := w.Debug.GetBadBlocks(ctx); err != nil || len(bad) != 0 {
+		t.Errorf("GetBadBlocks = %v %v", bad, err)
+	}
+	if roots, err := w.Debug.IntermediateRoots(ctx, head.Hash(), nil); err != nil || len(roots) != 2 || roots[1] != head.Root {
+		t.Errorf("IntermediateRoots = %v %v", roots, err)
+	}
+
+	// tracing
+	callTracer := "callTracer"
+	config := &tracers.TraceConfig{Tracer: &callTracer}
+	trace, err := w.Debug.TraceTransaction(ctx, call.Hash(), config)
+	if err != nil {
+		t.Fatalf("TraceTransaction: %v", err)
+	}
+	if frame, ok := trace.(map[string]interface{}); !ok || frame["to"] != strings.ToLower(contract.Hex()) {
+		t.Errorf("TraceTransaction = %v", trace)
+	}
+	for name, traceBlock := range map[string]func() ([]*web3.TxTraceResult, error){
+		"TraceBlock":         func() ([]*web3.TxTraceResult, error) { return w.Debug.TraceBlock(ctx, rawBlock, config) },
+		"TraceBlockByNumber": func() ([]*web3.TxTraceResult, error) { return w.Debug.TraceBlockByNumber(ctx, 1, config) },
+		"TraceBlockByHash":   func() ([]*web3.TxTraceResult, error) { return w.Debug.TraceBlockByHash(ctx, head.Hash(), config) },
+	} {
+		if results, err := traceBlock(); err != nil || len(results) != 2 || results[1].TxHash != call.Hash() {
+			t.Errorf("%s = %v %v", name, results, err)
+		}
+	}
+	blockFile := filepath.Join(t.TempDir(), "block.rlp")
+	if err := os.WriteFile(blockFile, rawBlock, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if results, err := w.Debug.TraceBlockFromFile(ctx, blockFile, config); err != nil || len(results) != 2 {
+		t.Errorf("TraceBlockFromFile = %v %v", results, err)
+	}
+	files, err := w.Debug.StandardTraceBlockToFile(ctx, head.Hash(), nil)
+	if err != nil || len(files) != 2 {
+		t.Errorf("StandardTraceBlockToFile = %v %v", files, err)
+	}
+	for _, file := range files {
+		os.Remove(file)
+	}
+	if _, err := w.Debug.TraceBadBlock(ctx, head.Hash(), config); err == nil {
+		t.Error("TraceBadBlock of a good block succeeded")
+	}
+	if _, err := w.Debug.StandardTraceBadBlockToFile(ctx, head.Hash(), nil); err == nil {
+		t.Error("StandardTraceBadBlockToFile of a good block succeeded")
+	}
+	input := hexutil.Bytes(common.HexToHash("0x07").Bytes())
+	args := web3.TransactionArgs{From: &node.Account, To: &contract, Input: &input}
+	trace, err = w.Debug.TraceCall(ctx, args, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), &tracers.TraceCallConfig{TraceConfig: *config})
+	if frame, ok := trace.(map[string]interface{}); err != nil || !ok || frame["input"] != input.String() {
+		t.Errorf("TraceCall = %v %v", trace, err)
+	}
+
+	// database
+	if ancients, err := w.Debug.DbAncients(ctx); err != nil || ancients != 0 {
+		t.Errorf("DbAncients = %d %v", ancients, err)
+	}
+	if _, err := w.Debug.DbAncient(ctx, "headers", 0); err == nil {
+		t.Error("DbAncient succeeded on an empty freezer")
+	}
+	// "h" + number + hash is the header key of the database schema
+	key := hexutil.Encode(append(append([]byte("h"), common.LeftPadBytes([]byte{1}, 8)...), head.Hash().Bytes()...))
+	if value, err := w.Debug.DbGet(ctx, key); err != nil || hexutil.Encode(value) != hexutil.Encode(rawHeader) {
+		t.Errorf("DbGet = %x %v", value, err)
+	}
+	if _, err := w.Debug.ChaindbProperty(ctx, "leveldb.stats"); err != nil {
+		t.Errorf("ChaindbProperty: %v", err)
+	}
+	if err := w.Debug.ChaindbCompact(ctx); err != nil {
+		t.Errorf("ChaindbCompact: %v", err)
+	}
+	if err := w.Debug.SetTrieFlushInterval(ctx, "2m"); err != nil {
+		t.Errorf("SetTrieFlushInterval: %v", err)
+	}
+	if interval, err := w.Debug.GetTrieFlushInterval(ctx); err != nil || interval != "2m0s" {
+		t.Errorf("GetTrieFlushInterval = %q %v", interval, err)
+	}
+
+	if err := w.Debug.SetHead(ctx, 0); err != nil {
+		t.Fatalf("SetHead: %v", err)
+	}
+	if number := node.Eth.BlockChain().CurrentBlock().Number.Uint64(); number != 0 {
+		t.Errorf("head after SetHead(0) = %d", number)
+	}
+}
+
+func TestDebugRuntimeDevNode(t *testing.T) {
+	w := web3test.NewWeb3(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	if err := w.Debug.Verbosity(ctx, 3); err != nil {
+		t.Errorf("Verbosity: %v", err)
+	}
+	if err := w.Debug.Vmodule(ctx, "eth/*=3"); err != nil {
+		t.Errorf("Vmodule: %v", err)
+	}
+	filter := "web3"
+	if _, err := w.Debug.Stacks(ctx, &filter); err != nil {
+		t.Errorf("Stacks: %v", err)
+	}
+	if err := w.Debug.FreeOSMemory(ctx); err != nil {
+		t.Errorf("FreeOSMemory: %v", err)
+	}
+	old, err := w.Debug.SetGCPercent(ctx, 50)
+	if err != nil {
+		t.Errorf("SetGCPercent: %v", err)
+	}
+	if previous, err := w.Debug.SetGCPercent(ctx, old); err != nil || previous != 50 {
+		t.Errorf("SetGCPercent = %d %v, want 50", previous, err)
+	}
+	if stats, err := w.Debug.MemStats(ctx); err != nil || stats.HeapAlloc == 0 {
+		t.Errorf("MemStats = %v %v", stats, err)
+	}
+	if _, err := w.Debug.GcStats(ctx); err != nil {
+		t.Errorf("GcStats: %v", err)
+	}
+
+	profiles := []struct {
+		name string
+		run  func(file string) error
+	}{
+		{"CpuProfile", func(file string) error { return w.Debug.CpuProfile(ctx, file, 0) }},
+		{"StartCPUProfile", func(file string) error {
+			if err := w.Debug.StartCPUProfile(ctx, file); err != nil {
+				return err
+			}
+			return w.Debug.StopCPUProfile(ctx)
+		}},
+		{"GoTrace", func(file string) error { return w.Debug.GoTrace(ctx, file, 0) }},
+		{"StartGoTrace", func(file string) error {
+			if err := w.Debug.StartGoTrace(ctx, file); err != nil {
+				return err
+			}
+			return w.Debug.StopGoTrace(ctx)
+		}},
+		{"BlockProfile", func(file string) error { return w.Debug.BlockProfile(ctx, file, 0) }},
+		{"WriteBlockProfile", func(file string) error {
+			if err := w.Debug.SetBlockProfileRate(ctx, 1); err != nil {
+				return err
+			}
+			defer w.Debug.SetBlockProfileRate(ctx, 0)
+			return w.Debug.WriteBlockProfile(ctx, file)
+		}},
+		{"MutexProfile", func(file string) error { return w.Debug.MutexProfile(ctx, file, 0) }},
+		{"WriteMutexProfile", func(file string) error {
+			if err := w.Debug.SetMutexProfileFraction(ctx, 1); err != nil {
+				return err
+			}
+			defer w.Debug.SetMutexProfileFraction(ctx, 0)
+			return w.Debug.WriteMutexProfile(ctx, file)
+		}},
+		{"WriteMemProfile", func(file string) error { return w.Debug.WriteMemProfile(ctx, file) }},
+	}
+	for _, p := range profiles {
+		file := filepath.Join(dir, p.name)
+		if err := p.run(file); err != nil {
+			t.Errorf("%s: %v", p.name, err)
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("%s wrote no file: %v", p.name, err)
+		}
+	}
+}
+
+func containsAddress(addrs []common.Address, addr common.Address) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
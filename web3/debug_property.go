@@ -1,18 +1,21 @@
 package web3
 
-import (
-	"github.com/ethereum/go-ethereum/rpc"
-)
-
 // debug_freezeClient
 // debug_seedHash
 
 type Debug struct {
-	c *rpc.Client
+	c    Client
+	gate *capabilityGate
 }
 
-func NewDebug(c *rpc.Client) *Debug {
+func NewDebug(c Client) *Debug {
 	d := &Debug{}
-	d.c = c
+	d.c = withCallErrors(c)
 	return d
 }
+
+// Available reports whether the node serves the debug namespace. It is true
+// until Web3.Capabilities has probed the node.
+func (d *Debug) Available() bool {
+	return d.gate.available("debug")
+}
@@ -0,0 +1,243 @@
+package web3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// DecodeRawHeader decodes a header returned by Debug.GetRawHeader.
+func DecodeRawHeader(raw []byte) (*types.Header, error) {
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(raw, header); err != nil {
+		return nil, fmt.Errorf("invalid raw header: %w", err)
+	}
+	return header, nil
+}
+
+// DecodeRawBlock decodes a block returned by Debug.GetRawBlock.
+func DecodeRawBlock(raw []byte) (*types.Block, error) {
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(raw, block); err != nil {
+		return nil, fmt.Errorf("invalid raw block: %w", err)
+	}
+	return block, nil
+}
+
+// DecodeRawReceipts decodes the receipts returned by Debug.GetRawReceipts.
+// Only their consensus fields are set: status, cumulative gas used, bloom and
+// logs, the latter without their block and transaction fields.
+func DecodeRawReceipts(raw []hexutil.Bytes) (types.Receipts, error) {
+	receipts := make(types.Receipts, len(raw))
+	for i, encoded := range raw {
+		receipts[i] = new(types.Receipt)
+		if err := receipts[i].UnmarshalBinary(encoded); err != nil {
+			return nil, fmt.Errorf("invalid raw receipt %d: %w", i, err)
+		}
+	}
+	return receipts, nil
+}
+
+// GetHeader is GetRawHeader decoded with DecodeRawHeader.
+func (d *Debug) GetHeader(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	raw, err := d.GetRawHeader(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeRawHeader(raw)
+}
+
+// GetBlock is GetRawBlock decoded with DecodeRawBlock.
+func (d *Debug) GetBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	raw, err := d.GetRawBlock(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeRawBlock(raw)
+}
+
+// GetReceipts is GetRawReceipts decoded with DecodeRawReceipts.
+func (d *Debug) GetReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (types.Receipts, error) {
+	raw, err := d.GetRawReceipts(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeRawReceipts(raw)
+}
+
+// BlockMismatch is a field whose value, as sent or as recomputed from the
+// content of a block, differs from that of the canonical header.
+type BlockMismatch struct {
+	Field string // JSON name of the field, such as "transactionsRoot"
+	Got   string
+	Want  string // in the canonical header, or in the receipt for its bloom
+}
+
+// BlockMismatchError lists the mismatches found by the block verifiers.
+type BlockMismatchError struct {
+	Hash       common.Hash // of the canonical header
+	Mismatches []BlockMismatch
+}
+
+func (e *BlockMismatchError) Error() string {
+	fields := make([]string, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		fields[i] = fmt.Sprintf("%s %s, want %s", m.Field, m.Got, m.Want)
+	}
+	return fmt.Sprintf("block %v mismatch: %s", e.Hash, strings.Join(fields, "; "))
+}
+
+// mismatches collects the mismatches of a block verification.
+type mismatches struct {
+	canonical *types.Header
+	found     []BlockMismatch
+}
+
+func (m *mismatches) check(field string, got, want interface{}) {
+	if g, w := fmt.Sprint(got), fmt.Sprint(want); g != w {
+		m.found = append(m.found, BlockMismatch{Field: field, Got: g, Want: w})
+	}
+}
+
+func (m *mismatches) err() error {
+	if len(m.found) == 0 {
+		return nil
+	}
+	return &BlockMismatchError{Hash: m.canonical.Hash(), Mismatches: m.found}
+}
+
+// checkHeader compares the fields of a header with those of the canonical
+// header, in their JSON form.
+func (m *mismatches) checkHeader(header *types.Header) error {
+	got, err := headerFields(header)
+	if err != nil {
+		return err
+	}
+	want, err := headerFields(m.canonical)
+	if err != nil {
+		return err
+	}
+	for field := range want {
+		if _, ok := got[field]; !ok {
+			got[field] = nil
+		}
+	}
+	names := make([]string, 0, len(got))
+	for field := range got {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+	for _, field := range names {
+		m.check(field, orNone(got[field]), orNone(want[field]))
+	}
+	return nil
+}
+
+func headerFields(header *types.Header) (map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(encoded, &fields)
+	return fields, err
+}
+
+func orNone(value json.RawMessage) string {
+	if value == nil || string(value) == "null" {
+		return "none"
+	}
+	return strings.Trim(string(value), `"`)
+}
+
+// VerifyHeader compares a header, such as one of Debug.GetHeader, with the
+// canonical header field by field. It returns a *BlockMismatchError listing
+// the fields that differ.
+func VerifyHeader(header, canonical *types.Header) error {
+	m := &mismatches{canonical: canonical}
+	if err := m.checkHeader(header); err != nil {
+		return err
+	}
+	return m.err()
+}
+
+// VerifyBlock checks a block, such as one of Debug.GetBlock, against the
+// canonical header: its own header field by field, and the transactions
+// root, uncles hash and withdrawals root recomputed from its body. It returns
+// a *BlockMismatchError listing the fields that differ.
+func VerifyBlock(block *types.Block, canonical *types.Header) error {
+	m := &mismatches{canonical: canonical}
+	if err := m.checkHeader(block.Header()); err != nil {
+		return err
+	}
+	m.check("transactionsRoot (recomputed)", types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)), canonical.TxHash)
+	m.check("sha3Uncles (recomputed)", types.CalcUncleHash(block.Uncles()), canonical.UncleHash)
+	if canonical.WithdrawalsHash != nil || block.Withdrawals() != nil {
+		got, want := "none", "none"
+		if block.Withdrawals() != nil {
+			got = types.DeriveSha(block.Withdrawals(), trie.NewStackTrie(nil)).Hex()
+		}
+		if canonical.WithdrawalsHash != nil {
+			want = canonical.WithdrawalsHash.Hex()
+		}
+		m.check("withdrawalsRoot (recomputed)", got, want)
+	}
+	return m.err()
+}
+
+// VerifyReceipts checks the receipts of a block, such as those of
+// Debug.GetReceipts, against its canonical header: the receipts root, the
+// logs bloom of the block and of each receipt, and the gas used. It returns a
+// *BlockMismatchError listing the fields that differ.
+func VerifyReceipts(receipts types.Receipts, canonical *types.Header) error {
+	m := &mismatches{canonical: canonical}
+	m.check("receiptsRoot (recomputed)", types.DeriveSha(receipts, trie.NewStackTrie(nil)), canonical.ReceiptHash)
+	m.check("logsBloom (recomputed)", hexutil.Bytes(types.CreateBloom(receipts).Bytes()), hexutil.Bytes(canonical.Bloom.Bytes()))
+	for i, receipt := range receipts {
+		bloom := types.BytesToBloom(types.LogsBloom(receipt.Logs))
+		m.check(fmt.Sprintf("receipts[%d].logsBloom (recomputed)", i), hexutil.Bytes(bloom.Bytes()), hexutil.Bytes(receipt.Bloom.Bytes()))
+	}
+	var gasUsed uint64
+	if len(receipts) > 0 {
+		gasUsed = receipts[len(receipts)-1].CumulativeGasUsed
+	}
+	m.check("gasUsed (recomputed)", gasUsed, canonical.GasUsed)
+	return m.err()
+}
+
+// GetVerifiedBlock fetches a block and its receipts with Debug.GetRawBlock and
+// Debug.GetRawReceipts and returns them once verified against the canonical
+// header of Eth, with VerifyBlock and VerifyReceipts. The receipts only have
+// their consensus fields, see DecodeRawReceipts.
+func (w *Web3) GetVerifiedBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, types.Receipts, error) {
+	canonical, err := w.Eth.header(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	at := rpc.BlockNumberOrHashWithHash(canonical.Hash(), false)
+	block, err := w.Debug.GetBlock(ctx, at)
+	if err != nil {
+		return nil, nil, err
+	}
+	receipts, err := w.Debug.GetReceipts(ctx, at)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := VerifyBlock(block, canonical); err != nil {
+		return nil, nil, err
+	}
+	if err := VerifyReceipts(receipts, canonical); err != nil {
+		return nil, nil, err
+	}
+	return block, receipts, nil
+}
@@ -0,0 +1,85 @@
+package web3_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+// mismatchedFields returns the fields of a *web3.BlockMismatchError.
+func mismatchedFields(t *testing.T, err error) map[string]bool {
+	t.Helper()
+	var mismatch *web3.BlockMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("err = %v, want a *web3.BlockMismatchError", err)
+	}
+	fields := make(map[string]bool)
+	for _, m := range mismatch.Mismatches {
+		fields[m.Field] = true
+	}
+	return fields
+}
+
+func TestVerifyRawBlock(t *testing.T) {
+	node := web3test.New(t)
+	w := node.Web3
+	ctx := context.Background()
+	deployStore(t, node, common.HexToHash("0x2a"))
+	canonical := node.Eth.BlockChain().CurrentBlock()
+
+	for _, at := range []rpc.BlockNumberOrHash{rpc.BlockNumberOrHashWithNumber(1), rpc.BlockNumberOrHashWithHash(canonical.Hash(), false)} {
+		block, receipts, err := w.GetVerifiedBlock(ctx, at)
+		if err != nil {
+			t.Fatalf("GetVerifiedBlock at %v: %v", at, err)
+		}
+		if block.Hash() != canonical.Hash() || len(block.Transactions()) != 2 || len(receipts) != 2 || len(receipts[1].Logs) != 1 {
+			t.Errorf("GetVerifiedBlock = %v with %d transactions, %d receipts", block.Hash(), len(block.Transactions()), len(receipts))
+		}
+	}
+
+	one := rpc.BlockNumberOrHashWithNumber(1)
+	header, err := w.Debug.GetHeader(ctx, one)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := web3.VerifyHeader(header, canonical); err != nil {
+		t.Errorf("VerifyHeader: %v", err)
+	}
+	header.GasUsed++
+	if fields := mismatchedFields(t, web3.VerifyHeader(header, canonical)); len(fields) != 2 || !fields["gasUsed"] || !fields["hash"] {
+		t.Errorf("VerifyHeader of a changed gas used reports %v", fields)
+	}
+
+	block, err := w.Debug.GetBlock(ctx, one)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropped := block.WithBody(block.Transactions()[:1], nil)
+	if fields := mismatchedFields(t, web3.VerifyBlock(dropped, canonical)); len(fields) != 1 || !fields["transactionsRoot (recomputed)"] {
+		t.Errorf("VerifyBlock without a transaction reports %v", fields)
+	}
+	withdrawn := block.WithWithdrawals([]*types.Withdrawal{{Index: 1, Amount: 1}})
+	if fields := mismatchedFields(t, web3.VerifyBlock(withdrawn, canonical)); len(fields) != 1 || !fields["withdrawalsRoot (recomputed)"] {
+		t.Errorf("VerifyBlock with a withdrawal reports %v", fields)
+	}
+
+	receipts, err := w.Debug.GetReceipts(ctx, one)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipts[1].Logs = nil
+	fields := mismatchedFields(t, web3.VerifyReceipts(receipts, canonical))
+	if len(fields) != 3 || !fields["receiptsRoot (recomputed)"] || !fields["logsBloom (recomputed)"] || !fields["receipts[1].logsBloom (recomputed)"] {
+		t.Errorf("VerifyReceipts without a log reports %v", fields)
+	}
+	if fields := mismatchedFields(t, web3.VerifyReceipts(receipts[:1], canonical)); !fields["gasUsed (recomputed)"] {
+		t.Errorf("VerifyReceipts without a receipt reports %v", fields)
+	}
+}
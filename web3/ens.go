@@ -0,0 +1,287 @@
+package web3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrENSNotFound is returned for names without resolver or record, and for
+// addresses without a verified reverse record.
+var ErrENSNotFound = errors.New("ens name not found")
+
+// ENSRegistry is the address of the ENS registry on mainnet and the public
+// testnets.
+var ENSRegistry = common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+
+// Selectors of the registry and resolver functions.
+var (
+	ensResolverSelector    = []byte{0x01, 0x78, 0xb8, 0xbf} // resolver(bytes32)
+	ensAddrSelector        = []byte{0x3b, 0x3b, 0x57, 0xde} // addr(bytes32)
+	ensNameSelector        = []byte{0x69, 0x1f, 0x34, 0x31} // name(bytes32)
+	ensTextSelector        = []byte{0x59, 0xd1, 0xd4, 0x3c} // text(bytes32,string)
+	ensContenthashSelector = []byte{0xbc, 0x1c, 0x58, 0xd1} // contenthash(bytes32)
+	ensResolveSelector     = []byte{0x90, 0x61, 0xb9, 0x23} // resolve(bytes,bytes), also the ENSIP-10 interface ID
+	erc165Selector         = []byte{0x01, 0xff, 0xc9, 0xa7} // supportsInterface(bytes4)
+)
+
+var (
+	ensBytesArgs   abi.Arguments
+	ensStringArgs  abi.Arguments
+	ensTextArgs    abi.Arguments
+	ensResolveArgs abi.Arguments
+)
+
+func init() {
+	arg := func(t string) abi.Argument {
+		typ, err := abi.NewType(t, "", nil)
+		if err != nil {
+			panic(err)
+		}
+		return abi.Argument{Type: typ}
+	}
+	ensBytesArgs = abi.Arguments{arg("bytes")}
+	ensStringArgs = abi.Arguments{arg("string")}
+	ensTextArgs = abi.Arguments{arg("bytes32"), arg("string")}
+	ensResolveArgs = abi.Arguments{arg("bytes"), arg("bytes")}
+}
+
+// ENSConfig configures an ENS client. Zero values get defaults.
+type ENSConfig struct {
+	// Registry is the ENS registry, ENSRegistry by default.
+	Registry common.Address
+	// Fetcher performs the gateway requests of offchain resolvers,
+	// http.DefaultClient by default.
+	Fetcher OffchainFetcher
+	// Block is the block names are resolved at, the latest by default.
+	Block *rpc.BlockNumberOrHash
+}
+
+// ENS resolves ENS names through Eth.Call. It lower cases the ASCII letters
+// of names, whose other characters must already be normalized, finds their
+// resolver in the registry, with ENSIP-10 wildcard resolution, and follows the
+// EIP-3668 offchain lookups of resolvers.
+type ENS struct {
+	eth    *Eth
+	config ENSConfig
+}
+
+func NewENS(w *Web3, config ENSConfig) *ENS {
+	if config.Registry == (common.Address{}) {
+		config.Registry = ENSRegistry
+	}
+	n := &ENS{}
+	n.eth = w.Eth
+	n.config = config
+	return n
+}
+
+func (n *ENS) call(ctx context.Context, to common.Address, input hexutil.Bytes) (hexutil.Bytes, error) {
+	return n.eth.CallOffchain(ctx, TransactionArgs{To: &to, Input: &input}, n.config.Block, n.config.Fetcher)
+}
+
+// resolverOf returns the resolver of the node of name in the registry.
+func (n *ENS) resolverOf(ctx context.Context, name string) (common.Address, error) {
+	node := Namehash(name)
+	result, err := n.call(ctx, n.config.Registry, append(common.CopyBytes(ensResolverSelector), node[:]...))
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(result) < 32 {
+		return common.Address{}, fmt.Errorf("registry returned %d bytes", len(result))
+	}
+	return common.BytesToAddress(result[:32]), nil
+}
+
+// supportsExtended reports whether resolver implements the ENSIP-10 resolve
+// function, according to ERC-165.
+func (n *ENS) supportsExtended(ctx context.Context, resolver common.Address) (bool, error) {
+	input := hexutil.Bytes(append(common.CopyBytes(erc165Selector), common.RightPadBytes(ensResolveSelector, 32)...))
+	result, err := n.eth.Call(ctx, TransactionArgs{To: &resolver, Input: &input}, n.config.Block, nil, nil)
+	if errors.Is(err, ErrExecutionReverted) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(result) >= 32 && result[31] == 1, nil
+}
+
+// Resolver returns the resolver of a name and whether it implements ENSIP-10
+// wildcard resolution. The resolver of the closest ancestor is used for names
+// without one, provided it implements it.
+func (n *ENS) Resolver(ctx context.Context, name string) (common.Address, bool, error) {
+	name, err := ensName(name)
+	if err != nil {
+		return common.Address{}, false, err
+	}
+	return n.resolver(ctx, name)
+}
+
+func (n *ENS) resolver(ctx context.Context, name string) (common.Address, bool, error) {
+	for current := name; ; {
+		resolver, err := n.resolverOf(ctx, current)
+		if err != nil {
+			return common.Address{}, false, err
+		}
+		if resolver != (common.Address{}) {
+			extended, err := n.supportsExtended(ctx, resolver)
+			if err != nil {
+				return common.Address{}, false, err
+			}
+			if current != name && !extended {
+				break
+			}
+			return resolver, extended, nil
+		}
+		if current == "" {
+			break
+		}
+		if i := strings.IndexByte(current, '.'); i >= 0 {
+			current = current[i+1:]
+		} else {
+			current = ""
+		}
+	}
+	return common.Address{}, false, fmt.Errorf("%w: no resolver for %q", ErrENSNotFound, name)
+}
+
+// resolve calls a resolver function of the node of name, with input the
+// selector and arguments after the node, and returns its result.
+func (n *ENS) resolve(ctx context.Context, name string, selector []byte, args []byte) ([]byte, error) {
+	name, err := ensName(name)
+	if err != nil {
+		return nil, err
+	}
+	resolver, extended, err := n.resolver(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	node := Namehash(name)
+	input := append(append(common.CopyBytes(selector), node[:]...), args...)
+	if !extended {
+		return n.call(ctx, resolver, input)
+	}
+	encoded, err := DNSEncodeName(name)
+	if err != nil {
+		return nil, err
+	}
+	packed, err := ensResolveArgs.Pack(encoded, []byte(input))
+	if err != nil {
+		return nil, err
+	}
+	result, err := n.call(ctx, resolver, append(common.CopyBytes(ensResolveSelector), packed...))
+	if err != nil {
+		return nil, err
+	}
+	values, err := ensBytesArgs.Unpack(result)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resolve result: %w", err)
+	}
+	return values[0].([]byte), nil
+}
+
+// Address returns the Ethereum address of a name.
+func (n *ENS) Address(ctx context.Context, name string) (common.Address, error) {
+	result, err := n.resolve(ctx, name, ensAddrSelector, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(result) < 32 {
+		return common.Address{}, fmt.Errorf("addr returned %d bytes", len(result))
+	}
+	addr := common.BytesToAddress(result[:32])
+	if addr == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("%w: no address for %q", ErrENSNotFound, name)
+	}
+	return addr, nil
+}
+
+// ResolveAddress returns the address of nameOrAddress, a hex address, returned
+// as is, or an ENS name. It lets user input fill the address fields of
+// TransactionArgs and the Eth methods.
+func (n *ENS) ResolveAddress(ctx context.Context, nameOrAddress string) (common.Address, error) {
+	if common.IsHexAddress(nameOrAddress) {
+		return common.HexToAddress(nameOrAddress), nil
+	}
+	return n.Address(ctx, nameOrAddress)
+}
+
+// Name returns the primary name of addr, the name of its reverse record. The
+// name must resolve back to addr.
+func (n *ENS) Name(ctx context.Context, addr common.Address) (string, error) {
+	reverse := strings.ToLower(addr.Hex()[2:]) + ".addr.reverse"
+	result, err := n.resolve(ctx, reverse, ensNameSelector, nil)
+	if err != nil {
+		return "", err
+	}
+	name, err := unpackString(result)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", fmt.Errorf("%w: no reverse record for %s", ErrENSNotFound, addr.Hex())
+	}
+	normalized, err := ensName(name)
+	if err != nil {
+		return "", err
+	}
+	forward, err := n.Address(ctx, normalized)
+	if err != nil && !errors.Is(err, ErrENSNotFound) {
+		return "", err
+	}
+	if forward != addr {
+		return "", fmt.Errorf("%w: %q does not resolve back to %s", ErrENSNotFound, normalized, addr.Hex())
+	}
+	return normalized, nil
+}
+
+// Text returns the text record key of a name, such as "url" or "avatar", empty
+// if it is not set.
+func (n *ENS) Text(ctx context.Context, name, key string) (string, error) {
+	name, err := ensName(name)
+	if err != nil {
+		return "", err
+	}
+	packed, err := ensTextArgs.Pack(Namehash(name), key)
+	if err != nil {
+		return "", err
+	}
+	// the node is added back by resolve
+	result, err := n.resolve(ctx, name, ensTextSelector, packed[32:])
+	if err != nil {
+		return "", err
+	}
+	return unpackString(result)
+}
+
+// Contenthash returns the ENSIP-7 contenthash record of a name, empty if it
+// is not set.
+func (n *ENS) Contenthash(ctx context.Context, name string) ([]byte, error) {
+	result, err := n.resolve(ctx, name, ensContenthashSelector, nil)
+	if err != nil {
+		return nil, err
+	}
+	values, err := ensBytesArgs.Unpack(result)
+	if err != nil {
+		return nil, fmt.Errorf("invalid contenthash: %w", err)
+	}
+	return values[0].([]byte), nil
+}
+
+func unpackString(result []byte) (string, error) {
+	if len(result) == 0 {
+		return "", nil
+	}
+	values, err := ensStringArgs.Unpack(result)
+	if err != nil {
+		return "", fmt.Errorf("invalid string: %w", err)
+	}
+	return values[0].(string), nil
+}
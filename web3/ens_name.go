@@ -0,0 +1,269 @@
+package web3
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrInvalidENSName is returned for names FoldENSName rejects and names ENS
+// cannot resolve as given.
+var ErrInvalidENSName = errors.New("invalid ens name")
+
+// zero width joiner and the emoji presentation selector
+const (
+	ensZWJ  = '\u200d'
+	ensFE0F = '\ufe0f'
+)
+
+// ensMapped are characters the mapping replaces with others rather than
+// with their compatibility form.
+var ensMapped = map[rune]string{
+	'\u2019': "'", // right single quotation mark
+}
+
+// ensConfusables are the letters of scripts other than Latin that look like
+// a Latin letter. A label written only with them in a single such script
+// reads like a Latin one, such as "раураӏ" for "paypal".
+var ensConfusables = map[rune]bool{
+	// Cyrillic
+	'а': true, 'е': true, 'о': true, 'р': true, 'с': true, 'у': true, 'х': true,
+	'ѕ': true, 'і': true, 'ј': true, 'һ': true, 'ӏ': true, 'ү': true, 'ԁ': true,
+	'ԛ': true, 'ԝ': true,
+	// Greek
+	'ι': true, 'κ': true, 'ν': true, 'ο': true, 'ρ': true, 'υ': true,
+}
+
+// ensScriptSets are the scripts that may be mixed within a label, besides
+// the Common and Inherited ones every label may use.
+// These are the sets of the highly restrictive level of UTS #39, which all
+// allow Latin.
+var ensScriptSets = [][]*unicode.RangeTable{
+	{unicode.Latin, unicode.Han, unicode.Hiragana, unicode.Katakana}, // Japanese
+	{unicode.Latin, unicode.Han, unicode.Hangul},                     // Korean
+	{unicode.Latin, unicode.Han, unicode.Bopomofo},                   // Chinese
+}
+
+// FoldENSName folds an ENS name with the rules of the ENS name normalization
+// standard, ENSIP-15, that can be expressed without its data tables. It maps
+// characters to their compatibility form and lower cases them, drops the
+// emoji presentation selector and composes the result to NFC. Then it
+// validates every label: the allowed ASCII characters, leading underscores,
+// reserved label extensions, leading combining marks, disallowed characters,
+// scripts mixed within a label and labels made only of letters that look like
+// Latin ones. It is not a conforming implementation of the standard: it suits
+// checking user input, but its result may differ from the normalized name,
+// which is why ENS does not use it.
+func FoldENSName(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	if !utf8.ValidString(name) {
+		return "", fmt.Errorf("%w: not UTF-8", ErrInvalidENSName)
+	}
+	var mapped strings.Builder
+	for _, r := range name {
+		switch {
+		case r == ensFE0F:
+		case isENSEmoji(r):
+			mapped.WriteRune(r)
+		case ensMapped[r] != "":
+			mapped.WriteString(ensMapped[r])
+		default:
+			mapped.WriteString(strings.ToLower(norm.NFKC.String(string(r))))
+		}
+	}
+	labels := strings.Split(norm.NFC.String(mapped.String()), ".")
+	for i, label := range labels {
+		if err := validateENSLabel(label); err != nil {
+			return "", fmt.Errorf("%w: label %d %q: %v", ErrInvalidENSName, i, label, err)
+		}
+	}
+	return strings.Join(labels, "."), nil
+}
+
+// ensName prepares a name for resolution with the part of ENSIP-15 that is
+// exact without its data tables: ASCII letters are lower cased and ASCII
+// labels validated. Other labels are used as is, they must already be
+// normalized, as by the ENS manager, since any other mapping could hash them
+// to another node. Only their composition to NFC is checked.
+func ensName(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		label = strings.Map(func(r rune) rune {
+			if r >= 'A' && r <= 'Z' {
+				return r + 'a' - 'A'
+			}
+			return r
+		}, label)
+		var err error
+		if isASCII(label) {
+			err = validateENSLabel(label)
+		} else if !utf8.ValidString(label) || !norm.NFC.IsNormalString(label) {
+			err = errors.New("not normalized")
+		}
+		if err != nil {
+			return "", fmt.Errorf("%w: label %d %q: %v", ErrInvalidENSName, i, label, err)
+		}
+		labels[i] = label
+	}
+	return strings.Join(labels, "."), nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// isENSEmoji reports whether r is kept as is by the mapping: pictographs,
+// emoji modifiers and the joiners and tags of emoji sequences. Symbols with a
+// compatibility form, such as the squared letter 🄰, are mapped instead.
+func isENSEmoji(r rune) bool {
+	switch {
+	case r == ensZWJ || r == '\u20e3':
+		return true
+	case r >= 0x1f000 && r <= 0x1faff, // pictographs, emoticons, modifiers
+		r >= 0x2600 && r <= 0x27bf,   // symbols and dingbats
+		r >= 0xe0020 && r <= 0xe007f: // tags
+		return norm.NFKC.IsNormalString(string(r))
+	}
+	return false
+}
+
+func validateENSLabel(label string) error {
+	if label == "" {
+		return errors.New("empty label")
+	}
+	if isASCII(label) && len(label) >= 4 && label[2] == '-' && label[3] == '-' {
+		return errors.New("label extension")
+	}
+	runes := []rune(label)
+	if unicode.In(runes[0], unicode.Mn, unicode.Me, unicode.Mc) {
+		return errors.New("leading combining mark")
+	}
+	leading := true
+	confusable := true
+	var scripts []*unicode.RangeTable
+	for i, r := range runes {
+		if r == '_' {
+			if !leading {
+				return errors.New("underscore after the start")
+			}
+			continue
+		}
+		leading = false
+		switch {
+		case r < utf8.RuneSelf:
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '$' || r == '\'') {
+				return fmt.Errorf("disallowed character %q", r)
+			}
+			if r >= 'a' && r <= 'z' && !containsTable(scripts, unicode.Latin) {
+				scripts = append(scripts, unicode.Latin)
+			}
+		case r == ensZWJ:
+			if i == 0 || i == len(runes)-1 || !isENSEmoji(runes[i-1]) || !isENSEmoji(runes[i+1]) {
+				return errors.New("joiner outside an emoji sequence")
+			}
+		case isENSEmoji(r):
+		case r == utf8.RuneError || unicode.In(r, unicode.Cc, unicode.Cf, unicode.Co, unicode.Cs, unicode.Z) || !unicode.IsPrint(r):
+			return fmt.Errorf("disallowed character %U", r)
+		case unicode.IsLetter(r):
+			if script := ensScript(r); script != nil && !containsTable(scripts, script) {
+				scripts = append(scripts, script)
+			}
+			confusable = confusable && ensConfusables[r]
+		}
+	}
+	if len(scripts) > 1 && !mixableScripts(scripts) {
+		return errors.New("mixed scripts")
+	}
+	if len(scripts) == 1 && scripts[0] != unicode.Latin && confusable {
+		return errors.New("whole-script confusable")
+	}
+	return nil
+}
+
+// ensScript returns the script of the letter r, nil for Common and Inherited
+// letters.
+func ensScript(r rune) *unicode.RangeTable {
+	for name, table := range unicode.Scripts {
+		if name != "Common" && name != "Inherited" && unicode.Is(table, r) {
+			return table
+		}
+	}
+	return nil
+}
+
+func containsTable(tables []*unicode.RangeTable, table *unicode.RangeTable) bool {
+	for _, t := range tables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
+
+func mixableScripts(scripts []*unicode.RangeTable) bool {
+	for _, set := range ensScriptSets {
+		mixable := true
+		for _, script := range scripts {
+			if !containsTable(set, script) {
+				mixable = false
+				break
+			}
+		}
+		if mixable {
+			return true
+		}
+	}
+	return false
+}
+
+// Labelhash returns the keccak256 hash of an ENS label.
+func Labelhash(label string) common.Hash {
+	return crypto.Keccak256Hash([]byte(label))
+}
+
+// Namehash returns the ENS node of a name normalized with ENSIP-15.
+func Namehash(name string) common.Hash {
+	var node common.Hash
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		label := Labelhash(labels[i])
+		node = crypto.Keccak256Hash(node[:], label[:])
+	}
+	return node
+}
+
+// DNSEncodeName returns the DNS wire format of a normalized name, as passed
+// to the resolve function of ENSIP-10 wildcard resolvers.
+func DNSEncodeName(name string) ([]byte, error) {
+	if name == "" {
+		return []byte{0}, nil
+	}
+	encoded := make([]byte, 0, len(name)+2)
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 255 {
+			return nil, fmt.Errorf("%w: label of %d bytes", ErrInvalidENSName, len(label))
+		}
+		encoded = append(encoded, byte(len(label)))
+		encoded = append(encoded, label...)
+	}
+	return append(encoded, 0), nil
+}
@@ -0,0 +1,76 @@
+package web3_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/moonfdd/web3-go/web3"
+)
+
+func TestFoldENSName(t *testing.T) {
+	for input, want := range map[string]string{
+		"":               "",
+		"Nick.ETH":       "nick.eth",
+		"ｎｉｃｋ.eth":       "nick.eth",
+		"Straße.eth":     "straße.eth",
+		"💩.eth":          "💩.eth",
+		"❤\ufe0f.eth":    "❤.eth",
+		"👩\u200d💻.eth":   "👩\u200d💻.eth",
+		"_dmarc.foo.eth": "_dmarc.foo.eth",
+		"$money.eth":     "$money.eth",
+		"日本ひらがなカタカナ.eth": "日本ひらがなカタカナ.eth",
+		"cafe\u0301.eth": "caf\u00e9.eth",
+		"ΒΙΤΑΛΙΚ.eth":    "βιταλικ.eth",
+		"🄰bc.eth":        "abc.eth",
+		"ab’c.eth":       "ab'c.eth",
+		"москва.eth":     "москва.eth",
+		"abc中文.eth":      "abc中文.eth",
+		"nftマーケット.eth":   "nftマーケット.eth",
+		"a한국.eth":        "a한국.eth",
+	} {
+		if got, err := web3.FoldENSName(input); err != nil || got != want {
+			t.Errorf("FoldENSName(%q) = %q %v, want %q", input, got, err, want)
+		}
+	}
+	for _, input := range []string{
+		"a..eth",
+		".eth",
+		"a b.eth",
+		"a_b.eth",
+		"xn--ls8h.eth",
+		"p\u0430ypal.eth", // Cyrillic a
+		"раураӏ.eth",      // all Cyrillic, reads as paypal
+		"ορ.eth",          // all Greek, reads as op
+		"\u0301a.eth",
+		"a\u200bb.eth",
+		"a\u200d.eth",
+		"a!.eth",
+		"\xff.eth",
+	} {
+		if got, err := web3.FoldENSName(input); !errors.Is(err, web3.ErrInvalidENSName) {
+			t.Errorf("FoldENSName(%q) = %q %v, want %v", input, got, err, web3.ErrInvalidENSName)
+		}
+	}
+}
+
+func TestNamehash(t *testing.T) {
+	for name, want := range map[string]string{
+		"":        "0x0000000000000000000000000000000000000000000000000000000000000000",
+		"eth":     "0x93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae",
+		"foo.eth": "0xde9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f",
+	} {
+		if got := web3.Namehash(name); got != common.HexToHash(want) {
+			t.Errorf("Namehash(%q) = %s, want %s", name, got, want)
+		}
+	}
+	encoded, err := web3.DNSEncodeName("foo.eth")
+	if want := []byte("\x03foo\x03eth\x00"); err != nil || !bytes.Equal(encoded, want) {
+		t.Errorf("DNSEncodeName = %x %v, want %x", encoded, err, want)
+	}
+	if _, err := web3.DNSEncodeName("foo..eth"); err == nil {
+		t.Error("DNSEncodeName of an empty label succeeded")
+	}
+}
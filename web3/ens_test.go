@@ -0,0 +1,234 @@
+package web3_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+)
+
+func abiArgs(types ...string) abi.Arguments {
+	var args abi.Arguments
+	for _, t := range types {
+		typ, _ := abi.NewType(t, "", nil)
+		args = append(args, abi.Argument{Type: typ})
+	}
+	return args
+}
+
+func selector(signature string) string {
+	return string(crypto.Keccak256([]byte(signature))[:4])
+}
+
+// offchainRevert is a revert carrying return data.
+type offchainRevert struct{ data []byte }
+
+func (offchainRevert) Error() string            { return "execution reverted" }
+func (offchainRevert) ErrorCode() int           { return 3 }
+func (e offchainRevert) ErrorData() interface{} { return hexutil.Encode(e.data) }
+
+// ensService is a stub node with an ENS registry, an onchain resolver and an
+// offchain wildcard resolver.
+type ensService struct {
+	registry, resolver, offchain common.Address
+	resolvers                    map[common.Hash]common.Address
+	addrs                        map[common.Hash]common.Address
+	names                        map[common.Hash]string
+	texts                        map[string]string
+	contenthash                  []byte
+}
+
+func newENSService() *ensService {
+	s := &ensService{
+		registry:  common.HexToAddress("0x00000000000000000000000000000000000000e0"),
+		resolver:  common.HexToAddress("0x00000000000000000000000000000000000000e1"),
+		offchain:  common.HexToAddress("0x00000000000000000000000000000000000000e2"),
+		resolvers: make(map[common.Hash]common.Address),
+		addrs:     make(map[common.Hash]common.Address),
+		names:     make(map[common.Hash]string),
+		texts:     make(map[string]string),
+	}
+	return s
+}
+
+func (s *ensService) Call(args map[string]json.RawMessage, block, overrides, blockOverrides *json.RawMessage) (hexutil.Bytes, error) {
+	var to common.Address
+	var input hexutil.Bytes
+	json.Unmarshal(args["to"], &to)
+	json.Unmarshal(args["input"], &input)
+	if len(input) < 4 {
+		return nil, offchainRevert{}
+	}
+	sel, data := string(input[:4]), input[4:]
+	var node common.Hash
+	if len(data) >= 32 {
+		node = common.BytesToHash(data[:32])
+	}
+	switch {
+	case to == s.registry && sel == selector("resolver(bytes32)"):
+		return common.LeftPadBytes(s.resolvers[node].Bytes(), 32), nil
+	case sel == selector("supportsInterface(bytes4)"):
+		extended := to == s.offchain && bytes.Equal(data[:4], []byte{0x90, 0x61, 0xb9, 0x23})
+		if extended {
+			return common.LeftPadBytes([]byte{1}, 32), nil
+		}
+		return make([]byte, 32), nil
+	case to == s.resolver:
+		return s.answer(sel, node, data)
+	case to == s.offchain && sel == selector("resolve(bytes,bytes)"):
+		values, err := abiArgs("bytes", "bytes").Unpack(data)
+		if err != nil {
+			return nil, offchainRevert{}
+		}
+		revert, _ := abiArgs("address", "string[]", "bytes", "bytes4", "bytes").Pack(
+			s.offchain,
+			[]string{"https://down.example/{sender}/{data}.json", "https://gateway.example/lookup"},
+			values[1].([]byte),
+			[4]byte([]byte(selector("resolveWithProof(bytes,bytes)"))),
+			values[0].([]byte),
+		)
+		return nil, offchainRevert{append([]byte(selector("OffchainLookup(address,string[],bytes,bytes4,bytes)")), revert...)}
+	case to == s.offchain && sel == selector("resolveWithProof(bytes,bytes)"):
+		values, err := abiArgs("bytes", "bytes").Unpack(data)
+		if err != nil {
+			return nil, offchainRevert{}
+		}
+		result, _ := abiArgs("bytes").Pack(values[0].([]byte))
+		return result, nil
+	}
+	return hexutil.Bytes{}, nil
+}
+
+// answer serves the resolver functions of the onchain resolver, and the
+// gateway of the offchain one.
+func (s *ensService) answer(sel string, node common.Hash, data []byte) (hexutil.Bytes, error) {
+	switch sel {
+	case selector("addr(bytes32)"):
+		return common.LeftPadBytes(s.addrs[node].Bytes(), 32), nil
+	case selector("name(bytes32)"):
+		return abiArgs("string").Pack(s.names[node])
+	case selector("text(bytes32,string)"):
+		values, err := abiArgs("bytes32", "string").Unpack(data)
+		if err != nil {
+			return nil, offchainRevert{}
+		}
+		return abiArgs("string").Pack(s.texts[values[1].(string)])
+	case selector("contenthash(bytes32)"):
+		return abiArgs("bytes").Pack(s.contenthash)
+	}
+	return nil, offchainRevert{}
+}
+
+func TestENS(t *testing.T) {
+	s := newENSService()
+	alice := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	bob := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	reverse := func(addr common.Address) common.Hash {
+		return web3.Namehash(strings.ToLower(addr.Hex()[2:]) + ".addr.reverse")
+	}
+	s.resolvers[web3.Namehash("alice.eth")] = s.resolver
+	s.resolvers[reverse(alice)] = s.resolver
+	s.resolvers[reverse(bob)] = s.resolver
+	s.resolvers[web3.Namehash("offchain.eth")] = s.offchain
+	s.addrs[web3.Namehash("alice.eth")] = alice
+	s.resolvers[web3.Namehash("café.eth")] = s.resolver
+	s.addrs[web3.Namehash("café.eth")] = alice
+	s.names[reverse(alice)] = "Alice.eth"
+	s.names[reverse(bob)] = "alice.eth"
+	s.texts["url"] = "https://alice.example"
+	s.contenthash = hexutil.MustDecode("0xe3010170122029f2d17be6139079dc48696d1f582a8530eb9805b561eda517e22a892c7e3f1f")
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", s); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	client := rpc.DialInProc(server)
+	t.Cleanup(client.Close)
+	w := web3.NewWeb3(client)
+	ctx := context.Background()
+
+	// the gateway resolves every name under offchain.eth to bob, after the
+	// first one failed
+	var requests []string
+	fetcher := web3.OffchainFetcherFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Host)
+		recorder := httptest.NewRecorder()
+		if req.URL.Host == "down.example" {
+			recorder.WriteHeader(http.StatusBadGateway)
+			return recorder.Result(), nil
+		}
+		var body struct {
+			Data   hexutil.Bytes  `json:"data"`
+			Sender common.Address `json:"sender"`
+		}
+		if raw, _ := io.ReadAll(req.Body); json.Unmarshal(raw, &body) != nil || body.Sender != s.offchain {
+			recorder.WriteHeader(http.StatusBadRequest)
+			return recorder.Result(), nil
+		}
+		json.NewEncoder(recorder).Encode(map[string]hexutil.Bytes{"data": common.LeftPadBytes(bob.Bytes(), 32)})
+		return recorder.Result(), nil
+	})
+	ens := web3.NewENS(w, web3.ENSConfig{Registry: s.registry, Fetcher: fetcher})
+
+	if addr, err := ens.Address(ctx, "Alice.ETH"); err != nil || addr != alice {
+		t.Errorf("Address = %v %v, want %v", addr, err, alice)
+	}
+	if addr, err := ens.ResolveAddress(ctx, bob.Hex()); err != nil || addr != bob {
+		t.Errorf("ResolveAddress of an address = %v %v", addr, err)
+	}
+	if name, err := ens.Name(ctx, alice); err != nil || name != "alice.eth" {
+		t.Errorf("Name = %q %v, want alice.eth", name, err)
+	}
+	if name, err := ens.Name(ctx, bob); !errors.Is(err, web3.ErrENSNotFound) {
+		t.Errorf("Name of a reverse record not resolving back = %q %v, want %v", name, err, web3.ErrENSNotFound)
+	}
+	if text, err := ens.Text(ctx, "alice.eth", "url"); err != nil || text != "https://alice.example" {
+		t.Errorf("Text = %q %v", text, err)
+	}
+	if hash, err := ens.Contenthash(ctx, "alice.eth"); err != nil || !bytes.Equal(hash, s.contenthash) {
+		t.Errorf("Contenthash = %x %v", hash, err)
+	}
+	if _, err := ens.Address(ctx, "nobody.eth"); !errors.Is(err, web3.ErrENSNotFound) {
+		t.Errorf("Address of an unknown name = %v, want %v", err, web3.ErrENSNotFound)
+	}
+	if _, err := ens.Address(ctx, "sub.alice.eth"); !errors.Is(err, web3.ErrENSNotFound) {
+		t.Errorf("Address under a resolver without wildcard = %v, want %v", err, web3.ErrENSNotFound)
+	}
+	// names other than ASCII are hashed as given, never folded
+	if addr, err := ens.Address(ctx, "CAFé.eth"); err != nil || addr != alice {
+		t.Errorf("Address of a normalized name = %v %v, want %v", addr, err, alice)
+	}
+	if _, err := ens.Address(ctx, "ｃafé.eth"); !errors.Is(err, web3.ErrENSNotFound) {
+		t.Errorf("Address of a name with a fullwidth letter = %v, want %v", err, web3.ErrENSNotFound)
+	}
+	for _, name := range []string{"cafe\u0301.eth", "a b.eth", "xn--ls8h.eth", ".eth"} {
+		if _, err := ens.Address(ctx, name); !errors.Is(err, web3.ErrInvalidENSName) {
+			t.Errorf("Address(%q) = %v, want %v", name, err, web3.ErrInvalidENSName)
+		}
+	}
+
+	resolver, extended, err := ens.Resolver(ctx, "bob.offchain.eth")
+	if err != nil || resolver != s.offchain || !extended {
+		t.Errorf("Resolver = %v %v %v", resolver, extended, err)
+	}
+	if addr, err := ens.ResolveAddress(ctx, "bob.offchain.eth"); err != nil || addr != bob {
+		t.Errorf("Address through the gateway = %v %v, want %v", addr, err, bob)
+	}
+	if want := []string{"GET down.example", "POST gateway.example"}; len(requests) != 2 || requests[0] != want[0] || requests[1] != want[1] {
+		t.Errorf("gateway requests = %v, want %v", requests, want)
+	}
+}
@@ -0,0 +1,238 @@
+package web3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Error kinds returned by the namespace methods. Match them with errors.Is:
+//
+//	_, err := w.Eth.SendRawTransaction(ctx, raw)
+//	if errors.Is(err, web3.ErrNonceTooLow) { ... }
+var (
+	ErrNonceTooLow       = errors.New("nonce too low")
+	ErrUnderpriced       = errors.New("transaction underpriced")
+	ErrAlreadyKnown      = errors.New("already known")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrMethodNotFound    = errors.New("method not found")
+	ErrRateLimited       = errors.New("rate limited")
+	ErrLimitExceeded     = errors.New("result limit exceeded") // too many results or too wide a block range
+	ErrExecutionReverted = errors.New("execution reverted")
+	ErrFilterNotFound    = errors.New("filter not found")
+)
+
+// errorPatterns maps lower-cased message fragments, as worded by Geth,
+// Erigon, Nethermind and Besu, to an error kind. The first match wins, so
+// more specific fragments come first.
+var errorPatterns = []struct {
+	fragment string
+	kind     error
+}{
+	// nonce
+	{"nonce too low", ErrNonceTooLow},    // geth, erigon, besu
+	{"nonce is too low", ErrNonceTooLow}, // nethermind, openethereum
+	{"oldnonce", ErrNonceTooLow},         // nethermind
+	{"nonce_too_low", ErrNonceTooLow},    // besu
+	{"nonce has already been used", ErrNonceTooLow},
+	// already known, checked before underpriced as some clients say both
+	{"already known", ErrAlreadyKnown},     // geth, erigon
+	{"known transaction", ErrAlreadyKnown}, // geth < 1.9, besu
+	{"alreadyknown", ErrAlreadyKnown},      // nethermind
+	{"already_known", ErrAlreadyKnown},     // besu
+	{"already exists", ErrAlreadyKnown},    // erigon txpool
+	{"already imported", ErrAlreadyKnown},  // openethereum
+	// underpriced
+	{"underpriced", ErrUnderpriced}, // geth, erigon, besu
+	{"feetoolow", ErrUnderpriced},   // nethermind
+	{"fee too low", ErrUnderpriced},
+	{"gas_price_too_low", ErrUnderpriced}, // besu
+	{"gas price below", ErrUnderpriced},   // besu
+	{"gas price too low", ErrUnderpriced},
+	{"less than block base fee", ErrUnderpriced}, // geth
+	{"pricing too low", ErrUnderpriced},
+	// funds
+	{"insufficient funds", ErrInsufficientFunds}, // geth, erigon
+	{"insufficientfunds", ErrInsufficientFunds},  // nethermind
+	{"insufficient balance", ErrInsufficientFunds},
+	{"upfront cost exceeds", ErrInsufficientFunds}, // besu
+	{"upfront_cost_exceeds_balance", ErrInsufficientFunds},
+	// reverts
+	{"execution reverted", ErrExecutionReverted},                                // geth, erigon, besu
+	{"reverted 0x", ErrExecutionReverted},                                       // nethermind: Reverted 0x...
+	{"transaction reverted", ErrExecutionReverted},                              // hardhat
+	{"vm exception while processing transaction: revert", ErrExecutionReverted}, // hardhat, ganache
+	// filters
+	{"filter not found", ErrFilterNotFound}, // geth, erigon, besu
+	{"filter with id", ErrFilterNotFound},   // nethermind: Filter with id: '...' does not exist.
+	// methods
+	{"does not exist/is not available", ErrMethodNotFound}, // geth
+	{"method not found", ErrMethodNotFound},
+	{"method not supported", ErrMethodNotFound},
+	{"unsupported method", ErrMethodNotFound},
+	// result limits, checked before rate limits as both use code -32005
+	{"query returned more than", ErrLimitExceeded}, // infura, geth based providers
+	{"response size exceeded", ErrLimitExceeded},   // alchemy
+	{"block range", ErrLimitExceeded},              // alchemy, quicknode, ankr
+	{"range limit", ErrLimitExceeded},
+	{"too many results", ErrLimitExceeded},
+	{"too many logs", ErrLimitExceeded},
+	// rate limits
+	{"rate limit", ErrRateLimited},
+	{"too many requests", ErrRateLimited},
+	{"request rate exceeded", ErrRateLimited}, // infura
+	{"request limit", ErrRateLimited},
+	{"compute units", ErrRateLimited}, // alchemy
+}
+
+// JSON-RPC error codes with a fixed meaning.
+const (
+	codeMethodNotFound   = -32601
+	codeLimitExceeded    = -32005
+	codeExecutionReverts = 3
+)
+
+// ClassifyError returns the error kind of err, one of the Err* variables of
+// this package, or nil if err does not match any of them.
+func ClassifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var callErr *CallError
+	if errors.As(err, &callErr) {
+		return callErr.Kind
+	}
+	kind := classifyMessage(err.Error())
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.ErrorCode() {
+		case codeMethodNotFound:
+			return ErrMethodNotFound
+		case codeLimitExceeded:
+			// providers use the code for result limits as well
+			if kind == ErrLimitExceeded {
+				return kind
+			}
+			return ErrRateLimited
+		case codeExecutionReverts:
+			return ErrExecutionReverted
+		}
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusTooManyRequests {
+		return ErrRateLimited
+	}
+	return kind
+}
+
+func classifyMessage(msg string) error {
+	msg = strings.ToLower(msg)
+	for _, p := range errorPatterns {
+		if strings.Contains(msg, p.fragment) {
+			return p.kind
+		}
+	}
+	return nil
+}
+
+// RevertData returns the return data of a reverted call, such as a custom
+// error or an EIP-3668 OffchainLookup, carried by err as JSON-RPC error data.
+// It is false if err is not a revert or has no data.
+func RevertData(err error) ([]byte, bool) {
+	if !errors.Is(err, ErrExecutionReverted) {
+		return nil, false
+	}
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return nil, false
+	}
+	encoded, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return nil, false
+	}
+	data, err := hexutil.Decode(encoded)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// CallError is the error returned by the namespace methods. It records the
+// JSON-RPC method that failed and unwraps both to the original error, so that
+// errors.As still finds rpc.Error and rpc.DataError, and to the classified
+// error kind.
+type CallError struct {
+	Method string
+	Kind   error // one of the Err* kinds, nil if unclassified
+	Err    error
+}
+
+func (e *CallError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Method, e.Err)
+}
+
+func (e *CallError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Err, e.Kind}
+}
+
+func newCallError(method string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var callErr *CallError
+	if errors.As(err, &callErr) && callErr.Method == method {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return &CallError{Method: method, Err: err}
+	}
+	return &CallError{Method: method, Kind: ClassifyError(err), Err: err}
+}
+
+// errorClient wraps the Client of a namespace and turns every error into a
+// *CallError.
+type errorClient struct {
+	Client
+}
+
+func withCallErrors(c Client) Client {
+	if _, ok := c.(*errorClient); ok || c == nil {
+		return c
+	}
+	return &errorClient{c}
+}
+
+// unwrapClient returns the client that was handed to NewWeb3 or to the
+// namespace constructor.
+func unwrapClient(c Client) Client {
+	for {
+		switch w := c.(type) {
+		case *errorClient:
+			c = w.Client
+		case *gatedClient:
+			c = w.Client
+		default:
+			return c
+		}
+	}
+}
+
+func (c *errorClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return newCallError(method, c.Client.CallContext(ctx, result, method, args...))
+}
+
+func (c *errorClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	err := c.Client.BatchCallContext(ctx, b)
+	for i := range b {
+		b[i].Error = newCallError(b[i].Method, b[i].Error)
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package web3_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+)
+
+// codeError is a JSON-RPC error with a code, as returned by rpc.Client.
+type codeError struct {
+	code int
+	msg  string
+}
+
+func (e codeError) Error() string  { return e.msg }
+func (e codeError) ErrorCode() int { return e.code }
+
+func TestClassifyError(t *testing.T) {
+	for _, test := range []struct {
+		err  error
+		want error
+	}{
+		{nil, nil},
+		{errors.New("nonce too low: next nonce 5, tx nonce 4"), web3.ErrNonceTooLow},
+		{codeError{-32010, "OldNonce"}, web3.ErrNonceTooLow},
+		{errors.New("already known"), web3.ErrAlreadyKnown},
+		{errors.New("replacement transaction underpriced"), web3.ErrUnderpriced},
+		{errors.New("insufficient funds for gas * price + value"), web3.ErrInsufficientFunds},
+		{codeError{3, "execution reverted: paused"}, web3.ErrExecutionReverted},
+		{codeError{-32015, "Reverted 0x08c379a0"}, web3.ErrExecutionReverted},
+		{errors.New("VM Exception while processing transaction: reverted with reason string 'no'"), web3.ErrExecutionReverted},
+		{errors.New("Transaction reverted without a reason string"), web3.ErrExecutionReverted},
+		{errors.New("filter not found"), web3.ErrFilterNotFound},
+		{codeError{-32601, "the method eth_foo does not exist/is not available"}, web3.ErrMethodNotFound},
+		{codeError{-32005, "query returned more than 10000 results"}, web3.ErrLimitExceeded},
+		{codeError{-32602, "Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range"}, web3.ErrLimitExceeded},
+		{codeError{-32005, "Requested range exceeds maximum range limit"}, web3.ErrLimitExceeded},
+		{codeError{-32005, "project ID request rate exceeded"}, web3.ErrRateLimited},
+		{codeError{-32005, "daily request count exceeded"}, web3.ErrRateLimited},
+		{rpc.HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}, web3.ErrRateLimited},
+		{errors.New("Your app has exceeded its compute units per second capacity"), web3.ErrRateLimited},
+		// words that merely contain a fragment
+		{errors.New("the proposal was reverted by the operator"), nil},
+		{errors.New("gas limit exceeded"), nil},
+		{fmt.Errorf("eth_call: %w", context.DeadlineExceeded), nil},
+		{&web3.CallError{Method: "eth_call", Kind: web3.ErrUnderpriced, Err: errors.New("x")}, web3.ErrUnderpriced},
+	} {
+		if got := web3.ClassifyError(test.err); got != test.want {
+			t.Errorf("ClassifyError(%v) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
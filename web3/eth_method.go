@@ -2,6 +2,9 @@ package web3
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -17,9 +20,12 @@ import (
 // from web3ext.go
 // method
 func (e *Eth) ChainID(ctx context.Context) (*big.Int, error) {
-	var result *big.Int
+	var result hexutil.Big
 	err := e.c.CallContext(ctx, &result, "eth_chainId")
-	return result, err
+	if err != nil {
+		return nil, err
+	}
+	return result.ToInt(), nil
 }
 
 // Sign calculates an ECDSA signature for:
@@ -51,6 +57,17 @@ func (e *Eth) Resend(ctx context.Context, sendArgs TransactionArgs, gasPrice *he
 	return result, err
 }
 
+// SignTypedDataV4 signs EIP-712 typed data with the key of addr. Geth does not
+// serve the method, wallets and development nodes do. The signature can be
+// checked locally with RecoverTypedData.
+// from anvil, hardhat
+// method
+func (e *Eth) SignTypedDataV4(ctx context.Context, addr common.Address, data *TypedData) (hexutil.Bytes, error) {
+	var result hexutil.Bytes
+	err := e.c.CallContext(ctx, &result, "eth_signTypedData_v4", addr, data)
+	return result, err
+}
+
 // SignTransactionResult represents a RLP encoded signed transaction.
 type SignTransactionResult struct {
 	Raw hexutil.Bytes      `json:"raw"`
@@ -59,11 +76,15 @@ type SignTransactionResult struct {
 
 // SignTransaction will sign the given transaction with the from account.
 // The node needs to have the private key of the account corresponding with
-// the given from address and it needs to be unlocked.
+// the given from address and it needs to be unlocked. With a Signer set by
+// WithSigner the missing fields are filled in and the Signer signs instead.
 // from TransactionAPI
 // from web3ext.go
 // method
 func (e *Eth) SignTransaction(ctx context.Context, args TransactionArgs) (*SignTransactionResult, error) {
+	if e.signer != nil {
+		return e.signTransaction(ctx, args)
+	}
 	var result *SignTransactionResult
 	err := e.c.CallContext(ctx, &result, "eth_signTransaction", args)
 	return result, err
@@ -193,8 +214,18 @@ func (e *Eth) GetRawTransactionByHash(ctx context.Context, hash common.Hash) (he
 	return result, err
 }
 
-// getRawTransactionFromBlock
-// todo
+// GetRawTransactionFromBlock returns the bytes of the transaction at the given
+// index of a block, given by hash or by number.
+// from TransactionAPI
+// from web3.js
+// method
+func (e *Eth) GetRawTransactionFromBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, index hexutil.Uint) (hexutil.Bytes, error) {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		return e.GetRawTransactionByBlockHashAndIndex(ctx, hash, index)
+	}
+	number, _ := blockNrOrHash.Number()
+	return e.GetRawTransactionByBlockNumberAndIndex(ctx, number, index)
+}
 
 // GetRawTransactionByBlockHashAndIndex returns the bytes of the transaction for the given block hash and index.
 // from TransactionAPI
@@ -285,12 +316,50 @@ func (e *Eth) FeeHistory(ctx context.Context, blockCount math.HexOrDecimal64, la
 // from web3ext.go
 // method
 func (e *Eth) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([]*types.Log, error) {
-
+	arg, err := toFilterArg(crit)
+	if err != nil {
+		return nil, err
+	}
 	var result []*types.Log
-	err := e.c.CallContext(ctx, &result, "eth_getLogs", crit)
+	err = e.c.CallContext(ctx, &result, "eth_getLogs", arg)
 	return result, err
 }
 
+// toFilterArg encodes crit the way the node decodes it. FilterCriteria has no
+// MarshalJSON of its own and would send its block numbers as decimals.
+func toFilterArg(crit filters.FilterCriteria) (interface{}, error) {
+	arg := map[string]interface{}{
+		"address": crit.Addresses,
+		"topics":  crit.Topics,
+	}
+	if crit.BlockHash != nil {
+		if crit.FromBlock != nil || crit.ToBlock != nil {
+			return nil, errors.New("cannot specify both BlockHash and FromBlock/ToBlock")
+		}
+		arg["blockHash"] = *crit.BlockHash
+		return arg, nil
+	}
+	if crit.FromBlock == nil {
+		arg["fromBlock"] = "0x0"
+	} else {
+		arg["fromBlock"] = toBlockNumArg(crit.FromBlock)
+	}
+	arg["toBlock"] = toBlockNumArg(crit.ToBlock)
+	return arg, nil
+}
+
+// toBlockNumArg encodes a block number, nil meaning latest and negative
+// numbers the rpc.BlockNumber tags.
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	if number.Sign() >= 0 {
+		return hexutil.EncodeBig(number)
+	}
+	return rpc.BlockNumber(number.Int64()).String()
+}
+
 // BlockOverrides is a set of header fields to override.
 type BlockOverrides struct {
 	Number      *hexutil.Big
@@ -329,20 +398,66 @@ func (e *Eth) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumbe
 	return result, err
 }
 
-// TransactionSender wraps transaction sending. The SendTransaction method injects a
-// signed transaction into the pending transaction pool for execution. If the transaction
-// was a contract creation, the TransactionReceipt method can be used to retrieve the
-// contract address after the transaction has been mined.
-//
-// The transaction must be signed and have a valid nonce to be included. Consumers of the
-// API can use package accounts to maintain local private keys and need can retrieve the
-// next available nonce using PendingNonceAt.
-// from TransactionSender
+// GetBalance returns the amount of wei for the given address in the state of the
+// given block number.
+// from BlockChainAPI
 // from web3.js
 // method
-func (e *Eth) SendTransaction(ctx context.Context, tx *types.Transaction) (common.Hash, error) {
+func (e *Eth) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	var result *hexutil.Big
+	err := e.c.CallContext(ctx, &result, "eth_getBalance", address, blockNrOrHash)
+	return result, err
+}
+
+// GetCode returns the code stored at the given address in the state for the given
+// block number.
+// from BlockChainAPI
+// from web3.js
+// method
+func (e *Eth) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	var result hexutil.Bytes
+	err := e.c.CallContext(ctx, &result, "eth_getCode", address, blockNrOrHash)
+	return result, err
+}
+
+// GetTransactionCount returns the number of transactions the given address has sent for the given block number
+// from TransactionAPI
+// from web3.js
+// method
+func (e *Eth) GetTransactionCount(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Uint64, error) {
+	var result hexutil.Uint64
+	err := e.c.CallContext(ctx, &result, "eth_getTransactionCount", address, blockNrOrHash)
+	return result, err
+}
+
+// SendTransaction creates a transaction for the given argument, sign it and submit it to the
+// transaction pool. The from account must be managed and unlocked by the node,
+// unless a Signer was set by WithSigner: the transaction is then signed by it
+// and sent with eth_sendRawTransaction.
+// from TransactionAPI
+// from web3.js
+// method
+func (e *Eth) SendTransaction(ctx context.Context, args TransactionArgs) (common.Hash, error) {
+	if e.signer != nil {
+		signed, err := e.signTransaction(ctx, args)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		return e.SendRawTransaction(ctx, signed.Raw)
+	}
 	var result common.Hash
-	err := e.c.CallContext(ctx, &result, "eth_sendTransaction", tx)
+	err := e.c.CallContext(ctx, &result, "eth_sendTransaction", args)
+	return result, err
+}
+
+// SendRawTransaction will add the signed transaction to the transaction pool.
+// The sender is responsible for signing the transaction and using the correct nonce.
+// from TransactionAPI
+// from web3.js
+// method
+func (e *Eth) SendRawTransaction(ctx context.Context, input hexutil.Bytes) (common.Hash, error) {
+	var result common.Hash
+	err := e.c.CallContext(ctx, &result, "eth_sendRawTransaction", input)
 	return result, err
 }
 
@@ -355,11 +470,77 @@ func (e *Eth) SendTransaction(ctx context.Context, tx *types.Transaction) (commo
 // eth_compileSerpent
 // todo
 
-// eth_submitWork
-// todo
+// WorkPackage is the proof-of-work package returned by eth_getWork.
+type WorkPackage struct {
+	HeaderHash  common.Hash // hash of the header without nonce and mix digest
+	SeedHash    common.Hash // seed hash of the ethash DAG
+	Target      common.Hash // boundary condition, 2^256 / difficulty
+	BlockNumber uint64      // only sent by nodes that return four fields
+}
+
+func (w *WorkPackage) UnmarshalJSON(input []byte) error {
+	var fields []string
+	if err := json.Unmarshal(input, &fields); err != nil {
+		return err
+	}
+	if len(fields) < 3 {
+		return fmt.Errorf("work package has %d fields, want at least 3", len(fields))
+	}
+	for i, h := range []*common.Hash{&w.HeaderHash, &w.SeedHash, &w.Target} {
+		if err := h.UnmarshalText([]byte(fields[i])); err != nil {
+			return fmt.Errorf("work package field %d: %w", i, err)
+		}
+	}
+	if len(fields) > 3 {
+		number, err := hexutil.DecodeUint64(fields[3])
+		if err != nil {
+			return fmt.Errorf("work package block number: %w", err)
+		}
+		w.BlockNumber = number
+	}
+	return nil
+}
+
+func (w WorkPackage) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]string{w.HeaderHash.Hex(), w.SeedHash.Hex(), w.Target.Hex(), hexutil.EncodeUint64(w.BlockNumber)})
+}
+
+// GetWork returns a work package for an external miner.
+// from API
+// from web3.js
+// method
+func (e *Eth) GetWork(ctx context.Context) (*WorkPackage, error) {
+	var result *WorkPackage
+	err := e.c.CallContext(ctx, &result, "eth_getWork")
+	return result, err
+}
 
-// eth_getWork
-// todo
+// SubmitWork can be used by external miner to submit their POW solution.
+// It returns an indication if the work was accepted.
+// Note either an invalid solution, a stale work a non-existent work will return false.
+// from API
+// from web3.js
+// method
+func (e *Eth) SubmitWork(ctx context.Context, nonce types.BlockNonce, hash, digest common.Hash) (bool, error) {
+	var result bool
+	err := e.c.CallContext(ctx, &result, "eth_submitWork", nonce, hash, digest)
+	return result, err
+}
+
+// SubmitHashrate can be used for remote miners to submit their hash rate.
+// This enables the node to report the combined hash rate of all miners
+// which submit work through this node.
+//
+// It accepts the miner hash rate and an identifier which must be unique
+// between nodes.
+// from API
+// from web3.js
+// method
+func (e *Eth) SubmitHashrate(ctx context.Context, rate hexutil.Uint64, id common.Hash) (bool, error) {
+	var result bool
+	err := e.c.CallContext(ctx, &result, "eth_submitHashrate", rate, id)
+	return result, err
+}
 
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with eth_getFilterChanges.
@@ -401,8 +582,12 @@ func (e *Eth) NewPendingTransactionFilter(ctx context.Context, fullTx *bool) (rp
 // from web3.js
 // method
 func (e *Eth) NewFilter(ctx context.Context, crit filters.FilterCriteria) (rpc.ID, error) {
+	arg, err := toFilterArg(crit)
+	if err != nil {
+		return "", err
+	}
 	var result rpc.ID
-	err := e.c.CallContext(ctx, &result, "eth_newFilter", crit)
+	err = e.c.CallContext(ctx, &result, "eth_newFilter", arg)
 	return result, err
 }
 
@@ -0,0 +1,246 @@
+package web3_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+// storeCode deploys a contract that stores the first calldata word in slot 0
+// and logs it with topic 1.
+var storeCode = common.FromHex("601280600b6000396000f3" + "60003580600055600052600160206000a100")
+
+// deployStore deploys the store contract, calls it with value and commits.
+func deployStore(t *testing.T, node *web3test.Node, value common.Hash) (common.Address, *types.Transaction) {
+	t.Helper()
+	deploy := node.SendTx(t, nil, nil, storeCode)
+	contract := crypto.CreateAddress(node.Account, deploy.Nonce())
+	call := node.SendTx(t, &contract, nil, value.Bytes())
+	node.Commit(t)
+	return contract, call
+}
+
+func TestEthDevNode(t *testing.T) {
+	node := web3test.New(t)
+	w := node.Web3
+	ctx := context.Background()
+
+	if id, err := w.Eth.ChainID(ctx); err != nil || id.Cmp(params.AllDevChainProtocolChanges.ChainID) != 0 {
+		t.Errorf("ChainID = %v %v", id, err)
+	}
+	if coinbase, err := w.Eth.Coinbase(ctx); err != nil || coinbase != node.Account {
+		t.Errorf("Coinbase = %v %v, want %v", coinbase, err, node.Account)
+	}
+	if accounts, err := w.Eth.Accounts(ctx); err != nil || len(accounts) != 1 || accounts[0] != node.Account {
+		t.Errorf("Accounts = %v %v", accounts, err)
+	}
+	if price, err := w.Eth.GasPrice(ctx); err != nil || price.ToInt().Sign() <= 0 {
+		t.Errorf("GasPrice = %v %v", price, err)
+	}
+	if tip, err := w.Eth.MaxPriorityFeePerGas(ctx); err != nil || tip == nil {
+		t.Errorf("MaxPriorityFeePerGas = %v %v", tip, err)
+	}
+	if _, err := w.Eth.Syncing(ctx); err != nil {
+		t.Errorf("Syncing: %v", err)
+	}
+	if _, err := w.Eth.Mining(ctx); err != nil {
+		t.Errorf("Mining: %v", err)
+	}
+	if _, err := w.Eth.Hashrate(ctx); err != nil {
+		t.Errorf("Hashrate: %v", err)
+	}
+
+	value := common.HexToHash("0x2a")
+	contract, call := deployStore(t, node, value)
+	if number, err := w.Eth.BlockNumber(ctx); err != nil || number != 1 {
+		t.Fatalf("BlockNumber = %d %v, want 1", number, err)
+	}
+
+	// blocks and headers
+	block, err := w.Eth.GetBlockByNumber(ctx, 1, true)
+	if err != nil {
+		t.Fatalf("GetBlockByNumber: %v", err)
+	}
+	hash := common.HexToHash(block["hash"].(string))
+	if txs := block["transactions"].([]interface{}); len(txs) != 2 {
+		t.Fatalf("block 1 has %d transactions, want 2", len(txs))
+	}
+	byHash, err := w.Eth.GetBlockByHash(ctx, hash, false)
+	if err != nil || byHash["number"] != "0x1" {
+		t.Errorf("GetBlockByHash = %v %v", byHash["number"], err)
+	}
+	if header, err := w.Eth.GetHeaderByHash(ctx, hash); err != nil || header["number"] != "0x1" {
+		t.Errorf("GetHeaderByHash = %v %v", header["number"], err)
+	}
+	if header, err := w.Eth.GetHeaderByNumber(ctx, rpc.LatestBlockNumber); err != nil || header["hash"] != hash.Hex() {
+		t.Errorf("GetHeaderByNumber(latest) = %v %v", header["hash"], err)
+	}
+	if missing, err := w.Eth.GetBlockByNumber(ctx, 100, false); err != nil || missing != nil {
+		t.Errorf("GetBlockByNumber(100) = %v %v, want nil", missing, err)
+	}
+
+	// raw transactions
+	want, _ := call.MarshalBinary()
+	for name, get := range map[string]func() (hexutil.Bytes, error){
+		"GetRawTransaction":       func() (hexutil.Bytes, error) { return w.Eth.GetRawTransaction(ctx, call.Hash()) },
+		"GetRawTransactionByHash": func() (hexutil.Bytes, error) { return w.Eth.GetRawTransactionByHash(ctx, call.Hash()) },
+		"GetRawTransactionByBlockHashAndIndex": func() (hexutil.Bytes, error) {
+			return w.Eth.GetRawTransactionByBlockHashAndIndex(ctx, hash, 1)
+		},
+		"GetRawTransactionByBlockNumberAndIndex": func() (hexutil.Bytes, error) {
+			return w.Eth.GetRawTransactionByBlockNumberAndIndex(ctx, 1, 1)
+		},
+	} {
+		if raw, err := get(); err != nil || hexutil.Encode(raw) != hexutil.Encode(want) {
+			t.Errorf("%s = %x %v, want %x", name, raw, err, want)
+		}
+	}
+
+	receipts, err := w.Eth.GetBlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(hash, true))
+	if err != nil || len(receipts) != 2 || receipts[0]["contractAddress"] != hexutil.Encode(contract.Bytes()) {
+		t.Errorf("GetBlockReceipts = %v %v", receipts, err)
+	}
+
+	// state
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	proof, err := w.Eth.GetProof(ctx, contract, []string{"0x0"}, latest)
+	if err != nil {
+		t.Fatalf("GetProof: %v", err)
+	}
+	if len(proof.AccountProof) == 0 || len(proof.StorageProof) != 1 || proof.StorageProof[0].Value.ToInt().Cmp(value.Big()) != 0 {
+		t.Errorf("GetProof = %+v", proof)
+	}
+	input := hexutil.Bytes(common.HexToHash("0x07").Bytes())
+	args := web3.TransactionArgs{From: &node.Account, To: &contract, Input: &input}
+	if out, err := w.Eth.Call(ctx, args, &latest, nil, nil); err != nil || len(out) != 0 {
+		t.Errorf("Call = %x %v", out, err)
+	}
+	code := hexutil.Bytes(common.FromHex("6000356000526020" + "6000f3"))
+	overrides := web3.StateOverride{contract: {Code: &code}}
+	if out, err := w.Eth.Call(ctx, args, &latest, &overrides, nil); err != nil || common.BytesToHash(out) != common.HexToHash("0x07") {
+		t.Errorf("Call with overrides = %x %v", out, err)
+	}
+	number := hexutil.Big(*big.NewInt(1000))
+	if _, err := w.Eth.Call(ctx, args, &latest, nil, &web3.BlockOverrides{Number: &number}); err != nil {
+		t.Errorf("Call with block overrides: %v", err)
+	}
+	if gas, err := w.Eth.EstimateGas(ctx, args, nil, nil); err != nil || gas <= 21000 {
+		t.Errorf("EstimateGas = %d %v", gas, err)
+	}
+	accessList, err := w.Eth.CreateAccessList(ctx, args, &latest)
+	if err != nil || accessList.Accesslist == nil || len(*accessList.Accesslist) != 1 || (*accessList.Accesslist)[0].Address != contract {
+		t.Errorf("CreateAccessList = %+v %v", accessList, err)
+	}
+	history, err := w.Eth.FeeHistory(ctx, 2, rpc.LatestBlockNumber, []float64{50})
+	if err != nil || len(history.GasUsedRatio) != 2 || len(history.Reward) != 2 {
+		t.Errorf("FeeHistory = %+v %v", history, err)
+	}
+
+	// logs and filters
+	crit := filters.FilterCriteria{FromBlock: big.NewInt(0), Addresses: []common.Address{contract}}
+	logs, err := w.Eth.GetLogs(ctx, crit)
+	if err != nil || len(logs) != 1 || common.BytesToHash(logs[0].Data) != value || logs[0].TxHash != call.Hash() {
+		t.Errorf("GetLogs = %v %v", logs, err)
+	}
+	id, err := w.Eth.NewFilter(ctx, crit)
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	if logs, err := w.Eth.GetFilterLogs(ctx, id); err != nil || len(logs) != 1 {
+		t.Errorf("GetFilterLogs = %v %v", logs, err)
+	}
+	if ok, err := w.Eth.UninstallFilter(ctx, id); err != nil || !ok {
+		t.Errorf("UninstallFilter = %v %v", ok, err)
+	}
+	if _, err := w.Eth.GetFilterLogs(ctx, id); !errors.Is(err, web3.ErrFilterNotFound) {
+		t.Errorf("GetFilterLogs after uninstall error = %v, want ErrFilterNotFound", err)
+	}
+	if _, err := w.Eth.NewBlockFilter(ctx); err != nil {
+		t.Errorf("NewBlockFilter: %v", err)
+	}
+	fullTx := true
+	if _, err := w.Eth.NewPendingTransactionFilter(ctx, &fullTx); err != nil {
+		t.Errorf("NewPendingTransactionFilter: %v", err)
+	}
+}
+
+func TestEthSendDevNode(t *testing.T) {
+	node := web3test.New(t)
+	w := node.Web3
+	ctx := context.Background()
+	to := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+
+	message := hexutil.Bytes("hello web3-go")
+	sig, err := w.Eth.Sign(ctx, node.Account, message)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	sig[crypto.RecoveryIDOffset] -= 27
+	if pub, err := crypto.SigToPub(accounts.TextHash(message), sig); err != nil || crypto.PubkeyToAddress(*pub) != node.Account {
+		t.Errorf("Sign produced a signature of someone else: %v", err)
+	}
+
+	value := (*hexutil.Big)(big.NewInt(params.Ether))
+	args := web3.TransactionArgs{From: &node.Account, To: &to, Value: value}
+	filled, err := w.Eth.FillTransaction(ctx, args)
+	if err != nil {
+		t.Fatalf("FillTransaction: %v", err)
+	}
+	if filled.Tx.Gas() != params.TxGas || filled.Tx.Nonce() != 0 {
+		t.Errorf("FillTransaction = gas %d nonce %d", filled.Tx.Gas(), filled.Tx.Nonce())
+	}
+	gas, nonce := hexutil.Uint64(params.TxGas), hexutil.Uint64(0)
+	price := (*hexutil.Big)(big.NewInt(10 * params.GWei))
+	legacy := web3.TransactionArgs{From: &node.Account, To: &to, Value: value, Gas: &gas, GasPrice: price, Nonce: &nonce}
+	signed, err := w.Eth.SignTransaction(ctx, legacy)
+	if err != nil {
+		t.Fatalf("SignTransaction: %v", err)
+	}
+	if sender, err := types.Sender(types.LatestSignerForChainID(signed.Tx.ChainId()), signed.Tx); err != nil || sender != node.Account {
+		t.Errorf("SignTransaction sender = %v %v", sender, err)
+	}
+
+	hash, err := w.Eth.SendTransaction(ctx, legacy)
+	if err != nil || hash != signed.Tx.Hash() {
+		t.Fatalf("SendTransaction = %v %v, want %v", hash, err, signed.Tx.Hash())
+	}
+	pending, err := w.Eth.PendingTransactions(ctx)
+	if err != nil || len(pending) != 1 || pending[0].Hash != hash {
+		t.Fatalf("PendingTransactions = %v %v", pending, err)
+	}
+	higher := (*hexutil.Big)(big.NewInt(20 * params.GWei))
+	replaced, err := w.Eth.Resend(ctx, legacy, higher, nil)
+	if err != nil || replaced == hash {
+		t.Fatalf("Resend = %v %v", replaced, err)
+	}
+	if _, err := w.Eth.SendRawTransaction(ctx, signed.Raw); !errors.Is(err, web3.ErrUnderpriced) {
+		t.Errorf("SendRawTransaction of the replaced transaction error = %v, want ErrUnderpriced", err)
+	}
+	if _, err := w.Eth.SubmitTransaction(ctx, signed.Tx); !errors.Is(err, web3.ErrMethodNotFound) {
+		t.Errorf("SubmitTransaction error = %v, want ErrMethodNotFound", err)
+	}
+	node.Commit(t)
+
+	next := node.SendTx(t, &to, big.NewInt(1), nil)
+	raw, _ := next.MarshalBinary()
+	if _, err := w.Eth.SendRawTransaction(ctx, raw); !errors.Is(err, web3.ErrAlreadyKnown) {
+		t.Errorf("SendRawTransaction twice error = %v, want ErrAlreadyKnown", err)
+	}
+	node.Commit(t)
+	if _, err := w.Eth.SendRawTransaction(ctx, raw); !errors.Is(err, web3.ErrNonceTooLow) {
+		t.Errorf("SendRawTransaction after inclusion error = %v, want ErrNonceTooLow", err)
+	}
+}
@@ -6,11 +6,12 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/rpc"
 )
 
 type Eth struct {
-	c *rpc.Client
+	c      Client
+	gate   *capabilityGate
+	signer Signer
 }
 
 // eth_compileSolidity
@@ -22,28 +23,18 @@ type Eth struct {
 // eth_compileSerpent
 // todo
 
-// eth_submitWork
-// todo
-
-// eth_getWork
-// todo
-
 // defaultAccount 属性
 // defaultBlock
 // eth_protocolVersion
 // eth_contract
 // createAccessList
 // filter
-// getBalance
 // getBlock
 // getBlockTransactionCount
 // getBlockUncleCount
-// getCode
 // getCompilers
-// getRawTransactionFromBlock
 // getStorageAt
 // getTransaction
-// getTransactionCount
 // getTransactionFromBlock
 // getTransactionReceipt
 // getUncle
@@ -51,15 +42,19 @@ type Eth struct {
 // icapNamereg
 // namereg
 // sendIBANTransaction
-// sendRawTransaction
-// submitWork
 
-func NewEth(c *rpc.Client) *Eth {
+func NewEth(c Client) *Eth {
 	e := &Eth{}
-	e.c = c
+	e.c = withCallErrors(c)
 	return e
 }
 
+// Available reports whether the node serves the eth namespace. It is true
+// until Web3.Capabilities has probed the node.
+func (e *Eth) Available() bool {
+	return e.gate.available("eth")
+}
+
 // RPCTransaction represents a transaction that will serialize to the RPC representation of a transaction
 type RPCTransaction struct {
 	BlockHash           *common.Hash      `json:"blockHash"`
@@ -172,9 +167,9 @@ func (e *Eth) Accounts(ctx context.Context) ([]common.Address, error) {
 // property
 
 func (e *Eth) BlockNumber(ctx context.Context) (uint64, error) {
-	var result uint64
+	var result hexutil.Uint64
 	err := e.c.CallContext(ctx, &result, "eth_blockNumber")
-	return result, err
+	return uint64(result), err
 }
 
 //eth_protocolVersion
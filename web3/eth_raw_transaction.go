@@ -0,0 +1,90 @@
+package web3
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DecodeRawTransaction decodes a transaction in its binary encoding, as
+// returned by the raw transaction methods, and recovers its sender.
+//
+// The sender is recovered with the signer of the fork that introduced the
+// type of the transaction, so any transaction of the chain is recovered;
+// legacy transactions without replay protection use the Homestead rules.
+// Transactions signed for another chain than chainID are rejected, unless
+// chainID is nil.
+func DecodeRawTransaction(raw []byte, chainID *big.Int) (*types.Transaction, common.Address, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, common.Address{}, fmt.Errorf("invalid raw transaction: %w", err)
+	}
+	if chainID == nil {
+		chainID = tx.ChainId()
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf("transaction %v: %w", tx.Hash(), err)
+	}
+	return tx, from, nil
+}
+
+// decodeRawTransaction decodes the result of a raw transaction method for the
+// chain of the node. Unknown transactions are returned as nil.
+func (e *Eth) decodeRawTransaction(ctx context.Context, raw hexutil.Bytes, err error) (*types.Transaction, common.Address, error) {
+	if err != nil || len(raw) == 0 {
+		return nil, common.Address{}, err
+	}
+	chainID, err := e.ChainID(ctx)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	return DecodeRawTransaction(raw, chainID)
+}
+
+// GetDecodedTransactionByHash is GetRawTransactionByHash decoded with
+// DecodeRawTransaction for the chain ID of the node. It returns a nil
+// transaction if the node does not know it.
+func (e *Eth) GetDecodedTransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, common.Address, error) {
+	raw, err := e.GetRawTransactionByHash(ctx, hash)
+	return e.decodeRawTransaction(ctx, raw, err)
+}
+
+// GetDecodedTransactionByBlockHashAndIndex is
+// GetRawTransactionByBlockHashAndIndex decoded with DecodeRawTransaction for
+// the chain ID of the node.
+func (e *Eth) GetDecodedTransactionByBlockHashAndIndex(ctx context.Context, blockHash common.Hash, index hexutil.Uint) (*types.Transaction, common.Address, error) {
+	raw, err := e.GetRawTransactionByBlockHashAndIndex(ctx, blockHash, index)
+	return e.decodeRawTransaction(ctx, raw, err)
+}
+
+// GetDecodedTransactionByBlockNumberAndIndex is
+// GetRawTransactionByBlockNumberAndIndex decoded with DecodeRawTransaction for
+// the chain ID of the node.
+func (e *Eth) GetDecodedTransactionByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) (*types.Transaction, common.Address, error) {
+	raw, err := e.GetRawTransactionByBlockNumberAndIndex(ctx, blockNr, index)
+	return e.decodeRawTransaction(ctx, raw, err)
+}
+
+// GetDecodedTransactionFromBlock is GetRawTransactionFromBlock decoded with
+// DecodeRawTransaction for the chain ID of the node.
+func (e *Eth) GetDecodedTransactionFromBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, index hexutil.Uint) (*types.Transaction, common.Address, error) {
+	raw, err := e.GetRawTransactionFromBlock(ctx, blockNrOrHash, index)
+	return e.decodeRawTransaction(ctx, raw, err)
+}
+
+// GetDecodedTransaction is GetRawTransaction decoded with
+// DecodeRawTransaction for the given chain ID, see Eth.ChainID. It returns a
+// nil transaction if the node does not know it.
+func (d *Debug) GetDecodedTransaction(ctx context.Context, hash common.Hash, chainID *big.Int) (*types.Transaction, common.Address, error) {
+	raw, err := d.GetRawTransaction(ctx, hash)
+	if err != nil || len(raw) == 0 {
+		return nil, common.Address{}, err
+	}
+	return DecodeRawTransaction(raw, chainID)
+}
@@ -0,0 +1,94 @@
+package web3_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/holiman/uint256"
+
+	"github.com/moonfdd/web3-go/web3"
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+func TestDecodeRawTransaction(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	chainID := big.NewInt(5)
+	to := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+
+	for name, tx := range map[string]struct {
+		tx     *types.Transaction
+		signer types.Signer
+	}{
+		"homestead":   {types.NewTx(&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000, To: &to}), types.HomesteadSigner{}},
+		"eip155":      {types.NewTx(&types.LegacyTx{Nonce: 2, GasPrice: big.NewInt(1), Gas: 21000, To: &to}), types.NewEIP155Signer(chainID)},
+		"access list": {types.NewTx(&types.AccessListTx{ChainID: chainID, Nonce: 3, GasPrice: big.NewInt(1), Gas: 21000, To: &to}), types.NewEIP2930Signer(chainID)},
+		"dynamic fee": {types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Nonce: 4, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Gas: 21000, To: &to}), types.NewLondonSigner(chainID)},
+		"blob":        {types.NewTx(&types.BlobTx{ChainID: uint256.MustFromBig(chainID), Nonce: 5, GasTipCap: uint256.NewInt(1), GasFeeCap: uint256.NewInt(2), Gas: 21000, To: to, BlobFeeCap: uint256.NewInt(1), BlobHashes: []common.Hash{{1}}}), types.NewCancunSigner(chainID)},
+	} {
+		signed, err := types.SignTx(tx.tx, tx.signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, _ := signed.MarshalBinary()
+		decoded, sender, err := web3.DecodeRawTransaction(raw, chainID)
+		if err != nil || decoded.Hash() != signed.Hash() || sender != from {
+			t.Errorf("DecodeRawTransaction of a %s transaction = %v %v %v", name, decoded, sender, err)
+		}
+		if _, sender, err := web3.DecodeRawTransaction(raw, nil); err != nil || sender != from {
+			t.Errorf("DecodeRawTransaction of a %s transaction without chain ID = %v %v", name, sender, err)
+		}
+		if _, _, err := web3.DecodeRawTransaction(raw, big.NewInt(1)); err == nil && name != "homestead" {
+			t.Errorf("DecodeRawTransaction of a %s transaction of another chain succeeded", name)
+		}
+	}
+	if _, _, err := web3.DecodeRawTransaction([]byte{0x02, 0xc0}, nil); err == nil {
+		t.Error("DecodeRawTransaction of garbage succeeded")
+	}
+}
+
+func TestGetDecodedTransactionDevNode(t *testing.T) {
+	node := web3test.New(t)
+	w := node.Web3
+	ctx := context.Background()
+	_, call := deployStore(t, node, common.HexToHash("0x2a"))
+	block := node.Eth.BlockChain().CurrentBlock().Hash()
+
+	for name, get := range map[string]func() (*types.Transaction, common.Address, error){
+		"ByHash": func() (*types.Transaction, common.Address, error) {
+			return w.Eth.GetDecodedTransactionByHash(ctx, call.Hash())
+		},
+		"ByBlockHashAndIndex": func() (*types.Transaction, common.Address, error) {
+			return w.Eth.GetDecodedTransactionByBlockHashAndIndex(ctx, block, 1)
+		},
+		"ByBlockNumberAndIndex": func() (*types.Transaction, common.Address, error) {
+			return w.Eth.GetDecodedTransactionByBlockNumberAndIndex(ctx, 1, 1)
+		},
+		"FromBlock by hash": func() (*types.Transaction, common.Address, error) {
+			return w.Eth.GetDecodedTransactionFromBlock(ctx, rpc.BlockNumberOrHashWithHash(block, false), 1)
+		},
+		"FromBlock by number": func() (*types.Transaction, common.Address, error) {
+			return w.Eth.GetDecodedTransactionFromBlock(ctx, rpc.BlockNumberOrHashWithNumber(1), 1)
+		},
+		"Debug": func() (*types.Transaction, common.Address, error) {
+			return w.Debug.GetDecodedTransaction(ctx, call.Hash(), node.Eth.BlockChain().Config().ChainID)
+		},
+	} {
+		tx, from, err := get()
+		if err != nil || tx == nil || tx.Hash() != call.Hash() || from != node.Account {
+			t.Errorf("GetDecodedTransaction%s = %v %v %v", name, tx, from, err)
+		}
+	}
+
+	if tx, _, err := w.Eth.GetDecodedTransactionByHash(ctx, common.HexToHash("0x01")); err != nil || tx != nil {
+		t.Errorf("GetDecodedTransactionByHash of an unknown transaction = %v %v, want nil", tx, err)
+	}
+	if _, _, err := w.Debug.GetDecodedTransaction(ctx, call.Hash(), big.NewInt(1)); err == nil {
+		t.Error("Debug.GetDecodedTransaction for another chain succeeded")
+	}
+}
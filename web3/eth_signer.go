@@ -0,0 +1,132 @@
+package web3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Signer signs the transactions of an Eth namespace in place of the node, see
+// Eth.WithSigner. The arguments it gets have their nonce, gas, fees, value
+// and chain ID filled in. *Clef implements it, and so does *Eth, which lets
+// another node sign.
+type Signer interface {
+	SignTransaction(ctx context.Context, args TransactionArgs) (*SignTransactionResult, error)
+}
+
+// WithSigner returns a copy of the Eth namespace whose SendTransaction and
+// SignTransaction fill in the nonce, gas, fees, value and chain ID of the
+// transaction and let s sign it, so the node needs no key. A nil s restores
+// signing by the node.
+func (e *Eth) WithSigner(s Signer) *Eth {
+	copied := *e
+	copied.signer = s
+	return &copied
+}
+
+// Signer returns the Signer set by WithSigner, or nil if the node signs.
+func (e *Eth) Signer() Signer {
+	return e.signer
+}
+
+func (e *Eth) signTransaction(ctx context.Context, args TransactionArgs) (*SignTransactionResult, error) {
+	args, err := e.fillTransaction(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := e.signer.SignTransaction(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if signed == nil {
+		return nil, errors.New("signer returned no transaction")
+	}
+	if len(signed.Raw) == 0 && signed.Tx != nil {
+		if signed.Raw, err = signed.Tx.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	return signed, nil
+}
+
+// fillTransaction sets the fields of args that the node would fill in for
+// eth_sendTransaction. Dynamic fees are used if the chain has a base fee, the
+// fee cap leaving room for the base fee to double.
+func (e *Eth) fillTransaction(ctx context.Context, args TransactionArgs) (TransactionArgs, error) {
+	if args.From == nil {
+		return args, errors.New("missing from address")
+	}
+	if args.Value == nil {
+		args.Value = new(hexutil.Big)
+	}
+	if args.ChainID == nil {
+		id, err := e.ChainID(ctx)
+		if err != nil {
+			return args, err
+		}
+		args.ChainID = (*hexutil.Big)(id)
+	}
+	if args.Nonce == nil {
+		nonce, err := e.GetTransactionCount(ctx, *args.From, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber))
+		if err != nil {
+			return args, err
+		}
+		args.Nonce = &nonce
+	}
+	if args.GasPrice == nil && (args.MaxFeePerGas == nil || args.MaxPriorityFeePerGas == nil) {
+		baseFee, err := e.latestBaseFee(ctx)
+		if err != nil {
+			return args, err
+		}
+		if baseFee != nil {
+			if args.MaxPriorityFeePerGas == nil {
+				if args.MaxPriorityFeePerGas, err = e.MaxPriorityFeePerGas(ctx); err != nil {
+					return args, err
+				}
+			}
+			if args.MaxFeePerGas == nil {
+				maxFee := new(big.Int).Mul(baseFee, big.NewInt(2))
+				args.MaxFeePerGas = (*hexutil.Big)(maxFee.Add(maxFee, args.MaxPriorityFeePerGas.ToInt()))
+			}
+		} else {
+			if args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil {
+				return args, errors.New("dynamic fees set on a chain without base fee")
+			}
+			if args.GasPrice, err = e.GasPrice(ctx); err != nil {
+				return args, err
+			}
+		}
+	}
+	if args.Gas == nil {
+		gas, err := e.EstimateGas(ctx, args, nil, nil)
+		if err != nil {
+			return args, err
+		}
+		args.Gas = &gas
+	}
+	return args, nil
+}
+
+// latestBaseFee returns the base fee of the latest block, nil before London.
+func (e *Eth) latestBaseFee(ctx context.Context) (*big.Int, error) {
+	head, err := e.GetBlockByNumber(ctx, rpc.LatestBlockNumber, false)
+	if err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, errors.New("latest block not found")
+	}
+	encoded, ok := head["baseFeePerGas"].(string)
+	if !ok {
+		return nil, nil
+	}
+	baseFee, err := hexutil.DecodeBig(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base fee: %w", err)
+	}
+	return baseFee, nil
+}
@@ -0,0 +1,97 @@
+package web3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// quantity decodes numbers that Anvil sends as hex strings and Hardhat as
+// plain JSON numbers.
+type quantity uint64
+
+func (q *quantity) UnmarshalJSON(input []byte) error {
+	var s string
+	if err := json.Unmarshal(input, &s); err == nil {
+		n, ok := new(big.Int).SetString(s, 0)
+		if !ok || !n.IsUint64() {
+			return fmt.Errorf("invalid quantity %q", s)
+		}
+		*q = quantity(n.Uint64())
+		return nil
+	}
+	var n uint64
+	if err := json.Unmarshal(input, &n); err != nil {
+		return err
+	}
+	*q = quantity(n)
+	return nil
+}
+
+// Snapshot snapshots the state of the chain and returns the id of the
+// snapshot, to be passed to Revert.
+// from anvil, hardhat
+// method
+func (e *Evm) Snapshot(ctx context.Context) (*hexutil.Big, error) {
+	var result *hexutil.Big
+	err := e.c.CallContext(ctx, &result, "evm_snapshot")
+	return result, err
+}
+
+// Revert reverts the state of the chain to a snapshot. The snapshot and the
+// ones taken after it are consumed. It returns whether the snapshot existed.
+// from anvil, hardhat
+// method
+func (e *Evm) Revert(ctx context.Context, id *hexutil.Big) (bool, error) {
+	var result bool
+	err := e.c.CallContext(ctx, &result, "evm_revert", id)
+	return result, err
+}
+
+// Mine mines a single block, with the given timestamp if it is not nil.
+// from anvil, hardhat
+// method
+func (e *Evm) Mine(ctx context.Context, timestamp *hexutil.Uint64) error {
+	var err error
+	if timestamp == nil {
+		err = e.c.CallContext(ctx, nil, "evm_mine")
+	} else {
+		err = e.c.CallContext(ctx, nil, "evm_mine", *timestamp)
+	}
+	return err
+}
+
+// IncreaseTime moves the time of the following blocks forward by the given
+// number of seconds and returns the total adjustment in seconds.
+// from anvil, hardhat
+// method
+func (e *Evm) IncreaseTime(ctx context.Context, seconds uint64) (uint64, error) {
+	var result quantity
+	err := e.c.CallContext(ctx, &result, "evm_increaseTime", hexutil.Uint64(seconds))
+	return uint64(result), err
+}
+
+// WithSnapshot runs fn against a snapshot of the chain and reverts to it
+// afterwards, whether fn fails, panics or succeeds. The error of fn comes
+// first, then the one of the revert.
+func (e *Evm) WithSnapshot(ctx context.Context, fn func() error) (err error) {
+	id, err := e.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		reverted, revertErr := e.Revert(context.WithoutCancel(ctx), id)
+		if err != nil {
+			return
+		}
+		if revertErr != nil {
+			err = revertErr
+		} else if !reverted {
+			err = fmt.Errorf("snapshot %v no longer exists", id)
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,18 @@
+package web3
+
+type Evm struct {
+	c    Client
+	gate *capabilityGate
+}
+
+func NewEvm(c Client) *Evm {
+	e := &Evm{}
+	e.c = withCallErrors(c)
+	return e
+}
+
+// Available reports whether the node serves the evm namespace. It is true
+// until Web3.Capabilities has probed the node.
+func (e *Evm) Available() bool {
+	return e.gate.available("evm")
+}
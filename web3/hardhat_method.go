@@ -0,0 +1,88 @@
+package web3
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// SetBalance sets the balance of an account.
+// from hardhat
+// method
+func (h *Hardhat) SetBalance(ctx context.Context, address common.Address, balance *hexutil.Big) error {
+	err := h.c.CallContext(ctx, nil, "hardhat_setBalance", address, balance)
+	return err
+}
+
+// SetCode sets the code of an account.
+// from hardhat
+// method
+func (h *Hardhat) SetCode(ctx context.Context, address common.Address, code hexutil.Bytes) error {
+	err := h.c.CallContext(ctx, nil, "hardhat_setCode", address, code)
+	return err
+}
+
+// SetStorageAt writes a single storage slot of an account.
+// from hardhat
+// method
+func (h *Hardhat) SetStorageAt(ctx context.Context, address common.Address, slot common.Hash, value common.Hash) (bool, error) {
+	var result bool
+	err := h.c.CallContext(ctx, &result, "hardhat_setStorageAt", address, (*hexutil.Big)(slot.Big()), value)
+	return result, err
+}
+
+// SetNonce sets the nonce of an account.
+// from hardhat
+// method
+func (h *Hardhat) SetNonce(ctx context.Context, address common.Address, nonce hexutil.Uint64) error {
+	err := h.c.CallContext(ctx, nil, "hardhat_setNonce", address, nonce)
+	return err
+}
+
+// ImpersonateAccount lets eth_sendTransaction send transactions from the
+// address without its key, until StopImpersonatingAccount is called.
+// from hardhat
+// method
+func (h *Hardhat) ImpersonateAccount(ctx context.Context, address common.Address) error {
+	err := h.c.CallContext(ctx, nil, "hardhat_impersonateAccount", address)
+	return err
+}
+
+// StopImpersonatingAccount ends the impersonation started by ImpersonateAccount.
+// from hardhat
+// method
+func (h *Hardhat) StopImpersonatingAccount(ctx context.Context, address common.Address) error {
+	err := h.c.CallContext(ctx, nil, "hardhat_stopImpersonatingAccount", address)
+	return err
+}
+
+// Reset resets the chain, see Anvil.Reset.
+// from hardhat
+// method
+func (h *Hardhat) Reset(ctx context.Context, fork *ForkConfig) error {
+	var err error
+	if fork == nil {
+		err = h.c.CallContext(ctx, nil, "hardhat_reset")
+	} else {
+		err = h.c.CallContext(ctx, nil, "hardhat_reset", ResetParams{Forking: fork})
+	}
+	return err
+}
+
+// Mine mines blocks blocks, interval seconds apart. Nil values mean one block
+// and one second. Trailing nil values are not sent.
+// from hardhat
+// method
+func (h *Hardhat) Mine(ctx context.Context, blocks, interval *hexutil.Uint64) error {
+	var err error
+	switch {
+	case interval != nil:
+		err = h.c.CallContext(ctx, nil, "hardhat_mine", blocks, interval)
+	case blocks != nil:
+		err = h.c.CallContext(ctx, nil, "hardhat_mine", blocks)
+	default:
+		err = h.c.CallContext(ctx, nil, "hardhat_mine")
+	}
+	return err
+}
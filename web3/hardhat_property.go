@@ -0,0 +1,18 @@
+package web3
+
+type Hardhat struct {
+	c    Client
+	gate *capabilityGate
+}
+
+func NewHardhat(c Client) *Hardhat {
+	e := &Hardhat{}
+	e.c = withCallErrors(c)
+	return e
+}
+
+// Available reports whether the node serves the hardhat namespace. It is true
+// until Web3.Capabilities has probed the node.
+func (h *Hardhat) Available() bool {
+	return h.gate.available("hardhat")
+}
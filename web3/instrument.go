@@ -0,0 +1,226 @@
+package web3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/moonfdd/web3-go/web3"
+
+// Metrics holds the Prometheus collectors recorded by InstrumentedClient. A
+// single Metrics can be shared by the clients of several endpoints, they are
+// told apart by the endpoint label. Metrics implements prometheus.Collector
+// and has to be registered by the caller.
+type Metrics struct {
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	size     *prometheus.HistogramVec
+}
+
+// NewMetrics creates the collectors with the given metric namespace, "web3"
+// if empty.
+func NewMetrics(namespace string) *Metrics {
+	if namespace == "" {
+		namespace = "web3"
+	}
+	m := &Metrics{}
+	m.requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "rpc",
+		Name:      "requests_total",
+		Help:      "Number of JSON-RPC requests sent, by method and endpoint.",
+	}, []string{"method", "endpoint"})
+	m.errors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "rpc",
+		Name:      "errors_total",
+		Help:      "Number of failed JSON-RPC requests, by method, endpoint and JSON-RPC error code.",
+	}, []string{"method", "endpoint", "code"})
+	m.latency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "rpc",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of JSON-RPC requests, by method and endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "endpoint"})
+	m.size = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "rpc",
+		Name:      "response_size_bytes",
+		Help:      "Size of JSON-RPC results, by method and endpoint.",
+		Buckets:   prometheus.ExponentialBuckets(64, 4, 10),
+	}, []string{"method", "endpoint"})
+	return m
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.errors.Describe(ch)
+	m.latency.Describe(ch)
+	m.size.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.errors.Collect(ch)
+	m.latency.Collect(ch)
+	m.size.Collect(ch)
+}
+
+func (m *Metrics) observe(method, endpoint string, d time.Duration, size int, err error) {
+	m.requests.WithLabelValues(method, endpoint).Inc()
+	m.latency.WithLabelValues(method, endpoint).Observe(d.Seconds())
+	if err != nil {
+		m.errors.WithLabelValues(method, endpoint, errorCode(err)).Inc()
+		return
+	}
+	m.size.WithLabelValues(method, endpoint).Observe(float64(size))
+}
+
+// errorCode returns the JSON-RPC error code of err, or a short description
+// of the failure if the node never answered.
+func errorCode(err error) string {
+	var (
+		rpcErr  rpc.Error
+		httpErr rpc.HTTPError
+	)
+	switch {
+	case errors.As(err, &rpcErr):
+		return strconv.Itoa(rpcErr.ErrorCode())
+	case errors.As(err, &httpErr):
+		return "http_" + strconv.Itoa(httpErr.StatusCode)
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	return "transport"
+}
+
+// InstrumentedClient records Prometheus metrics and an OpenTelemetry span for
+// every request sent through it. Wrap the client handed to NewWeb3 with it to
+// instrument all namespaces at once.
+type InstrumentedClient struct {
+	c        Client
+	endpoint string
+	metrics  *Metrics
+	tracer   trace.Tracer
+}
+
+// NewInstrumentedClient wraps c. Either metrics or tp may be nil; a nil tp
+// uses the global OpenTelemetry tracer provider. The endpoint is used as the
+// metric label and span attribute. It is fixed for the wrapper: wrapping a
+// MultiClient records every request under one label whichever endpoint
+// served it. Set MultiClientConfig.Metrics and TracerProvider instead to
+// instrument each endpoint.
+func NewInstrumentedClient(c Client, endpoint string, metrics *Metrics, tp trace.TracerProvider) *InstrumentedClient {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	i := &InstrumentedClient{}
+	i.c = c
+	i.endpoint = endpoint
+	i.metrics = metrics
+	i.tracer = tp.Tracer(instrumentationName)
+	return i
+}
+
+func (i *InstrumentedClient) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs,
+		attribute.String("rpc.system", "jsonrpc"),
+		attribute.String("server.address", i.endpoint),
+	)
+	return i.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) {
+			span.SetAttributes(attribute.Int("rpc.jsonrpc.error_code", rpcErr.ErrorCode()))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// CallContext implements Client.
+func (i *InstrumentedClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	ctx, span := i.startSpan(ctx, method, attribute.String("rpc.method", method))
+	start := time.Now()
+	var raw json.RawMessage
+	err := i.c.CallContext(ctx, &raw, method, args...)
+	if err == nil && result != nil && len(raw) > 0 {
+		err = json.Unmarshal(raw, result)
+	}
+	if i.metrics != nil {
+		i.metrics.observe(method, i.endpoint, time.Since(start), len(raw), err)
+	}
+	span.SetAttributes(attribute.Int("rpc.response.size", len(raw)))
+	endSpan(span, err)
+	return err
+}
+
+// BatchCallContext implements Client. The batch is recorded as a single span,
+// metrics are recorded per element.
+func (i *InstrumentedClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	methods := make([]string, len(b))
+	for j, elem := range b {
+		methods[j] = elem.Method
+	}
+	ctx, span := i.startSpan(ctx, "batch",
+		attribute.StringSlice("rpc.method", methods),
+		attribute.Int("rpc.batch.size", len(b)),
+	)
+	start := time.Now()
+	raws := make([]json.RawMessage, len(b))
+	batch := make([]rpc.BatchElem, len(b))
+	for j, elem := range b {
+		batch[j] = rpc.BatchElem{Method: elem.Method, Args: elem.Args, Result: &raws[j]}
+	}
+	err := i.c.BatchCallContext(ctx, batch)
+	d := time.Since(start)
+	for j := range b {
+		elemErr := err
+		if elemErr == nil {
+			elemErr = batch[j].Error
+			if elemErr == nil && b[j].Result != nil && len(raws[j]) > 0 {
+				elemErr = json.Unmarshal(raws[j], b[j].Result)
+			}
+			b[j].Error = elemErr
+		}
+		if i.metrics != nil {
+			i.metrics.observe(b[j].Method, i.endpoint, d, len(raws[j]), elemErr)
+		}
+	}
+	endSpan(span, err)
+	return err
+}
+
+// Subscribe passes subscriptions through to the wrapped client, so that an
+// instrumented *rpc.Client can still serve the subscriptions of a MultiClient.
+// Only the subscription request itself is traced.
+func (i *InstrumentedClient) Subscribe(ctx context.Context, namespace string, channel interface{}, args ...interface{}) (*rpc.ClientSubscription, error) {
+	sc, ok := i.c.(subscriber)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	ctx, span := i.startSpan(ctx, namespace+"_subscribe", attribute.String("rpc.method", namespace+"_subscribe"))
+	sub, err := sc.Subscribe(ctx, namespace, channel, args...)
+	endSpan(span, err)
+	return sub, err
+}
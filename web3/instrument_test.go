@@ -0,0 +1,194 @@
+package web3_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/moonfdd/web3-go/web3"
+)
+
+// spanRecorder is an in-memory tracer provider that keeps every ended span.
+type spanRecorder struct {
+	embedded.TracerProvider
+
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordingTracer struct {
+	embedded.Tracer
+	recorder *spanRecorder
+}
+
+type recordedSpan struct {
+	noop.Span
+	recorder *spanRecorder
+
+	name   string
+	kind   trace.SpanKind
+	attrs  map[attribute.Key]attribute.Value
+	status codes.Code
+	errs   []error
+}
+
+func (r *spanRecorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return &recordingTracer{recorder: r}
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	span := &recordedSpan{recorder: t.recorder, name: name, kind: cfg.SpanKind(), attrs: make(map[attribute.Key]attribute.Value)}
+	span.SetAttributes(cfg.Attributes()...)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+func (r *spanRecorder) ended() []*recordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*recordedSpan(nil), r.spans...)
+}
+
+func (s *recordedSpan) IsRecording() bool { return true }
+
+func (s *recordedSpan) SetAttributes(attrs ...attribute.KeyValue) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordedSpan) SetStatus(code codes.Code, _ string) { s.status = code }
+
+func (s *recordedSpan) RecordError(err error, _ ...trace.EventOption) { s.errs = append(s.errs, err) }
+
+func (s *recordedSpan) End(...trace.SpanEndOption) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.spans = append(s.recorder.spans, s)
+}
+
+func TestInstrumentedClient(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &endpointService{id: 7}); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	metrics := web3.NewMetrics("")
+	recorder := &spanRecorder{}
+	c := web3.NewInstrumentedClient(client, "node", metrics, recorder)
+	ctx := context.Background()
+
+	if id, err := web3.NewWeb3(c).Eth.ChainID(ctx); err != nil || id.Uint64() != 7 {
+		t.Fatalf("ChainID = %v %v", id, err)
+	}
+	if err := c.CallContext(ctx, nil, "eth_fail"); err == nil {
+		t.Fatal("eth_fail succeeded")
+	}
+	var id hexutil.Uint64
+	batch := []rpc.BatchElem{
+		{Method: "eth_chainId", Result: &id},
+		{Method: "eth_fail"},
+	}
+	if err := c.BatchCallContext(ctx, batch); err != nil {
+		t.Fatal(err)
+	}
+	if id != 7 || batch[0].Error != nil || batch[1].Error == nil {
+		t.Errorf("batch = %d %v %v", id, batch[0].Error, batch[1].Error)
+	}
+
+	want := `
+# HELP web3_rpc_requests_total Number of JSON-RPC requests sent, by method and endpoint.
+# TYPE web3_rpc_requests_total counter
+web3_rpc_requests_total{endpoint="node",method="eth_chainId"} 2
+web3_rpc_requests_total{endpoint="node",method="eth_fail"} 2
+# HELP web3_rpc_errors_total Number of failed JSON-RPC requests, by method, endpoint and JSON-RPC error code.
+# TYPE web3_rpc_errors_total counter
+web3_rpc_errors_total{code="-32000",endpoint="node",method="eth_fail"} 2
+`
+	if err := testutil.CollectAndCompare(metrics, strings.NewReader(want), "web3_rpc_requests_total", "web3_rpc_errors_total"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(metrics, "web3_rpc_request_duration_seconds"); n != 2 {
+		t.Errorf("%d latency series, want 2", n)
+	}
+
+	spans := recorder.ended()
+	if len(spans) != 3 {
+		t.Fatalf("%d spans, want 3", len(spans))
+	}
+	for i, want := range []struct {
+		name   string
+		status codes.Code
+	}{
+		{"eth_chainId", codes.Unset},
+		{"eth_fail", codes.Error},
+		// element errors do not fail the batch
+		{"batch", codes.Unset},
+	} {
+		span := spans[i]
+		if span.name != want.name || span.status != want.status || span.kind != trace.SpanKindClient {
+			t.Errorf("span %d = %s %v %v, want %s %v", i, span.name, span.kind, span.status, want.name, want.status)
+		}
+		if span.attrs["server.address"].AsString() != "node" || span.attrs["rpc.system"].AsString() != "jsonrpc" {
+			t.Errorf("span %s attributes = %v", span.name, span.attrs)
+		}
+	}
+	if code := spans[1].attrs["rpc.jsonrpc.error_code"].AsInt64(); code != -32000 || len(spans[1].errs) != 1 {
+		t.Errorf("failed span error code %d, %d errors", code, len(spans[1].errs))
+	}
+	if size := spans[2].attrs["rpc.batch.size"].AsInt64(); size != 2 {
+		t.Errorf("batch span size = %d", size)
+	}
+}
+
+func TestMultiClientInstrumentsEndpoints(t *testing.T) {
+	_, servers, endpoints := newEndpoints(t, 3)
+	metrics := web3.NewMetrics("")
+	recorder := &spanRecorder{}
+	m := web3.NewMultiClient(web3.MultiClientConfig{
+		Strategy:       web3.PrimaryBackup,
+		Metrics:        metrics,
+		TracerProvider: recorder,
+	}, endpoints...)
+	defer m.Close()
+
+	chainID(t, m)
+	servers[0].Stop()
+	if id := chainID(t, m); id != 1 {
+		t.Fatalf("call answered by %d, want the backup", id)
+	}
+
+	// every request is recorded under the endpoint that served it
+	want := `
+# HELP web3_rpc_requests_total Number of JSON-RPC requests sent, by method and endpoint.
+# TYPE web3_rpc_requests_total counter
+web3_rpc_requests_total{endpoint="0",method="eth_chainId"} 2
+web3_rpc_requests_total{endpoint="1",method="eth_chainId"} 1
+`
+	if err := testutil.CollectAndCompare(metrics, strings.NewReader(want), "web3_rpc_requests_total"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(metrics, "web3_rpc_errors_total"); n != 1 {
+		t.Errorf("%d error series, want the failed primary", n)
+	}
+	var addresses []string
+	for _, span := range recorder.ended() {
+		addresses = append(addresses, span.attrs["server.address"].AsString())
+	}
+	if strings.Join(addresses, ",") != "0,0,1" {
+		t.Errorf("spans recorded for endpoints %v", addresses)
+	}
+}
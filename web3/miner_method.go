@@ -15,9 +15,16 @@ import (
 // from MinerAPI
 // from web3ext.go
 // method
-func (m *Miner) Start(ctx context.Context) error {
-
-	err := m.c.CallContext(ctx, nil, "miner_start")
+//
+// Geth 1.13 and later dropped the threads parameter, so it is only sent when
+// threads is not nil.
+func (m *Miner) Start(ctx context.Context, threads *int) error {
+	var err error
+	if threads == nil {
+		err = m.c.CallContext(ctx, nil, "miner_start")
+	} else {
+		err = m.c.CallContext(ctx, nil, "miner_start", *threads)
+	}
 	return err
 }
 
@@ -71,12 +78,23 @@ func (m *Miner) SetGasLimit(ctx context.Context, gasLimit hexutil.Uint64) (bool,
 	return result, err
 }
 
+// SetGasTip sets the minimum tip a transaction must pay to be included by the
+// miner.
+// from MinerAPI
+// from web3ext.go
+// method
+func (m *Miner) SetGasTip(ctx context.Context, gasTip hexutil.Big) error {
+	err := m.c.CallContext(ctx, nil, "miner_setGasTip", gasTip)
+	return err
+}
+
 // SetRecommitInterval updates the interval for miner sealing work recommitting.
+// The interval is in milliseconds.
 // from MinerAPI
 // from web3ext.go
 // method
 func (m *Miner) SetRecommitInterval(ctx context.Context, interval int) error {
-	err := m.c.CallContext(ctx, nil, "miner_setRecommitInterval")
+	err := m.c.CallContext(ctx, nil, "miner_setRecommitInterval", interval)
 	return err
 }
 
@@ -0,0 +1,201 @@
+package web3_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+func TestMinerDevNode(t *testing.T) {
+	node := web3test.New(t)
+	w := node.Web3
+	ctx := context.Background()
+
+	etherbase := common.HexToAddress("0x00000000000000000000000000000000000000ee")
+	if ok, err := w.Miner.SetEtherbase(ctx, etherbase); err != nil || !ok {
+		t.Fatalf("SetEtherbase: %v %v", ok, err)
+	}
+	if coinbase, err := w.Eth.Coinbase(ctx); err != nil || coinbase != etherbase {
+		t.Fatalf("Coinbase = %v %v, want %v", coinbase, err, etherbase)
+	}
+	if ok, err := w.Miner.SetExtra(ctx, "web3-go"); err != nil || !ok {
+		t.Fatalf("SetExtra: %v %v", ok, err)
+	}
+	if ok, err := w.Miner.SetGasLimit(ctx, 20_000_000); err != nil || !ok {
+		t.Fatalf("SetGasLimit: %v %v", ok, err)
+	}
+	if ok, err := w.Miner.SetGasPrice(ctx, hexutil.Big(*big.NewInt(params.GWei))); err != nil || !ok {
+		t.Fatalf("SetGasPrice: %v %v", ok, err)
+	}
+	if err := w.Miner.SetRecommitInterval(ctx, 1000); err != nil {
+		t.Fatalf("SetRecommitInterval: %v", err)
+	}
+	if err := w.Miner.Start(ctx, nil); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := w.Miner.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	// geth 1.13 removed the threads parameter, the proof-of-work endpoints and
+	// has not gained miner_setGasTip yet.
+	threads := 1
+	if err := w.Miner.Start(ctx, &threads); err == nil {
+		t.Error("Start with threads succeeded on a node without the parameter")
+	}
+	if err := w.Miner.SetGasTip(ctx, hexutil.Big(*big.NewInt(params.GWei))); !errors.Is(err, web3.ErrMethodNotFound) {
+		t.Errorf("SetGasTip error = %v, want ErrMethodNotFound", err)
+	}
+	if _, err := w.Eth.GetWork(ctx); !errors.Is(err, web3.ErrMethodNotFound) {
+		t.Errorf("GetWork error = %v, want ErrMethodNotFound", err)
+	}
+	if _, err := w.Eth.SubmitHashrate(ctx, 100, common.Hash{1}); !errors.Is(err, web3.ErrMethodNotFound) {
+		t.Errorf("SubmitHashrate error = %v, want ErrMethodNotFound", err)
+	}
+
+	node.Commit(t)
+	number, err := w.Eth.BlockNumber(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if number != 1 {
+		t.Fatalf("BlockNumber = %d, want 1", number)
+	}
+	header, err := w.Eth.GetHeaderByNumber(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extra := header["extraData"]; extra != hexutil.Encode([]byte("web3-go")) {
+		t.Errorf("extraData = %v, want %q", extra, "web3-go")
+	}
+}
+
+func TestWorkPackageJSON(t *testing.T) {
+	var work web3.WorkPackage
+	input := `["0x1111111111111111111111111111111111111111111111111111111111111111","0x2222222222222222222222222222222222222222222222222222222222222222","0x3333333333333333333333333333333333333333333333333333333333333333","0x10"]`
+	if err := work.UnmarshalJSON([]byte(input)); err != nil {
+		t.Fatal(err)
+	}
+	if work.HeaderHash != common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111") || work.BlockNumber != 16 {
+		t.Errorf("unexpected work package %+v", work)
+	}
+	output, err := work.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != input {
+		t.Errorf("MarshalJSON = %s, want %s", output, input)
+	}
+}
+
+// powService is a stub proof-of-work node that records the parameters of the
+// miner and eth mining requests as sent.
+type powService struct {
+	params []string
+}
+
+func (s *powService) record(params ...json.RawMessage) {
+	for _, p := range params {
+		s.params = append(s.params, string(p))
+	}
+}
+
+func (s *powService) Start(threads *json.RawMessage) {
+	if threads == nil {
+		s.params = append(s.params, "no threads")
+		return
+	}
+	s.record(*threads)
+}
+
+func (s *powService) SetGasTip(tip json.RawMessage) bool {
+	s.record(tip)
+	return true
+}
+
+func (s *powService) GetWork() [4]string {
+	return [4]string{
+		"0x1111111111111111111111111111111111111111111111111111111111111111",
+		"0x2222222222222222222222222222222222222222222222222222222222222222",
+		"0x3333333333333333333333333333333333333333333333333333333333333333",
+		"0x2a",
+	}
+}
+
+func (s *powService) SubmitWork(nonce, hash, digest json.RawMessage) bool {
+	s.record(nonce, hash, digest)
+	return true
+}
+
+func (s *powService) SubmitHashrate(rate, id json.RawMessage) bool {
+	s.record(rate, id)
+	return false
+}
+
+func TestMinerProofOfWork(t *testing.T) {
+	s := &powService{}
+	server := rpc.NewServer()
+	for _, namespace := range []string{"eth", "miner"} {
+		if err := server.RegisterName(namespace, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer server.Stop()
+	client := rpc.DialInProc(server)
+	defer client.Close()
+	w := web3.NewWeb3(client)
+	ctx := context.Background()
+
+	threads := 4
+	if err := w.Miner.Start(ctx, &threads); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := w.Miner.Start(ctx, nil); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := w.Miner.SetGasTip(ctx, hexutil.Big(*big.NewInt(params.GWei))); err != nil {
+		t.Fatalf("SetGasTip: %v", err)
+	}
+	work, err := w.Eth.GetWork(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if work.HeaderHash != common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111") ||
+		work.SeedHash != common.HexToHash("0x2222222222222222222222222222222222222222222222222222222222222222") ||
+		work.Target != common.HexToHash("0x3333333333333333333333333333333333333333333333333333333333333333") ||
+		work.BlockNumber != 42 {
+		t.Errorf("GetWork = %+v", work)
+	}
+	if ok, err := w.Eth.SubmitWork(ctx, types.EncodeNonce(0x0102), common.Hash{0xaa}, common.Hash{0xbb}); err != nil || !ok {
+		t.Errorf("SubmitWork = %v %v", ok, err)
+	}
+	if ok, err := w.Eth.SubmitHashrate(ctx, 1000, common.Hash{0xcc}); err != nil || ok {
+		t.Errorf("SubmitHashrate = %v %v", ok, err)
+	}
+
+	want := []string{
+		`4`,
+		`no threads`,
+		`"0x3b9aca00"`,
+		`"0x0000000000000102"`,
+		`"0xaa00000000000000000000000000000000000000000000000000000000000000"`,
+		`"0xbb00000000000000000000000000000000000000000000000000000000000000"`,
+		`"0x3e8"`,
+		`"0xcc00000000000000000000000000000000000000000000000000000000000000"`,
+	}
+	if !reflect.DeepEqual(s.params, want) {
+		t.Errorf("parameters sent = %q\nwant %q", s.params, want)
+	}
+}
@@ -1,15 +1,18 @@
 package web3
 
-import (
-	"github.com/ethereum/go-ethereum/rpc"
-)
-
 type Miner struct {
-	c *rpc.Client
+	c    Client
+	gate *capabilityGate
 }
 
-func NewMiner(c *rpc.Client) *Miner {
+func NewMiner(c Client) *Miner {
 	e := &Miner{}
-	e.c = c
+	e.c = withCallErrors(c)
 	return e
 }
+
+// Available reports whether the node serves the miner namespace. It is true
+// until Web3.Capabilities has probed the node.
+func (m *Miner) Available() bool {
+	return m.gate.available("miner")
+}
@@ -0,0 +1,606 @@
+package web3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Strategy decides in which order a MultiClient tries its healthy endpoints.
+type Strategy int
+
+const (
+	// RoundRobin spreads requests evenly over the healthy endpoints.
+	RoundRobin Strategy = iota
+	// LeastLatency prefers the endpoint with the lowest observed latency.
+	LeastLatency
+	// PrimaryBackup always uses the first healthy endpoint in the order the
+	// endpoints were given, falling back to the next one on failure.
+	PrimaryBackup
+)
+
+func (s Strategy) String() string {
+	switch s {
+	case RoundRobin:
+		return "round-robin"
+	case LeastLatency:
+		return "least-latency"
+	case PrimaryBackup:
+		return "primary-backup"
+	}
+	return fmt.Sprintf("Strategy(%d)", int(s))
+}
+
+// ErrNoHealthyEndpoint is returned when every endpoint of a MultiClient
+// failed or has been ejected.
+var ErrNoHealthyEndpoint = errors.New("no healthy endpoint available")
+
+// MultiClientConfig configures the failover and health check behaviour of a
+// MultiClient.
+type MultiClientConfig struct {
+	Strategy Strategy
+
+	// MaxBlockLag ejects endpoints whose eth_blockNumber is more than
+	// MaxBlockLag blocks behind the highest head seen across all endpoints.
+	// Zero disables lag based ejection.
+	MaxBlockLag uint64
+
+	// HealthCheckInterval is the period of the background health check.
+	// Zero disables the background loop, CheckHealth can still be called
+	// manually.
+	HealthCheckInterval time.Duration
+
+	// HealthCheckTimeout bounds the health probe of a single endpoint.
+	// Defaults to 5 seconds.
+	HealthCheckTimeout time.Duration
+
+	// EjectionCooldown is how long an endpoint stays ejected after a request
+	// to it failed. It is tried again once the cooldown is over, so that a
+	// transient error does not eject it for good when no health check runs.
+	// A passing health check restores it earlier. Endpoints ejected by a
+	// health check stay ejected until a later check passes. Defaults to 30
+	// seconds.
+	EjectionCooldown time.Duration
+
+	// Metrics and TracerProvider, if either is set, instrument every endpoint
+	// with its own InstrumentedClient labelled by the endpoint name, so that
+	// requests are recorded under the endpoint that actually served them.
+	// Health checks are recorded as well.
+	Metrics        *Metrics
+	TracerProvider trace.TracerProvider
+}
+
+// Endpoint is a single node behind a MultiClient.
+type Endpoint struct {
+	Name string
+	c    Client
+
+	mu        sync.RWMutex
+	healthy   bool
+	syncing   bool
+	head      uint64
+	latency   time.Duration
+	lastError error
+	checkedAt time.Time
+	// failedAt is when a request ejected the endpoint, zero if it was not
+	// ejected by a request.
+	failedAt time.Time
+}
+
+func NewEndpoint(name string, c Client) *Endpoint {
+	e := &Endpoint{}
+	e.Name = name
+	e.c = c
+	e.healthy = true
+	return e
+}
+
+// EndpointStatus is a point in time view of the health of an Endpoint.
+type EndpointStatus struct {
+	Name      string
+	Healthy   bool
+	Syncing   bool
+	Head      uint64
+	Latency   time.Duration
+	LastError error
+	CheckedAt time.Time
+}
+
+// Status returns the last known health of the endpoint.
+func (e *Endpoint) Status() EndpointStatus {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return EndpointStatus{
+		Name:      e.Name,
+		Healthy:   e.healthy,
+		Syncing:   e.syncing,
+		Head:      e.head,
+		Latency:   e.latency,
+		LastError: e.lastError,
+		CheckedAt: e.checkedAt,
+	}
+}
+
+func (e *Endpoint) isHealthy() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.healthy
+}
+
+func (e *Endpoint) observeLatency(d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.latency == 0 {
+		e.latency = d
+	} else {
+		// exponentially weighted moving average, 1/4 weight for the new sample
+		e.latency = (3*e.latency + d) / 4
+	}
+}
+
+func (e *Endpoint) markFailed(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.healthy = false
+	e.lastError = err
+	e.failedAt = time.Now()
+}
+
+// readmit restores the endpoint if a request ejected it at least cooldown
+// ago, and reports whether it is healthy.
+func (e *Endpoint) readmit(cooldown time.Duration) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.healthy && !e.failedAt.IsZero() && time.Since(e.failedAt) >= cooldown {
+		e.healthy = true
+		e.failedAt = time.Time{}
+	}
+	return e.healthy
+}
+
+// MultiClient spreads requests over several endpoints, fails over to the next
+// endpoint when one of them cannot be reached and ejects endpoints that are
+// syncing or lagging behind the chain head. It implements Client and can be
+// passed to NewWeb3.
+type MultiClient struct {
+	config    MultiClientConfig
+	endpoints []*Endpoint
+	next      uint64
+
+	subsMu sync.Mutex
+	subs   map[*MultiSubscription]struct{}
+
+	quit      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func NewMultiClient(config MultiClientConfig, endpoints ...*Endpoint) *MultiClient {
+	if config.HealthCheckTimeout == 0 {
+		config.HealthCheckTimeout = 5 * time.Second
+	}
+	if config.EjectionCooldown == 0 {
+		config.EjectionCooldown = 30 * time.Second
+	}
+	m := &MultiClient{}
+	m.config = config
+	m.endpoints = endpoints
+	if config.Metrics != nil || config.TracerProvider != nil {
+		for _, e := range endpoints {
+			e.c = NewInstrumentedClient(e.c, e.Name, config.Metrics, config.TracerProvider)
+		}
+	}
+	m.subs = make(map[*MultiSubscription]struct{})
+	m.quit = make(chan struct{})
+	if config.HealthCheckInterval > 0 {
+		m.wg.Add(1)
+		go m.healthLoop()
+	}
+	return m
+}
+
+// DialMultiClient connects to every url and wraps the connections in a
+// MultiClient. The url doubles as the endpoint name.
+func DialMultiClient(ctx context.Context, config MultiClientConfig, urls ...string) (*MultiClient, error) {
+	endpoints := make([]*Endpoint, 0, len(urls))
+	for _, url := range urls {
+		c, err := rpc.DialContext(ctx, url)
+		if err != nil {
+			for _, e := range endpoints {
+				e.c.(*rpc.Client).Close()
+			}
+			return nil, fmt.Errorf("dial %s: %w", url, err)
+		}
+		endpoints = append(endpoints, NewEndpoint(url, c))
+	}
+	return NewMultiClient(config, endpoints...), nil
+}
+
+// Endpoints returns the endpoints in the order they were given.
+func (m *MultiClient) Endpoints() []*Endpoint {
+	return m.endpoints
+}
+
+// Close stops the health check loop and all subscriptions. Endpoints that
+// wrap an *rpc.Client are closed as well.
+func (m *MultiClient) Close() {
+	m.closeOnce.Do(func() {
+		close(m.quit)
+		m.wg.Wait()
+		m.subsMu.Lock()
+		subs := make([]*MultiSubscription, 0, len(m.subs))
+		for sub := range m.subs {
+			subs = append(subs, sub)
+		}
+		m.subsMu.Unlock()
+		for _, sub := range subs {
+			sub.Unsubscribe()
+		}
+		for _, e := range m.endpoints {
+			c := e.c
+			if i, ok := c.(*InstrumentedClient); ok {
+				c = i.c
+			}
+			if c, ok := c.(*rpc.Client); ok {
+				c.Close()
+			}
+		}
+	})
+}
+
+// candidates returns the endpoints in the order they should be tried for the
+// next request. Healthy endpoints come first, ejected ones are kept at the end
+// as a last resort so that a full outage of the health check does not block
+// every request. Endpoints whose EjectionCooldown is over are healthy again.
+func (m *MultiClient) candidates() []*Endpoint {
+	healthy, ejected := m.partition()
+	return append(healthy, ejected...)
+}
+
+// partition splits the endpoints into the healthy ones, in the order of the
+// strategy, and the ejected ones.
+func (m *MultiClient) partition() (healthy, ejected []*Endpoint) {
+	healthy = make([]*Endpoint, 0, len(m.endpoints))
+	for _, e := range m.endpoints {
+		if e.readmit(m.config.EjectionCooldown) {
+			healthy = append(healthy, e)
+		} else {
+			ejected = append(ejected, e)
+		}
+	}
+	switch m.config.Strategy {
+	case RoundRobin:
+		if n := len(healthy); n > 1 {
+			start := int(atomic.AddUint64(&m.next, 1) % uint64(n))
+			healthy = append(healthy[start:], healthy[:start]...)
+		}
+	case LeastLatency:
+		latency := make(map[*Endpoint]time.Duration, len(healthy))
+		for _, e := range healthy {
+			latency[e] = e.Status().Latency
+		}
+		// insertion sort, the endpoint list is short
+		for i := 1; i < len(healthy); i++ {
+			for j := i; j > 0 && latency[healthy[j]] < latency[healthy[j-1]]; j-- {
+				healthy[j], healthy[j-1] = healthy[j-1], healthy[j]
+			}
+		}
+	case PrimaryBackup:
+	}
+	return healthy, ejected
+}
+
+// isEndpointFailure reports whether err means the endpoint could not serve the
+// request, as opposed to the node answering with a JSON-RPC error or with a
+// result that does not decode into the requested type.
+func isEndpointFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var (
+		rpcErr  rpc.Error
+		typeErr *json.UnmarshalTypeError
+	)
+	return !errors.As(err, &rpcErr) && !errors.As(err, &typeErr)
+}
+
+func (m *MultiClient) do(ctx context.Context, fn func(e *Endpoint) error) error {
+	candidates := m.candidates()
+	if len(candidates) == 0 {
+		return ErrNoHealthyEndpoint
+	}
+	var errs []error
+	for _, e := range candidates {
+		start := time.Now()
+		err := fn(e)
+		if !isEndpointFailure(ctx, err) {
+			if err == nil {
+				e.observeLatency(time.Since(start))
+			}
+			return err
+		}
+		e.markFailed(err)
+		errs = append(errs, fmt.Errorf("%s: %w", e.Name, err))
+	}
+	return fmt.Errorf("%w: %w", ErrNoHealthyEndpoint, errors.Join(errs...))
+}
+
+// CallContext sends the request to the preferred endpoint and fails over to
+// the next one if the endpoint cannot be reached.
+func (m *MultiClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return m.do(ctx, func(e *Endpoint) error {
+		return e.c.CallContext(ctx, result, method, args...)
+	})
+}
+
+// BatchCallContext sends the whole batch to a single endpoint, failing over
+// to the next one if the endpoint cannot be reached.
+func (m *MultiClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	return m.do(ctx, func(e *Endpoint) error {
+		return e.c.BatchCallContext(ctx, b)
+	})
+}
+
+func (m *MultiClient) healthLoop() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.config.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.CheckHealth(context.Background())
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// CheckHealth probes every endpoint with eth_blockNumber and eth_syncing and
+// ejects the ones that fail, are syncing or lag behind the highest head by
+// more than MaxBlockLag blocks. Ejected endpoints are restored as soon as a
+// later check passes. Sticky subscriptions on ejected endpoints are moved to
+// a healthy one.
+func (m *MultiClient) CheckHealth(ctx context.Context) []EndpointStatus {
+	type probe struct {
+		head    uint64
+		syncing bool
+		latency time.Duration
+		err     error
+	}
+	probes := make([]probe, len(m.endpoints))
+	var wg sync.WaitGroup
+	for i, e := range m.endpoints {
+		wg.Add(1)
+		go func(i int, e *Endpoint) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, m.config.HealthCheckTimeout)
+			defer cancel()
+			var (
+				head    hexutil.Uint64
+				syncing interface{}
+			)
+			start := time.Now()
+			batch := []rpc.BatchElem{
+				{Method: "eth_blockNumber", Result: &head},
+				{Method: "eth_syncing", Result: &syncing},
+			}
+			err := e.c.BatchCallContext(ctx, batch)
+			latency := time.Since(start)
+			for _, elem := range batch {
+				if err == nil && elem.Error != nil {
+					err = fmt.Errorf("%s: %w", elem.Method, elem.Error)
+				}
+			}
+			isSyncing, _ := syncing.(bool)
+			if _, ok := syncing.(map[string]interface{}); ok {
+				isSyncing = true
+			}
+			probes[i] = probe{head: uint64(head), syncing: isSyncing, latency: latency, err: err}
+		}(i, e)
+	}
+	wg.Wait()
+
+	var best uint64
+	for _, p := range probes {
+		if p.err == nil && p.head > best {
+			best = p.head
+		}
+	}
+	statuses := make([]EndpointStatus, len(m.endpoints))
+	for i, e := range m.endpoints {
+		p := probes[i]
+		e.mu.Lock()
+		e.checkedAt = time.Now()
+		e.failedAt = time.Time{}
+		e.lastError = p.err
+		e.syncing = p.syncing
+		if p.err == nil {
+			e.head = p.head
+			if e.latency == 0 {
+				e.latency = p.latency
+			} else {
+				e.latency = (3*e.latency + p.latency) / 4
+			}
+		}
+		lagging := m.config.MaxBlockLag > 0 && e.head < best && best-e.head > m.config.MaxBlockLag
+		switch {
+		case p.err != nil:
+			e.healthy = false
+		case p.syncing:
+			e.healthy = false
+			e.lastError = fmt.Errorf("endpoint is syncing")
+		case lagging:
+			e.healthy = false
+			e.lastError = fmt.Errorf("endpoint is %d blocks behind head %d", best-e.head, best)
+		default:
+			e.healthy = true
+		}
+		e.mu.Unlock()
+		statuses[i] = e.Status()
+	}
+
+	m.subsMu.Lock()
+	for sub := range m.subs {
+		sub.checkEndpoint()
+	}
+	m.subsMu.Unlock()
+	return statuses
+}
+
+// subscriber is implemented by clients that support subscriptions, such as
+// *rpc.Client.
+type subscriber interface {
+	Subscribe(ctx context.Context, namespace string, channel interface{}, args ...interface{}) (*rpc.ClientSubscription, error)
+}
+
+// MultiSubscription is a subscription that sticks to one endpoint of a
+// MultiClient and transparently re-subscribes on another endpoint when the
+// current one fails or is ejected. Notifications keep arriving on the channel
+// given to Subscribe. It implements ethereum.Subscription.
+type MultiSubscription struct {
+	m         *MultiClient
+	namespace string
+	channel   interface{}
+	args      []interface{}
+
+	mu       sync.Mutex
+	endpoint *Endpoint
+	sub      *rpc.ClientSubscription
+
+	failover chan struct{}
+	unsub    chan struct{}
+	err      chan error
+	once     sync.Once
+}
+
+// Subscribe creates a subscription on the preferred endpoint that supports
+// subscriptions. Notifications are delivered to channel, which must be a
+// writable channel of the notification type, exactly as for
+// (*rpc.Client).Subscribe.
+func (m *MultiClient) Subscribe(ctx context.Context, namespace string, channel interface{}, args ...interface{}) (*MultiSubscription, error) {
+	s := &MultiSubscription{}
+	s.m = m
+	s.namespace = namespace
+	s.channel = channel
+	s.args = args
+	s.failover = make(chan struct{}, 1)
+	s.unsub = make(chan struct{})
+	s.err = make(chan error, 1)
+	if err := s.subscribe(ctx, nil); err != nil {
+		return nil, err
+	}
+	m.subsMu.Lock()
+	m.subs[s] = struct{}{}
+	m.subsMu.Unlock()
+	go s.loop()
+	return s, nil
+}
+
+// EthSubscribe registers a subscription under the "eth" namespace.
+func (m *MultiClient) EthSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (*MultiSubscription, error) {
+	return m.Subscribe(ctx, "eth", channel, args...)
+}
+
+func (s *MultiSubscription) subscribe(ctx context.Context, skip *Endpoint) error {
+	var errs []error
+	for _, e := range s.m.candidates() {
+		if e == skip {
+			continue
+		}
+		sc, ok := e.c.(subscriber)
+		if !ok {
+			continue
+		}
+		sub, err := sc.Subscribe(ctx, s.namespace, s.channel, s.args...)
+		if err != nil {
+			if isEndpointFailure(ctx, err) {
+				e.markFailed(err)
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", e.Name, err))
+			continue
+		}
+		s.mu.Lock()
+		s.endpoint = e
+		s.sub = sub
+		s.mu.Unlock()
+		return nil
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("%w: no endpoint supports subscriptions", ErrNoHealthyEndpoint)
+	}
+	return fmt.Errorf("%w: %w", ErrNoHealthyEndpoint, errors.Join(errs...))
+}
+
+// Endpoint returns the endpoint currently serving the subscription.
+func (s *MultiSubscription) Endpoint() *Endpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.endpoint
+}
+
+func (s *MultiSubscription) checkEndpoint() {
+	if e := s.Endpoint(); e != nil && !e.isHealthy() {
+		select {
+		case s.failover <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *MultiSubscription) loop() {
+	defer func() {
+		s.m.subsMu.Lock()
+		delete(s.m.subs, s)
+		s.m.subsMu.Unlock()
+	}()
+	for {
+		s.mu.Lock()
+		sub, endpoint := s.sub, s.endpoint
+		s.mu.Unlock()
+		select {
+		case <-s.unsub:
+			sub.Unsubscribe()
+			close(s.err)
+			return
+		case err := <-sub.Err():
+			if err == nil {
+				// unsubscribed underneath us, e.g. the client was closed
+				close(s.err)
+				return
+			}
+			endpoint.markFailed(err)
+		case <-s.failover:
+			// the signal may predate a move to a healthy endpoint
+			if endpoint.isHealthy() {
+				continue
+			}
+			sub.Unsubscribe()
+		}
+		if err := s.subscribe(context.Background(), endpoint); err != nil {
+			s.err <- err
+			close(s.err)
+			return
+		}
+	}
+}
+
+// Unsubscribe stops the subscription on whichever endpoint currently serves it.
+func (s *MultiSubscription) Unsubscribe() {
+	s.once.Do(func() {
+		close(s.unsub)
+	})
+}
+
+// Err returns the subscription error channel. It only receives an error when
+// re-subscribing failed on every endpoint, and is closed on Unsubscribe.
+func (s *MultiSubscription) Err() <-chan error {
+	return s.err
+}
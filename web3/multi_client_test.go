@@ -0,0 +1,336 @@
+package web3_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+)
+
+// endpointService is a stub node behind one endpoint of a MultiClient. Its
+// chain ID is the endpoint number so that results tell who answered.
+type endpointService struct {
+	id int
+
+	mu      sync.Mutex
+	head    uint64
+	syncing bool
+	delay   time.Duration
+	calls   int
+}
+
+func (s *endpointService) ChainId() hexutil.Uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	time.Sleep(s.delay)
+	return hexutil.Uint64(s.id)
+}
+
+func (s *endpointService) BlockNumber() hexutil.Uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return hexutil.Uint64(s.head)
+}
+
+func (s *endpointService) Syncing() interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.syncing {
+		return map[string]hexutil.Uint64{"currentBlock": hexutil.Uint64(s.head)}
+	}
+	return false
+}
+
+func (s *endpointService) Fail() error {
+	return errors.New("failed")
+}
+
+// Ticks notifies the endpoint number every few milliseconds.
+func (s *endpointService) Ticks(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, ok := rpc.NotifierFromContext(ctx)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+	go func() {
+		for {
+			select {
+			case <-time.After(5 * time.Millisecond):
+				notifier.Notify(sub.ID, s.id)
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+func (s *endpointService) setHealth(head uint64, syncing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.head, s.syncing = head, syncing
+}
+
+// newEndpoints starts n stub nodes and returns their services, servers and
+// endpoints, named "0" to "n-1".
+func newEndpoints(t *testing.T, n int) ([]*endpointService, []*rpc.Server, []*web3.Endpoint) {
+	var (
+		services  []*endpointService
+		servers   []*rpc.Server
+		endpoints []*web3.Endpoint
+	)
+	for i := 0; i < n; i++ {
+		s := &endpointService{id: i, head: 100}
+		server := rpc.NewServer()
+		if err := server.RegisterName("eth", s); err != nil {
+			t.Fatal(err)
+		}
+		if err := server.RegisterName("test", s); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(server.Stop)
+		client := rpc.DialInProc(server)
+		t.Cleanup(client.Close)
+		services = append(services, s)
+		servers = append(servers, server)
+		endpoints = append(endpoints, web3.NewEndpoint(string(rune('0'+i)), client))
+	}
+	return services, servers, endpoints
+}
+
+func chainID(t *testing.T, m *web3.MultiClient) int {
+	t.Helper()
+	var id hexutil.Uint64
+	if err := m.CallContext(context.Background(), &id, "eth_chainId"); err != nil {
+		t.Fatalf("eth_chainId: %v", err)
+	}
+	return int(id)
+}
+
+func TestMultiClientPrimaryBackup(t *testing.T) {
+	_, servers, endpoints := newEndpoints(t, 3)
+	m := web3.NewMultiClient(web3.MultiClientConfig{Strategy: web3.PrimaryBackup}, endpoints...)
+	defer m.Close()
+
+	for i := 0; i < 3; i++ {
+		if id := chainID(t, m); id != 0 {
+			t.Fatalf("call %d answered by %d, want the primary", i, id)
+		}
+	}
+	// a JSON-RPC error is an answer, not an endpoint failure
+	if err := m.CallContext(context.Background(), nil, "eth_fail"); err == nil || !endpoints[0].Status().Healthy {
+		t.Errorf("eth_fail = %v, primary healthy %v", err, endpoints[0].Status().Healthy)
+	}
+
+	servers[0].Stop()
+	if id := chainID(t, m); id != 1 {
+		t.Errorf("call with the primary down answered by %d, want the first backup", id)
+	}
+	if status := endpoints[0].Status(); status.Healthy || status.LastError == nil {
+		t.Errorf("primary status after failing = %+v", status)
+	}
+	servers[1].Stop()
+	if id := chainID(t, m); id != 2 {
+		t.Errorf("call with two endpoints down answered by %d", id)
+	}
+	servers[2].Stop()
+	if err := m.CallContext(context.Background(), nil, "eth_chainId"); !errors.Is(err, web3.ErrNoHealthyEndpoint) {
+		t.Errorf("call with every endpoint down: %v, want %v", err, web3.ErrNoHealthyEndpoint)
+	}
+}
+
+// flakyClient fails its next failures requests before reaching the node.
+type flakyClient struct {
+	web3.Client
+	failures int
+}
+
+func (c *flakyClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if c.failures > 0 {
+		c.failures--
+		return errors.New("connection reset by peer")
+	}
+	return c.Client.CallContext(ctx, result, method, args...)
+}
+
+func TestMultiClientEjectionCooldown(t *testing.T) {
+	var endpoints []*web3.Endpoint
+	for i := 0; i < 2; i++ {
+		server := rpc.NewServer()
+		if err := server.RegisterName("eth", &endpointService{id: i}); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(server.Stop)
+		client := rpc.DialInProc(server)
+		t.Cleanup(client.Close)
+		var c web3.Client = client
+		if i == 0 {
+			c = &flakyClient{Client: client, failures: 1}
+		}
+		endpoints = append(endpoints, web3.NewEndpoint(string(rune('0'+i)), c))
+	}
+	m := web3.NewMultiClient(web3.MultiClientConfig{Strategy: web3.PrimaryBackup, EjectionCooldown: 50 * time.Millisecond}, endpoints...)
+	defer m.Close()
+
+	// a transient error ejects the primary for the cooldown only
+	if id := chainID(t, m); id != 1 {
+		t.Fatalf("call with the primary failing answered by %d, want the backup", id)
+	}
+	if id := chainID(t, m); id != 1 || endpoints[0].Status().Healthy {
+		t.Errorf("call during the cooldown answered by %d, primary healthy %v", id, endpoints[0].Status().Healthy)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if id := chainID(t, m); id != 0 || !endpoints[0].Status().Healthy {
+		t.Errorf("call after the cooldown answered by %d, primary healthy %v", id, endpoints[0].Status().Healthy)
+	}
+}
+
+func TestMultiClientRoundRobin(t *testing.T) {
+	services, _, endpoints := newEndpoints(t, 3)
+	m := web3.NewMultiClient(web3.MultiClientConfig{Strategy: web3.RoundRobin}, endpoints...)
+	defer m.Close()
+
+	seen := make(map[int]int)
+	for i := 0; i < 6; i++ {
+		seen[chainID(t, m)]++
+	}
+	for i, s := range services {
+		if seen[i] != 2 || s.calls != 2 {
+			t.Errorf("endpoint %d answered %d of 6 calls, want 2", i, seen[i])
+		}
+	}
+}
+
+func TestMultiClientLeastLatency(t *testing.T) {
+	services, _, endpoints := newEndpoints(t, 2)
+	services[0].delay = 20 * time.Millisecond
+	m := web3.NewMultiClient(web3.MultiClientConfig{Strategy: web3.LeastLatency}, endpoints...)
+	defer m.Close()
+
+	m.CheckHealth(context.Background())
+	// the first calls measure both endpoints, the fast one wins afterwards
+	chainID(t, m)
+	chainID(t, m)
+	for i := 0; i < 3; i++ {
+		if id := chainID(t, m); id != 1 {
+			t.Errorf("call %d answered by %d, want the fast endpoint", i, id)
+		}
+	}
+}
+
+func TestMultiClientCheckHealth(t *testing.T) {
+	services, servers, endpoints := newEndpoints(t, 4)
+	m := web3.NewMultiClient(web3.MultiClientConfig{Strategy: web3.PrimaryBackup, MaxBlockLag: 5}, endpoints...)
+	defer m.Close()
+	ctx := context.Background()
+
+	services[0].setHealth(100, true) // syncing
+	services[1].setHealth(90, false) // lagging
+	services[2].setHealth(104, false)
+	services[3].setHealth(100, false)
+	statuses := m.CheckHealth(ctx)
+	for i, want := range []bool{false, false, true, true} {
+		if statuses[i].Healthy != want {
+			t.Errorf("endpoint %d healthy = %v, want %v: %v", i, statuses[i].Healthy, want, statuses[i].LastError)
+		}
+	}
+	if statuses[2].Head != 104 || statuses[2].CheckedAt.IsZero() {
+		t.Errorf("status = %+v", statuses[2])
+	}
+	if id := chainID(t, m); id != 2 {
+		t.Errorf("call answered by %d, want the first healthy endpoint", id)
+	}
+	if services[0].calls != 0 || services[1].calls != 0 {
+		t.Error("ejected endpoints were called")
+	}
+
+	// recovered endpoints are restored, unreachable ones ejected
+	services[0].setHealth(104, false)
+	servers[2].Stop()
+	statuses = m.CheckHealth(ctx)
+	for i, want := range []bool{true, false, false, true} {
+		if statuses[i].Healthy != want {
+			t.Errorf("endpoint %d healthy after recovery = %v, want %v: %v", i, statuses[i].Healthy, want, statuses[i].LastError)
+		}
+	}
+	if id := chainID(t, m); id != 0 {
+		t.Errorf("call answered by %d, want the restored primary", id)
+	}
+}
+
+func TestMultiClientHealthLoop(t *testing.T) {
+	services, _, endpoints := newEndpoints(t, 2)
+	services[0].setHealth(100, true)
+	m := web3.NewMultiClient(web3.MultiClientConfig{HealthCheckInterval: 5 * time.Millisecond}, endpoints...)
+	defer m.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for endpoints[0].Status().Healthy || endpoints[0].Status().CheckedAt.IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatal("the health loop did not eject the syncing endpoint")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMultiSubscriptionResubscribe(t *testing.T) {
+	_, servers, endpoints := newEndpoints(t, 3)
+	m := web3.NewMultiClient(web3.MultiClientConfig{Strategy: web3.PrimaryBackup}, endpoints...)
+	defer m.Close()
+
+	ticks := make(chan int, 16)
+	sub, err := m.Subscribe(context.Background(), "test", ticks, "ticks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+	waitTick := func(want int) {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case id := <-ticks:
+				if id == want {
+					return
+				}
+			case err := <-sub.Err():
+				t.Fatalf("subscription failed: %v", err)
+			case <-timeout:
+				t.Fatalf("no notification from endpoint %d", want)
+			}
+		}
+	}
+	waitTick(0)
+	if sub.Endpoint() != endpoints[0] {
+		t.Fatalf("subscription on %s, want the primary", sub.Endpoint().Name)
+	}
+
+	// the endpoint drops the connection
+	servers[0].Stop()
+	waitTick(1)
+	if sub.Endpoint() != endpoints[1] || endpoints[0].Status().Healthy {
+		t.Errorf("subscription on %s after the primary dropped", sub.Endpoint().Name)
+	}
+
+	// the health check ejects the endpoint serving the subscription
+	servers[1].Stop()
+	m.CheckHealth(context.Background())
+	waitTick(2)
+
+	servers[2].Stop()
+	select {
+	case err := <-sub.Err():
+		if !errors.Is(err, web3.ErrNoHealthyEndpoint) {
+			t.Errorf("subscription error = %v, want %v", err, web3.ErrNoHealthyEndpoint)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("no subscription error with every endpoint down")
+	}
+}
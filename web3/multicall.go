@@ -0,0 +1,246 @@
+package web3
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Multicall3Address is the address Multicall3 is deployed at on most chains.
+var Multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// multicall3Code is the runtime of the canonical Multicall3 deployed at
+// Multicall3Address, from github.com/mds1/multicall. It is deployed with a
+// state override on chains without Multicall3.
+var multicall3Code = hexutil.MustDecode("0x6080604052600436106100f35760003560e01c80634d2301cc1161008a578063a8b0574e11610059578063a8b0574e1461025a578063bce38bd714610275578063c3077fa914610288578063ee82ac5e1461029b57600080fd5b80634d2301cc146101ec57806372425d9d1461022157806382ad56cb1461023457806386d516e81461024757600080fd5b80633408e470116100c65780633408e47014610191578063399542e9146101a45780633e64a696146101c657806342cbb15c146101d957600080fd5b80630f28c97d146100f8578063174dea711461011a578063252dba421461013a57806327e86d6e1461015b575b600080fd5b34801561010457600080fd5b50425b6040519081526020015b60405180910390f35b61012d610128366004610a85565b6102ba565b6040516101119190610bbe565b61014d610148366004610a85565b6104ef565b604051610111929190610bd8565b34801561016757600080fd5b50437fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff0140610107565b34801561019d57600080fd5b5046610107565b6101b76101b2366004610c60565b610690565b60405161011193929190610cba565b3480156101d257600080fd5b5048610107565b3480156101e557600080fd5b5043610107565b3480156101f857600080fd5b50610107610207366004610ce2565b73ffffffffffffffffffffffffffffffffffffffff163190565b34801561022d57600080fd5b5044610107565b61012d610242366004610a85565b6106ab565b34801561025357600080fd5b5045610107565b34801561026657600080fd5b50604051418152602001610111565b61012d610283366004610c60565b61085a565b6101b7610296366004610a85565b610a1a565b3480156102a757600080fd5b506101076102b6366004610d18565b4090565b60606000828067ffffffffffffffff8111156102d8576102d8610d31565b60405190808252806020026020018201604052801561031e57816020015b6040805180820190915260008152606060208201528152602001906001900390816102f65790505b5092503660005b8281101561047757600085828151811061034157610341610d60565b6020026020010151905087878381811061035d5761035d610d60565b905060200281019061036f9190610d8f565b6040810135958601959093506103886020850185610ce2565b73ffffffffffffffffffffffffffffffffffffffff16816103ac6060870187610dcd565b6040516103ba929190610e32565b60006040518083038185875af1925050503d80600081146103f7576040519150601f19603f3d011682016040523d82523d6000602084013e6103fc565b606091505b50602080850191909152901515808452908501351761046d577f08c379a000000000000000000000000000000000000000000000000000000000600052602060045260176024527f4d756c746963616c6c333a2063616c6c206661696c656400000000000000000060445260846000fd5b5050600101610325565b508234146104e6576040517f08c379a000000000000000000000000000000000000000000000000000000000815260206004820152601a60248201527f4d756c746963616c6c333a2076616c7565206d69736d6174636800000000000060448201526064015b60405180910390fd5b50505092915050565b436060828067ffffffffffffffff81111561050c5761050c610d31565b60405190808252806020026020018201604052801561053f57816020015b606081526020019060019003908161052a5790505b5091503660005b8281101561068657600087878381811061056257610562610d60565b90506020028101906105749190610e42565b92506105836020840184610ce2565b73ffffffffffffffffffffffffffffffffffffffff166105a66020850185610dcd565b6040516105b4929190610e32565b6000604051808303816000865af19150503d80600081146105f1576040519150601f19603f3d011682016040523d82523d6000602084013e6105f6565b606091505b5086848151811061060957610609610d60565b602090810291909101015290508061067d576040517f08c379a000000000000000000000000000000000000000000000000000000000815260206004820152601760248201527f4d756c746963616c6c333a2063616c6c206661696c656400000000000000000060448201526064016104dd565b50600101610546565b5050509250929050565b43804060606106a086868661085a565b905093509350939050565b6060818067ffffffffffffffff8111156106c7576106c7610d31565b60405190808252806020026020018201604052801561070d57816020015b6040805180820190915260008152606060208201528152602001906001900390816106e55790505b5091503660005b828110156104e657600084828151811061073057610730610d60565b6020026020010151905086868381811061074c5761074c610d60565b905060200281019061075e9190610e76565b925061076d6020840184610ce2565b73ffffffffffffffffffffffffffffffffffffffff166107906040850185610dcd565b60405161079e929190610e32565b6000604051808303816000865af19150503d80600081146107db576040519150601f19603f3d011682016040523d82523d6000602084013e6107e0565b606091505b506020808401919091529015158083529084013517610851577f08c379a000000000000000000000000000000000000000000000000000000000600052602060045260176024527f4d756c746963616c6c333a2063616c6c206661696c656400000000000000000060445260646000fd5b50600101610714565b6060818067ffffffffffffffff81111561087657610876610d31565b6040519080825280602002602001820160405280156108bc57816020015b6040805180820190915260008152606060208201528152602001906001900390816108945790505b5091503660005b82811015610a105760008482815181106108df576108df610d60565b602002602001015190508686838181106108fb576108fb610d60565b905060200281019061090d9190610e42565b925061091c6020840184610ce2565b73ffffffffffffffffffffffffffffffffffffffff1661093f6020850185610dcd565b60405161094d929190610e32565b6000604051808303816000865af19150503d806000811461098a576040519150601f19603f3d011682016040523d82523d6000602084013e61098f565b606091505b506020830152151581528715610a07578051610a07576040517f08c379a000000000000000000000000000000000000000000000000000000000815260206004820152601760248201527f4d756c746963616c6c333a2063616c6c206661696c656400000000000000000060448201526064016104dd565b506001016108c3565b5050509392505050565b6000806060610a2b60018686610690565b919790965090945092505050565b60008083601f840112610a4b57600080fd5b50813567ffffffffffffffff811115610a6357600080fd5b6020830191508360208260051b8501011115610a7e57600080fd5b9250929050565b60008060208385031215610a9857600080fd5b823567ffffffffffffffff811115610aaf57600080fd5b610abb85828601610a39565b90969095509350505050565b6000815180845260005b81811015610aed57602081850181015186830182015201610ad1565b81811115610aff576000602083870101525b50601f017fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe0169290920160200192915050565b600082825180855260208086019550808260051b84010181860160005b84811015610bb1578583037fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe001895281518051151584528401516040858501819052610b9d81860183610ac7565b9a86019a9450505090830190600101610b4f565b5090979650505050505050565b602081526000610bd16020830184610b32565b9392505050565b600060408201848352602060408185015281855180845260608601915060608160051b870101935082870160005b82811015610c52577fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffa0888703018452610c40868351610ac7565b95509284019290840190600101610c06565b509398975050505050505050565b600080600060408486031215610c7557600080fd5b83358015158114610c8557600080fd5b9250602084013567ffffffffffffffff811115610ca157600080fd5b610cad86828701610a39565b9497909650939450505050565b838152826020820152606060408201526000610cd96060830184610b32565b95945050505050565b600060208284031215610cf457600080fd5b813573ffffffffffffffffffffffffffffffffffffffff81168114610bd157600080fd5b600060208284031215610d2a57600080fd5b5035919050565b7f4e487b7100000000000000000000000000000000000000000000000000000000600052604160045260246000fd5b7f4e487b7100000000000000000000000000000000000000000000000000000000600052603260045260246000fd5b600082357fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff81833603018112610dc357600080fd5b9190910192915050565b60008083357fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe1843603018112610e0257600080fd5b83018035915067ffffffffffffffff821115610e1d57600080fd5b602001915036819003821315610a7e57600080fd5b8183823760009101908152919050565b600082357fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffc1833603018112610dc357600080fd5b600082357fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffa1833603018112610dc357600080fdfea2646970667358221220bb2b5c71a328032f97c676ae39a1ec2148d3e5d6f73d95e9b17910152d61f16264736f6c634300080c0033")
+
+// aggregate3Selector is the selector of
+// aggregate3((address,bool,bytes)[]).
+var aggregate3Selector = []byte{0x82, 0xad, 0x56, 0xcb}
+
+var (
+	aggregate3Args    abi.Arguments
+	aggregate3Results abi.Arguments
+)
+
+func init() {
+	calls, err := abi.NewType("tuple[]", "", []abi.ArgumentMarshaling{
+		{Name: "target", Type: "address"},
+		{Name: "allowFailure", Type: "bool"},
+		{Name: "callData", Type: "bytes"},
+	})
+	if err != nil {
+		panic(err)
+	}
+	results, err := abi.NewType("tuple[]", "", []abi.ArgumentMarshaling{
+		{Name: "success", Type: "bool"},
+		{Name: "returnData", Type: "bytes"},
+	})
+	if err != nil {
+		panic(err)
+	}
+	aggregate3Args = abi.Arguments{{Type: calls}}
+	aggregate3Results = abi.Arguments{{Type: results}}
+}
+
+// aggregate3Call and aggregate3Result are the ABI tuples of aggregate3.
+type aggregate3Call struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type aggregate3Result struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// MulticallCall is one call of a Multicall.
+type MulticallCall struct {
+	Target   common.Address
+	CallData []byte
+	// AllowFailure lets the call revert without failing the others of its
+	// batch.
+	AllowFailure bool
+	// Gas is the gas the call is expected to use when splitting calls into
+	// batches, MulticallConfig.CallGas if zero.
+	Gas uint64
+}
+
+// MulticallResult is the outcome of one call of a Multicall.
+type MulticallResult struct {
+	Success    bool
+	ReturnData []byte
+	// Revert is the decoded revert reason of a failed call, nil if it
+	// succeeded or the reason is unknown to MulticallConfig.Signatures.
+	Revert *DecodedCall
+}
+
+// MulticallConfig tunes a Multicall. Zero values get defaults.
+type MulticallConfig struct {
+	// Address is the Multicall3 contract, Multicall3Address by default.
+	Address common.Address
+	// Block is the block calls are made at, the latest by default.
+	Block *rpc.BlockNumberOrHash
+	// MaxGas is the gas limit of every aggregate3 call and the budget of the
+	// calls of a batch, 25M by default.
+	MaxGas uint64
+	// CallGas is the gas budgeted for calls without Gas, 100k by default.
+	CallGas uint64
+	// MaxCalldata is the budget of the encoded calls of a batch in bytes,
+	// 100KB by default.
+	MaxCalldata int
+	// Signatures decodes revert reasons, DefaultSignatureDB by default.
+	Signatures *SignatureDB
+}
+
+// Multicall packs calls into aggregate3 calls of Multicall3, so that many
+// reads take a few Eth.Call round trips. Multicall3 is deployed with a state
+// override on chains where it is missing.
+type Multicall struct {
+	eth    *Eth
+	config MulticallConfig
+
+	mu        sync.Mutex
+	overrides *StateOverride
+	checked   bool
+}
+
+func NewMulticall(w *Web3, config MulticallConfig) *Multicall {
+	if config.Address == (common.Address{}) {
+		config.Address = Multicall3Address
+	}
+	if config.MaxGas == 0 {
+		config.MaxGas = 25_000_000
+	}
+	if config.CallGas == 0 {
+		config.CallGas = 100_000
+	}
+	if config.MaxCalldata == 0 {
+		config.MaxCalldata = 100_000
+	}
+	if config.Signatures == nil {
+		config.Signatures = DefaultSignatureDB()
+	}
+	m := &Multicall{}
+	m.eth = w.Eth
+	m.config = config
+	return m
+}
+
+// stateOverride returns the state override deploying multicall3Code if there
+// is no code at the configured address, nil otherwise. The check is done
+// once.
+func (m *Multicall) stateOverride(ctx context.Context) (*StateOverride, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.checked {
+		return m.overrides, nil
+	}
+	block := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if m.config.Block != nil {
+		block = *m.config.Block
+	}
+	code, err := m.eth.GetCode(ctx, m.config.Address, block)
+	if err != nil {
+		return nil, err
+	}
+	if len(code) == 0 {
+		deployed := hexutil.Bytes(multicall3Code)
+		m.overrides = &StateOverride{m.config.Address: {Code: &deployed}}
+	}
+	m.checked = true
+	return m.overrides, nil
+}
+
+// batches splits calls into the [start, end) ranges of the aggregate3 calls,
+// within the gas and calldata budgets.
+func (m *Multicall) batches(calls []MulticallCall) [][2]int {
+	var batches [][2]int
+	start, gas, size := 0, uint64(0), 0
+	for i, call := range calls {
+		callGas := call.Gas
+		if callGas == 0 {
+			callGas = m.config.CallGas
+		}
+		// head, target, allowFailure, offset, length and padded data
+		callSize := 5*32 + (len(call.CallData)+31)/32*32
+		if i > start && (gas+callGas > m.config.MaxGas || size+callSize > m.config.MaxCalldata) {
+			batches = append(batches, [2]int{start, i})
+			start, gas, size = i, 0, 0
+		}
+		gas += callGas
+		size += callSize
+	}
+	if start < len(calls) {
+		batches = append(batches, [2]int{start, len(calls)})
+	}
+	return batches
+}
+
+// Call makes calls and returns their results in the same order. It fails if
+// a call without AllowFailure reverts, or if an aggregate3 call fails.
+func (m *Multicall) Call(ctx context.Context, calls []MulticallCall) ([]MulticallResult, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+	overrides, err := m.stateOverride(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]MulticallResult, 0, len(calls))
+	for _, batch := range m.batches(calls) {
+		batchResults, err := m.aggregate(ctx, calls[batch[0]:batch[1]], overrides)
+		if err != nil {
+			return nil, fmt.Errorf("multicall of calls %d to %d: %w", batch[0], batch[1]-1, err)
+		}
+		results = append(results, batchResults...)
+	}
+	return results, nil
+}
+
+func (m *Multicall) aggregate(ctx context.Context, calls []MulticallCall, overrides *StateOverride) ([]MulticallResult, error) {
+	packed := make([]aggregate3Call, len(calls))
+	for i, call := range calls {
+		packed[i] = aggregate3Call{Target: call.Target, AllowFailure: call.AllowFailure, CallData: call.CallData}
+	}
+	encoded, err := aggregate3Args.Pack(packed)
+	if err != nil {
+		return nil, err
+	}
+	input := hexutil.Bytes(append(common.CopyBytes(aggregate3Selector), encoded...))
+	gas := hexutil.Uint64(m.config.MaxGas)
+	output, err := m.eth.Call(ctx, TransactionArgs{To: &m.config.Address, Gas: &gas, Input: &input}, m.config.Block, overrides, nil)
+	if err != nil {
+		if revert, ok := RevertData(err); ok {
+			if reason, decodeErr := m.config.Signatures.DecodeRevert(revert); decodeErr == nil {
+				return nil, fmt.Errorf("%w: %s", err, reason)
+			}
+		}
+		return nil, err
+	}
+	values, err := aggregate3Results.Unpack(output)
+	if err != nil {
+		return nil, fmt.Errorf("invalid aggregate3 result: %w", err)
+	}
+	var decoded []aggregate3Result
+	if err := aggregate3Results.Copy(&decoded, values); err != nil {
+		return nil, fmt.Errorf("invalid aggregate3 result: %w", err)
+	}
+	if len(decoded) != len(calls) {
+		return nil, fmt.Errorf("aggregate3 returned %d results for %d calls", len(decoded), len(calls))
+	}
+	results := make([]MulticallResult, len(decoded))
+	for i, result := range decoded {
+		results[i] = MulticallResult{Success: result.Success, ReturnData: result.ReturnData}
+		if !result.Success {
+			results[i].Revert, _ = m.config.Signatures.DecodeRevert(result.ReturnData)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,142 @@
+package web3_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/moonfdd/web3-go/web3"
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+// reverterCode reverts with its calldata.
+var reverterCode = common.FromHex("3660006000373660" + "00fd")
+
+var (
+	identity = common.BytesToAddress([]byte{4})
+	reverter = common.HexToAddress("0x00000000000000000000000000000000000000c1")
+)
+
+// callCounter counts the eth_call requests sent through it and keeps the
+// state override of the last one.
+type callCounter struct {
+	web3.Client
+	calls     int
+	overrides *web3.StateOverride
+}
+
+func (c *callCounter) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if method == "eth_call" {
+		c.calls++
+		c.overrides = args[2].(*web3.StateOverride)
+	}
+	return c.Client.CallContext(ctx, result, method, args...)
+}
+
+func errorString(message string) []byte {
+	return calldata("Error(string)", []byte{0x20}, []byte{byte(len(message))}, common.RightPadBytes([]byte(message), 32))
+}
+
+func TestMulticallDevNode(t *testing.T) {
+	node := web3test.New(t, web3test.WithAlloc(types.GenesisAlloc{reverter: {Code: reverterCode}}))
+	counter := &callCounter{Client: node.Client}
+	w := web3.NewWeb3(counter)
+	ctx := context.Background()
+
+	multicall := web3.NewMulticall(w, web3.MulticallConfig{})
+	results, err := multicall.Call(ctx, []web3.MulticallCall{
+		{Target: identity, CallData: []byte("hello")},
+		{Target: reverter, CallData: errorString("nope"), AllowFailure: true},
+		{Target: common.HexToAddress("0xdead")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || !results[0].Success || string(results[0].ReturnData) != "hello" {
+		t.Fatalf("results = %+v", results)
+	}
+	if results[1].Success || results[1].Revert == nil || results[1].Revert.String() != `Error(message: "nope")` {
+		t.Errorf("failed call = %+v", results[1])
+	}
+	if !results[2].Success || len(results[2].ReturnData) != 0 {
+		t.Errorf("call to an account = %+v", results[2])
+	}
+
+	// a failure without allowFailure fails the batch
+	_, err = multicall.Call(ctx, []web3.MulticallCall{{Target: reverter, CallData: errorString("nope")}})
+	if !errors.Is(err, web3.ErrExecutionReverted) || !strings.HasSuffix(err.Error(), `Error(message: "Multicall3: call failed")`) {
+		t.Errorf("Call with a failure = %v", err)
+	}
+
+	// every call of 100 bytes takes 288 bytes of the batch
+	split := web3.NewMulticall(w, web3.MulticallConfig{MaxCalldata: 600})
+	var calls []web3.MulticallCall
+	for i := 0; i < 5; i++ {
+		calls = append(calls, web3.MulticallCall{Target: identity, CallData: bytes.Repeat([]byte{byte(i)}, 100)})
+	}
+	counter.calls = 0
+	results, err = split.Call(ctx, calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, result := range results {
+		if !bytes.Equal(result.ReturnData, calls[i].CallData) {
+			t.Errorf("result %d = %x", i, result.ReturnData)
+		}
+	}
+	if counter.calls != 3 {
+		t.Errorf("%d aggregate3 calls, want 3", counter.calls)
+	}
+	counter.calls = 0
+	if _, err := web3.NewMulticall(w, web3.MulticallConfig{MaxGas: 250_000}).Call(ctx, calls); err != nil || counter.calls != 3 {
+		t.Errorf("Call within a gas budget = %v in %d aggregate3 calls, want 3", err, counter.calls)
+	}
+}
+
+// TestMulticallDeployed runs the code of the override deployed at the
+// Multicall3 address, where no override is sent.
+func TestMulticallDeployed(t *testing.T) {
+	dev := web3test.New(t)
+	counter := &callCounter{Client: dev.Client}
+	ctx := context.Background()
+	if _, err := web3.NewMulticall(web3.NewWeb3(counter), web3.MulticallConfig{}).Call(ctx, []web3.MulticallCall{{Target: identity}}); err != nil {
+		t.Fatal(err)
+	}
+	if counter.overrides == nil {
+		t.Fatal("no state override sent to a node without Multicall3")
+	}
+	code := *(*counter.overrides)[web3.Multicall3Address].Code
+
+	node := web3test.New(t, web3test.WithAlloc(types.GenesisAlloc{
+		web3.Multicall3Address: {Code: code},
+		reverter:               {Code: reverterCode},
+	}))
+	counter = &callCounter{Client: node.Client}
+	multicall := web3.NewMulticall(web3.NewWeb3(counter), web3.MulticallConfig{})
+	results, err := multicall.Call(ctx, []web3.MulticallCall{
+		{Target: identity, CallData: crypto.Keccak256([]byte("a"))},
+		{Target: reverter, CallData: calldata("Panic(uint256)", []byte{0x11}), AllowFailure: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counter.overrides != nil {
+		t.Errorf("state override %v sent to a node with Multicall3", *counter.overrides)
+	}
+	if !results[0].Success || !bytes.Equal(results[0].ReturnData, crypto.Keccak256([]byte("a"))) {
+		t.Errorf("result 0 = %+v", results[0])
+	}
+	if results[1].Success || results[1].Revert == nil || results[1].Revert.Name != "Panic" {
+		t.Errorf("result 1 = %+v", results[1])
+	}
+	_, err = multicall.Call(ctx, []web3.MulticallCall{{Target: reverter, CallData: errorString("nope")}})
+	if !errors.Is(err, web3.ErrExecutionReverted) || !strings.HasSuffix(err.Error(), `Error(message: "Multicall3: call failed")`) {
+		t.Errorf("Call with a failure = %v", err)
+	}
+}
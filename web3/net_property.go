@@ -4,19 +4,25 @@ import (
 	"context"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/rpc"
 )
 
 type Net struct {
-	c *rpc.Client
+	c    Client
+	gate *capabilityGate
 }
 
-func NewNet(c *rpc.Client) *Net {
+func NewNet(c Client) *Net {
 	e := &Net{}
-	e.c = c
+	e.c = withCallErrors(c)
 	return e
 }
 
+// Available reports whether the node serves the net namespace. It is true
+// until Web3.Capabilities has probed the node.
+func (n *Net) Available() bool {
+	return n.gate.available("net")
+}
+
 // Returns the external api version. This method does not require user acceptance. Available methods are
 // available via enumeration anyway, and this info does not contain user-specific data
 // from SignerAPI
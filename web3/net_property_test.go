@@ -0,0 +1,23 @@
+package web3_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+func TestNetDevNode(t *testing.T) {
+	w := web3test.NewWeb3(t)
+	ctx := context.Background()
+
+	if version, err := w.Net.Version(ctx); err != nil || version != "1337" {
+		t.Errorf("Version = %q %v, want 1337", version, err)
+	}
+	if listening, err := w.Net.Listening(ctx); err != nil || !listening {
+		t.Errorf("Listening = %v %v, want true", listening, err)
+	}
+	if count, err := w.Net.PeerCount(ctx); err != nil || count != 0 {
+		t.Errorf("PeerCount = %d %v, want 0", count, err)
+	}
+}
@@ -0,0 +1,237 @@
+package web3
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// OtsOperationType is the kind of an internal operation.
+type OtsOperationType int
+
+const (
+	OtsTransfer OtsOperationType = iota
+	OtsSelfDestruct
+	OtsCreate
+	OtsCreate2
+)
+
+// OtsInternalOperation is an ETH transfer or contract creation made by a
+// contract during a transaction.
+type OtsInternalOperation struct {
+	Type  OtsOperationType `json:"type"`
+	From  common.Address   `json:"from"`
+	To    common.Address   `json:"to"`
+	Value *hexutil.Big     `json:"value"`
+}
+
+// OtsTrace is a call frame of a transaction, listed in the order the frames
+// were entered. Type is the opcode, such as "CALL" or "CREATE2", and Depth is
+// 0 for the top level call. Value is nil for frames that cannot carry one.
+type OtsTrace struct {
+	Type   string         `json:"type"`
+	Depth  int            `json:"depth"`
+	From   common.Address `json:"from"`
+	To     common.Address `json:"to"`
+	Value  *hexutil.Big   `json:"value"`
+	Input  hexutil.Bytes  `json:"input"`
+	Output hexutil.Bytes  `json:"output"`
+}
+
+// OtsContractCreator is the transaction that created a contract and the
+// address that sent it.
+type OtsContractCreator struct {
+	Hash    common.Hash    `json:"hash"`
+	Creator common.Address `json:"creator"`
+}
+
+// OtsBlock is the header of a block as ots_getBlockDetails returns it, with
+// the number of transactions instead of the transactions themselves. The
+// logs bloom is left out by the node.
+type OtsBlock struct {
+	Number           *hexutil.Big   `json:"number"`
+	Hash             common.Hash    `json:"hash"`
+	ParentHash       common.Hash    `json:"parentHash"`
+	Miner            common.Address `json:"miner"`
+	StateRoot        common.Hash    `json:"stateRoot"`
+	TransactionsRoot common.Hash    `json:"transactionsRoot"`
+	ReceiptsRoot     common.Hash    `json:"receiptsRoot"`
+	Difficulty       *hexutil.Big   `json:"difficulty"`
+	ExtraData        hexutil.Bytes  `json:"extraData"`
+	GasLimit         hexutil.Uint64 `json:"gasLimit"`
+	GasUsed          hexutil.Uint64 `json:"gasUsed"`
+	BaseFeePerGas    *hexutil.Big   `json:"baseFeePerGas"`
+	Timestamp        hexutil.Uint64 `json:"timestamp"`
+	Size             hexutil.Uint64 `json:"size"`
+	Uncles           []common.Hash  `json:"uncles"`
+	TransactionCount uint64         `json:"transactionCount"`
+}
+
+// OtsIssuance is the ETH minted by a block.
+type OtsIssuance struct {
+	BlockReward *hexutil.Big `json:"blockReward"`
+	UncleReward *hexutil.Big `json:"uncleReward"`
+	Issuance    *hexutil.Big `json:"issuance"`
+}
+
+// OtsBlockDetails is the result of ots_getBlockDetails. TotalFees is the sum
+// of the fees paid by the transactions of the block, burnt ones included.
+type OtsBlockDetails struct {
+	Block     *OtsBlock    `json:"block"`
+	Issuance  *OtsIssuance `json:"issuance"`
+	TotalFees *hexutil.Big `json:"totalFees"`
+}
+
+// OtsReceipt is a receipt returned by the ots_search* methods, which add the
+// timestamp of the block.
+type OtsReceipt struct {
+	Type              hexutil.Uint64  `json:"type"`
+	Status            hexutil.Uint64  `json:"status"`
+	TransactionHash   common.Hash     `json:"transactionHash"`
+	TransactionIndex  hexutil.Uint64  `json:"transactionIndex"`
+	BlockHash         common.Hash     `json:"blockHash"`
+	BlockNumber       *hexutil.Big    `json:"blockNumber"`
+	From              common.Address  `json:"from"`
+	To                *common.Address `json:"to"`
+	ContractAddress   *common.Address `json:"contractAddress"`
+	CumulativeGasUsed hexutil.Uint64  `json:"cumulativeGasUsed"`
+	GasUsed           hexutil.Uint64  `json:"gasUsed"`
+	EffectiveGasPrice *hexutil.Big    `json:"effectiveGasPrice"`
+	Logs              []*types.Log    `json:"logs"`
+	Timestamp         uint64          `json:"timestamp"`
+}
+
+func (r *OtsReceipt) UnmarshalJSON(input []byte) error {
+	type receipt OtsReceipt
+	var dec struct {
+		*receipt
+		Timestamp quantity `json:"timestamp"`
+	}
+	dec.receipt = (*receipt)(r)
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	r.Timestamp = uint64(dec.Timestamp)
+	return nil
+}
+
+// OtsTransactionsPage is a page of the transactions of an address, newest
+// first, with their receipts in the same order. FirstPage is set on the page
+// holding the newest transactions and LastPage on the one holding the oldest.
+type OtsTransactionsPage struct {
+	Txs       []*RPCTransaction `json:"txs"`
+	Receipts  []*OtsReceipt     `json:"receipts"`
+	FirstPage bool              `json:"firstPage"`
+	LastPage  bool              `json:"lastPage"`
+}
+
+// GetApiLevel returns the version of the Otterscan API served by the node.
+// from OtterscanAPI
+// from erigon
+// property
+func (o *Ots) GetApiLevel(ctx context.Context) (uint64, error) {
+	var result uint64
+	err := o.c.CallContext(ctx, &result, "ots_getApiLevel")
+	return result, err
+}
+
+// HasCode reports whether there is code at the address at the given block.
+// from OtterscanAPI
+// from erigon
+// method
+func (o *Ots) HasCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (bool, error) {
+	var result bool
+	err := o.c.CallContext(ctx, &result, "ots_hasCode", address, blockNrOrHash)
+	return result, err
+}
+
+// GetInternalOperations returns the ETH transfers, self destructs and contract
+// creations made by contracts during a transaction.
+// from OtterscanAPI
+// from erigon
+// method
+func (o *Ots) GetInternalOperations(ctx context.Context, hash common.Hash) ([]*OtsInternalOperation, error) {
+	var result []*OtsInternalOperation
+	err := o.c.CallContext(ctx, &result, "ots_getInternalOperations", hash)
+	return result, err
+}
+
+// TraceTransaction returns the call frames of a transaction.
+// from OtterscanAPI
+// from erigon
+// method
+func (o *Ots) TraceTransaction(ctx context.Context, hash common.Hash) ([]*OtsTrace, error) {
+	var result []*OtsTrace
+	err := o.c.CallContext(ctx, &result, "ots_traceTransaction", hash)
+	return result, err
+}
+
+// GetBlockDetails returns the header, issuance and fees of a block.
+// from OtterscanAPI
+// from erigon
+// method
+func (o *Ots) GetBlockDetails(ctx context.Context, number rpc.BlockNumber) (*OtsBlockDetails, error) {
+	var result *OtsBlockDetails
+	err := o.c.CallContext(ctx, &result, "ots_getBlockDetails", number)
+	return result, err
+}
+
+// GetBlockDetailsByHash is GetBlockDetails for a block given by hash.
+// from OtterscanAPI
+// from erigon
+// method
+func (o *Ots) GetBlockDetailsByHash(ctx context.Context, hash common.Hash) (*OtsBlockDetails, error) {
+	var result *OtsBlockDetails
+	err := o.c.CallContext(ctx, &result, "ots_getBlockDetailsByHash", hash)
+	return result, err
+}
+
+// SearchTransactionsBefore returns the transactions sent or received by an
+// address in blocks before blockNumber, or in the whole chain if it is 0.
+// Pages hold whole blocks, so they may have more than pageSize transactions.
+// from OtterscanAPI
+// from erigon
+// method
+func (o *Ots) SearchTransactionsBefore(ctx context.Context, address common.Address, blockNumber uint64, pageSize int) (*OtsTransactionsPage, error) {
+	var result *OtsTransactionsPage
+	err := o.c.CallContext(ctx, &result, "ots_searchTransactionsBefore", address, blockNumber, pageSize)
+	return result, err
+}
+
+// SearchTransactionsAfter returns the transactions sent or received by an
+// address in blocks after blockNumber, the oldest first, though each page is
+// still ordered newest first.
+// from OtterscanAPI
+// from erigon
+// method
+func (o *Ots) SearchTransactionsAfter(ctx context.Context, address common.Address, blockNumber uint64, pageSize int) (*OtsTransactionsPage, error) {
+	var result *OtsTransactionsPage
+	err := o.c.CallContext(ctx, &result, "ots_searchTransactionsAfter", address, blockNumber, pageSize)
+	return result, err
+}
+
+// GetTransactionBySenderAndNonce returns the hash of the transaction sent by
+// an address with the given nonce, or nil if there is none.
+// from OtterscanAPI
+// from erigon
+// method
+func (o *Ots) GetTransactionBySenderAndNonce(ctx context.Context, sender common.Address, nonce uint64) (*common.Hash, error) {
+	var result *common.Hash
+	err := o.c.CallContext(ctx, &result, "ots_getTransactionBySenderAndNonce", sender, nonce)
+	return result, err
+}
+
+// GetContractCreator returns the transaction that created a contract, or nil
+// if the address is not a contract.
+// from OtterscanAPI
+// from erigon
+// method
+func (o *Ots) GetContractCreator(ctx context.Context, address common.Address) (*OtsContractCreator, error) {
+	var result *OtsContractCreator
+	err := o.c.CallContext(ctx, &result, "ots_getContractCreator", address)
+	return result, err
+}
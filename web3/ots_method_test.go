@@ -0,0 +1,227 @@
+package web3_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+)
+
+// otsService is a stub Erigon serving the Otterscan API for an address with
+// transactions in the blocks listed in blocks, in ascending order.
+type otsService struct {
+	blocks []uint64
+}
+
+func (s *otsService) GetApiLevel() int { return 8 }
+
+func (s *otsService) HasCode(addr common.Address, blockNrOrHash rpc.BlockNumberOrHash) bool {
+	return addr == common.HexToAddress("0xc0de")
+}
+
+func (s *otsService) GetContractCreator(addr common.Address) json.RawMessage {
+	if addr != common.HexToAddress("0xc0de") {
+		return json.RawMessage(`null`)
+	}
+	return json.RawMessage(`{"hash":"0x00000000000000000000000000000000000000000000000000000000000000aa","creator":"0x00000000000000000000000000000000000000ee"}`)
+}
+
+func (s *otsService) GetTransactionBySenderAndNonce(addr common.Address, nonce uint64) json.RawMessage {
+	if nonce > 0 {
+		return json.RawMessage(`null`)
+	}
+	return json.RawMessage(`"0x00000000000000000000000000000000000000000000000000000000000000aa"`)
+}
+
+func (s *otsService) GetInternalOperations(hash common.Hash) json.RawMessage {
+	return json.RawMessage(`[{"type":0,"from":"0x000000000000000000000000000000000000c0de","to":"0x00000000000000000000000000000000000000ee","value":"0x64"},{"type":3,"from":"0x000000000000000000000000000000000000c0de","to":"0x00000000000000000000000000000000000000ff","value":"0x0"}]`)
+}
+
+func (s *otsService) TraceTransaction(hash common.Hash) json.RawMessage {
+	return json.RawMessage(`[{"type":"CALL","depth":0,"from":"0x00000000000000000000000000000000000000ee","to":"0x000000000000000000000000000000000000c0de","value":"0x64","input":"0x12345678","output":"0x"},{"type":"STATICCALL","depth":1,"from":"0x000000000000000000000000000000000000c0de","to":"0x00000000000000000000000000000000000000ff","value":null,"input":"0x","output":"0x01"}]`)
+}
+
+func (s *otsService) GetBlockDetails(number rpc.BlockNumber) json.RawMessage {
+	return json.RawMessage(`{"block":{"number":"0x10","hash":"0x00000000000000000000000000000000000000000000000000000000000000bb","miner":"0x00000000000000000000000000000000000000ee","gasUsed":"0x5208","baseFeePerGas":"0x7","timestamp":"0x64","logsBloom":null,"transactionCount":3},"issuance":{"blockReward":"0x0","uncleReward":"0x0","issuance":"0x0"},"totalFees":"0x1f4"}`)
+}
+
+func (s *otsService) tx(i int) map[string]interface{} {
+	return map[string]interface{}{
+		"hash":        common.BigToHash(big.NewInt(int64(i))),
+		"blockNumber": hexutil.Uint64(s.blocks[i]),
+		"from":        common.HexToAddress("0xee"),
+		"nonce":       hexutil.Uint64(i),
+	}
+}
+
+// page returns the transactions of whole blocks, taken from indexes in the
+// given order until there are at least size of them, newest first.
+func (s *otsService) page(indexes []int, size int) ([]map[string]interface{}, []map[string]interface{}, int) {
+	var taken []int
+	for n, i := range indexes {
+		if len(taken) >= size && s.blocks[i] != s.blocks[indexes[n-1]] {
+			break
+		}
+		taken = append(taken, i)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(taken)))
+	txs := make([]map[string]interface{}, 0, len(taken))
+	receipts := make([]map[string]interface{}, 0, len(taken))
+	for _, i := range taken {
+		txs = append(txs, s.tx(i))
+		receipts = append(receipts, map[string]interface{}{
+			"transactionHash": common.BigToHash(big.NewInt(int64(i))),
+			"status":          "0x1",
+			"timestamp":       1_700_000_000 + s.blocks[i],
+		})
+	}
+	return txs, receipts, len(taken)
+}
+
+func (s *otsService) SearchTransactionsBefore(addr common.Address, block uint64, size int) map[string]interface{} {
+	var indexes []int
+	for i := len(s.blocks) - 1; i >= 0; i-- {
+		if block == 0 || s.blocks[i] < block {
+			indexes = append(indexes, i)
+		}
+	}
+	txs, receipts, n := s.page(indexes, size)
+	return map[string]interface{}{
+		"txs": txs, "receipts": receipts,
+		"firstPage": len(indexes) == len(s.blocks),
+		"lastPage":  n == len(indexes),
+	}
+}
+
+func (s *otsService) SearchTransactionsAfter(addr common.Address, block uint64, size int) map[string]interface{} {
+	var indexes []int
+	for i := range s.blocks {
+		if s.blocks[i] > block {
+			indexes = append(indexes, i)
+		}
+	}
+	txs, receipts, n := s.page(indexes, size)
+	return map[string]interface{}{
+		"txs": txs, "receipts": receipts,
+		"firstPage": n == len(indexes),
+		"lastPage":  len(indexes) == len(s.blocks),
+	}
+}
+
+func newStubOtterscan(t *testing.T, blocks ...uint64) *web3.Web3 {
+	t.Helper()
+	server := rpc.NewServer()
+	if err := server.RegisterName("ots", &otsService{blocks}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	client := rpc.DialInProc(server)
+	t.Cleanup(client.Close)
+	return web3.NewWeb3(client)
+}
+
+func TestOtsErigonResponses(t *testing.T) {
+	w := newStubOtterscan(t)
+	ctx := context.Background()
+	contract := common.HexToAddress("0xc0de")
+
+	if level, err := w.Ots.GetApiLevel(ctx); err != nil || level != 8 {
+		t.Errorf("GetApiLevel = %d %v, want 8", level, err)
+	}
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if ok, err := w.Ots.HasCode(ctx, contract, latest); err != nil || !ok {
+		t.Errorf("HasCode = %v %v, want true", ok, err)
+	}
+	creator, err := w.Ots.GetContractCreator(ctx, contract)
+	if err != nil || creator == nil || creator.Creator != common.HexToAddress("0xee") || creator.Hash != common.HexToHash("0xaa") {
+		t.Errorf("GetContractCreator = %+v %v", creator, err)
+	}
+	if creator, err := w.Ots.GetContractCreator(ctx, common.HexToAddress("0xee")); err != nil || creator != nil {
+		t.Errorf("GetContractCreator of an account = %+v %v, want nil", creator, err)
+	}
+	if hash, err := w.Ots.GetTransactionBySenderAndNonce(ctx, common.HexToAddress("0xee"), 0); err != nil || hash == nil || *hash != common.HexToHash("0xaa") {
+		t.Errorf("GetTransactionBySenderAndNonce = %v %v", hash, err)
+	}
+	if hash, err := w.Ots.GetTransactionBySenderAndNonce(ctx, common.HexToAddress("0xee"), 1); err != nil || hash != nil {
+		t.Errorf("GetTransactionBySenderAndNonce of an unused nonce = %v %v, want nil", hash, err)
+	}
+
+	ops, err := w.Ots.GetInternalOperations(ctx, common.HexToHash("0xaa"))
+	if err != nil || len(ops) != 2 {
+		t.Fatalf("GetInternalOperations = %v %v", ops, err)
+	}
+	if ops[0].Type != web3.OtsTransfer || ops[0].Value.ToInt().Int64() != 100 || ops[1].Type != web3.OtsCreate2 {
+		t.Errorf("internal operations = %+v %+v", ops[0], ops[1])
+	}
+	traces, err := w.Ots.TraceTransaction(ctx, common.HexToHash("0xaa"))
+	if err != nil || len(traces) != 2 {
+		t.Fatalf("TraceTransaction = %v %v", traces, err)
+	}
+	if traces[1].Type != "STATICCALL" || traces[1].Depth != 1 || traces[1].Value != nil || traces[1].Output[0] != 1 {
+		t.Errorf("static call trace = %+v", traces[1])
+	}
+
+	details, err := w.Ots.GetBlockDetails(ctx, 16)
+	if err != nil {
+		t.Fatalf("GetBlockDetails: %v", err)
+	}
+	if details.Block.Number.ToInt().Int64() != 16 || details.Block.TransactionCount != 3 || details.TotalFees.ToInt().Int64() != 500 {
+		t.Errorf("block details = %+v, fees %v", details.Block, details.TotalFees)
+	}
+}
+
+func TestOtsSearchTransactions(t *testing.T) {
+	blocks := []uint64{1, 2, 2, 3, 5, 7, 7, 8}
+	w := newStubOtterscan(t, blocks...)
+	ctx := context.Background()
+	addr := common.HexToAddress("0xee")
+
+	page, err := w.Ots.SearchTransactionsBefore(ctx, addr, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !page.FirstPage || page.LastPage || len(page.Txs) != 3 || len(page.Receipts) != 3 {
+		t.Fatalf("first page = %d txs, first %v, last %v", len(page.Txs), page.FirstPage, page.LastPage)
+	}
+	if page.Receipts[0].Timestamp != 1_700_000_008 || page.Receipts[0].Status != 1 {
+		t.Errorf("receipt = %+v", page.Receipts[0])
+	}
+
+	for _, test := range []struct {
+		name   string
+		search *web3.OtsSearch
+		want   []uint64
+	}{
+		{"newest first", w.Ots.SearchTransactions(addr, 2), []uint64{8, 7, 7, 5, 3, 2, 2, 1}},
+		{"oldest first", w.Ots.SearchTransactionsFromOldest(addr, 2), []uint64{2, 2, 1, 5, 3, 7, 7, 8}},
+		{"single page", w.Ots.SearchTransactions(addr, 100), []uint64{8, 7, 7, 5, 3, 2, 2, 1}},
+	} {
+		txs, receipts, err := test.search.All(ctx)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if len(txs) != len(test.want) || len(receipts) != len(test.want) {
+			t.Fatalf("%s: got %d txs and %d receipts, want %d", test.name, len(txs), len(receipts), len(test.want))
+		}
+		for i, tx := range txs {
+			if got := tx.BlockNumber.ToInt().Uint64(); got != test.want[i] {
+				t.Errorf("%s: tx %d in block %d, want %d", test.name, i, got, test.want[i])
+			}
+		}
+		if page, err := test.search.Next(ctx); page != nil || err != nil {
+			t.Errorf("%s: Next after the last page = %v %v", test.name, page, err)
+		}
+	}
+
+	empty := newStubOtterscan(t)
+	if page, err := empty.Ots.SearchTransactions(addr, 2).Next(ctx); page != nil || err != nil {
+		t.Errorf("Next without transactions = %v %v", page, err)
+	}
+}
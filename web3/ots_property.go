@@ -0,0 +1,18 @@
+package web3
+
+type Ots struct {
+	c    Client
+	gate *capabilityGate
+}
+
+func NewOts(c Client) *Ots {
+	e := &Ots{}
+	e.c = withCallErrors(c)
+	return e
+}
+
+// Available reports whether the node serves the ots namespace. It is true
+// until Web3.Capabilities has probed the node.
+func (o *Ots) Available() bool {
+	return o.gate.available("ots")
+}
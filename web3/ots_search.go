@@ -0,0 +1,83 @@
+package web3
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// OtsSearch pages through the transactions of an address. It is not safe for
+// concurrent use.
+type OtsSearch struct {
+	o        *Ots
+	address  common.Address
+	pageSize int
+	after    bool
+	cursor   uint64
+	done     bool
+}
+
+// SearchTransactions returns an OtsSearch going from the newest transactions
+// of an address to the oldest.
+func (o *Ots) SearchTransactions(address common.Address, pageSize int) *OtsSearch {
+	return &OtsSearch{o: o, address: address, pageSize: pageSize}
+}
+
+// SearchTransactionsFromOldest returns an OtsSearch going from the oldest
+// transactions of an address to the newest. The pages come oldest first but
+// the transactions in each page are still ordered newest first.
+func (o *Ots) SearchTransactionsFromOldest(address common.Address, pageSize int) *OtsSearch {
+	return &OtsSearch{o: o, address: address, pageSize: pageSize, after: true}
+}
+
+// Next returns the next page, or nil once every page has been returned.
+func (s *OtsSearch) Next(ctx context.Context) (*OtsTransactionsPage, error) {
+	if s.done {
+		return nil, nil
+	}
+	var page *OtsTransactionsPage
+	var err error
+	if s.after {
+		page, err = s.o.SearchTransactionsAfter(ctx, s.address, s.cursor, s.pageSize)
+	} else {
+		page, err = s.o.SearchTransactionsBefore(ctx, s.address, s.cursor, s.pageSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if page == nil || len(page.Txs) == 0 {
+		s.done = true
+		return nil, nil
+	}
+	// pages hold whole blocks, so the next one starts past the block of the
+	// transaction furthest from where the search started
+	edge := page.Txs[len(page.Txs)-1]
+	s.done = page.LastPage
+	if s.after {
+		edge = page.Txs[0]
+		s.done = page.FirstPage
+	}
+	if edge.BlockNumber == nil {
+		s.done = true
+	} else {
+		s.cursor = edge.BlockNumber.ToInt().Uint64()
+	}
+	return page, nil
+}
+
+// All returns the transactions and receipts of every remaining page.
+func (s *OtsSearch) All(ctx context.Context) ([]*RPCTransaction, []*OtsReceipt, error) {
+	var txs []*RPCTransaction
+	var receipts []*OtsReceipt
+	for {
+		page, err := s.Next(ctx)
+		if err != nil {
+			return txs, receipts, err
+		}
+		if page == nil {
+			return txs, receipts, nil
+		}
+		txs = append(txs, page.Txs...)
+		receipts = append(receipts, page.Receipts...)
+	}
+}
@@ -46,6 +46,8 @@ func (p *Personal) Sign(ctx context.Context, data hexutil.Bytes, addr common.Add
 // Note, the signature must conform to the secp256k1 curve R, S and V values, where
 // the V value must be 27 or 28 for legacy reasons.
 //
+// RecoverMessage does the same locally, without sending the message to the node.
+//
 // https://geth.ethereum.org/docs/interacting-with-geth/rpc/ns-personal#personal-ecrecover
 // from PersonalAccountAPI
 // from web3ext.go
@@ -116,9 +118,10 @@ func (p *Personal) InitializeWallet(ctx context.Context, url string) (string, er
 // from web3.js
 // method
 func (p *Personal) NewAccount(ctx context.Context, password string) (common.AddressEIP55, error) {
-	var result common.AddressEIP55
+	// AddressEIP55 only marshals, decode the checksummed hex as a plain address
+	var result common.Address
 	err := p.c.CallContext(ctx, &result, "personal_newAccount", password)
-	return result, err
+	return common.AddressEIP55(result), err
 }
 
 // UnlockAccount will unlock the account associated with the given address with
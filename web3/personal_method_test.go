@@ -0,0 +1,100 @@
+package web3_test
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/moonfdd/web3-go/web3"
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+func TestPersonalDevNode(t *testing.T) {
+	node := web3test.New(t)
+	w := node.Web3
+	ctx := context.Background()
+
+	accounts, err := w.Personal.ListAccounts(ctx)
+	if err != nil || len(accounts) != 1 || accounts[0] != node.Account {
+		t.Fatalf("ListAccounts = %v %v, want [%v]", accounts, err, node.Account)
+	}
+	wallets, err := w.Personal.ListWallets(ctx)
+	if err != nil || len(wallets) != 1 || wallets[0].Status != "Unlocked" {
+		t.Fatalf("ListWallets = %+v %v", wallets, err)
+	}
+
+	created, err := w.Personal.NewAccount(ctx, "secret")
+	if err != nil {
+		t.Fatalf("NewAccount: %v", err)
+	}
+	key, _ := crypto.GenerateKey()
+	imported, err := w.Personal.ImportRawKey(ctx, hex.EncodeToString(crypto.FromECDSA(key)), "secret")
+	if err != nil || imported != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("ImportRawKey = %v %v", imported, err)
+	}
+	if ok, err := w.Personal.UnlockAccount(ctx, common.Address(created), "wrong", nil); err == nil || ok {
+		t.Errorf("UnlockAccount with a wrong password = %v %v", ok, err)
+	}
+	duration := uint64(60)
+	if ok, err := w.Personal.UnlockAccount(ctx, common.Address(created), "secret", &duration); err != nil || !ok {
+		t.Errorf("UnlockAccount = %v %v", ok, err)
+	}
+	if ok, err := w.Personal.LockAccount(ctx, common.Address(created)); err != nil || !ok {
+		t.Errorf("LockAccount = %v %v", ok, err)
+	}
+
+	message := hexutil.Bytes("hello web3-go")
+	sig, err := w.Personal.Sign(ctx, message, node.Account, web3test.Password)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if signer, err := w.Personal.EcRecover(ctx, message, sig); err != nil || signer != node.Account {
+		t.Errorf("EcRecover = %v %v, want %v", signer, err, node.Account)
+	}
+
+	to := common.Address(created)
+	gas := hexutil.Uint64(21000)
+	nonce := hexutil.Uint64(0)
+	fee := (*hexutil.Big)(big.NewInt(10 * params.GWei))
+	value := (*hexutil.Big)(big.NewInt(params.Ether))
+	args := web3.TransactionArgs{From: &node.Account, To: &to, Gas: &gas, MaxFeePerGas: fee, MaxPriorityFeePerGas: fee, Value: value, Nonce: &nonce}
+	signed, err := w.Personal.SignTransaction(ctx, args, web3test.Password)
+	if err != nil {
+		t.Fatalf("SignTransaction: %v", err)
+	}
+	if signed.Tx == nil || *signed.Tx.To() != to || len(signed.Raw) == 0 {
+		t.Errorf("SignTransaction = %+v", signed)
+	}
+	hash, err := w.Personal.SendTransaction(ctx, args, web3test.Password)
+	if err != nil || hash != signed.Tx.Hash() {
+		t.Fatalf("SendTransaction = %v %v, want %v", hash, err, signed.Tx.Hash())
+	}
+	node.Commit(t)
+	state, err := node.Eth.BlockChain().State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance := state.GetBalance(to).ToBig(); balance.Cmp(value.ToInt()) != 0 {
+		t.Errorf("balance of %v = %v, want %v", to, balance, value)
+	}
+
+	// no smartcard or hardware wallet is attached to the node
+	if err := w.Personal.OpenWallet(ctx, "keycard://0000", nil); err == nil {
+		t.Error("OpenWallet succeeded without a hardware wallet")
+	}
+	if _, err := w.Personal.DeriveAccount(ctx, "keycard://0000", "m/44'/60'/0'/0/0", nil); err == nil {
+		t.Error("DeriveAccount succeeded without a hardware wallet")
+	}
+	if err := w.Personal.Unpair(ctx, "keycard://0000", "123456"); err == nil {
+		t.Error("Unpair succeeded without a smartcard")
+	}
+	if _, err := w.Personal.InitializeWallet(ctx, "keycard://0000"); err == nil {
+		t.Error("InitializeWallet succeeded without a smartcard")
+	}
+}
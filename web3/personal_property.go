@@ -5,19 +5,25 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/rpc"
 )
 
 type Personal struct {
-	c *rpc.Client
+	c    Client
+	gate *capabilityGate
 }
 
-func NewPersonal(c *rpc.Client) *Personal {
+func NewPersonal(c Client) *Personal {
 	e := &Personal{}
-	e.c = c
+	e.c = withCallErrors(c)
 	return e
 }
 
+// Available reports whether the node serves the personal namespace. It is true
+// until Web3.Capabilities has probed the node.
+func (p *Personal) Available() bool {
+	return p.gate.available("personal")
+}
+
 // rawWallet is a JSON representation of an accounts.Wallet interface, with its
 // data contents extracted into plain fields.
 type RawWallet struct {
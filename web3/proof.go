@@ -0,0 +1,223 @@
+package web3
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
+)
+
+// ErrInvalidProof is returned when a Merkle proof does not prove the values
+// it comes with.
+var ErrInvalidProof = errors.New("invalid proof")
+
+// verifyProof walks the Merkle Patricia trie nodes of proof from root to the
+// value of key, nil if the proof shows that the trie has no such key.
+func verifyProof(root common.Hash, key []byte, proof []string) ([]byte, error) {
+	nodes := memorydb.New()
+	for i, encoded := range proof {
+		node, err := hexutil.Decode(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: node %d: %v", ErrInvalidProof, i, err)
+		}
+		nodes.Put(crypto.Keccak256(node), node)
+	}
+	value, err := trie.VerifyProof(root, key, nodes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+	return value, nil
+}
+
+// VerifyAccountProof checks the account proof of an Eth.GetProof result
+// against the state root of the block it was requested at, confirming its
+// nonce, balance, code hash and storage hash. The storage proofs are checked
+// by VerifyStorageProof.
+func VerifyAccountProof(stateRoot common.Hash, result *AccountResult) error {
+	value, err := verifyProof(stateRoot, crypto.Keccak256(result.Address[:]), result.AccountProof)
+	if err != nil {
+		return fmt.Errorf("account %v: %w", result.Address, err)
+	}
+	// a missing account is proven by a proof ending elsewhere, and is empty
+	account := types.StateAccount{Balance: new(uint256.Int), Root: types.EmptyRootHash, CodeHash: types.EmptyCodeHash.Bytes()}
+	if value != nil {
+		if err := rlp.DecodeBytes(value, &account); err != nil {
+			return fmt.Errorf("%w: account %v: %v", ErrInvalidProof, result.Address, err)
+		}
+	}
+	var balance *big.Int
+	if result.Balance != nil {
+		balance = result.Balance.ToInt()
+	}
+	codeHash, storageHash := result.CodeHash, result.StorageHash
+	if value == nil {
+		// nodes report a zero hash rather than the empty one for missing accounts
+		if codeHash == (common.Hash{}) {
+			codeHash = types.EmptyCodeHash
+		}
+		if storageHash == (common.Hash{}) {
+			storageHash = types.EmptyRootHash
+		}
+	}
+	switch {
+	case uint64(result.Nonce) != account.Nonce:
+		return fmt.Errorf("%w: account %v: nonce %d, proven %d", ErrInvalidProof, result.Address, result.Nonce, account.Nonce)
+	case balance == nil || balance.Cmp(account.Balance.ToBig()) != 0:
+		return fmt.Errorf("%w: account %v: balance %v, proven %v", ErrInvalidProof, result.Address, balance, account.Balance)
+	case !bytes.Equal(codeHash[:], account.CodeHash):
+		return fmt.Errorf("%w: account %v: code hash %v, proven %x", ErrInvalidProof, result.Address, result.CodeHash, account.CodeHash)
+	case storageHash != account.Root:
+		return fmt.Errorf("%w: account %v: storage hash %v, proven %v", ErrInvalidProof, result.Address, result.StorageHash, account.Root)
+	}
+	return nil
+}
+
+// VerifyStorageProof checks a storage proof of an Eth.GetProof result against
+// the storage hash of its account, confirming its value. An account without
+// storage has the empty root, or the zero hash if it is missing, and an empty
+// proof that proves every value zero.
+func VerifyStorageProof(storageHash common.Hash, proof StorageResult) error {
+	key, err := decodeStorageKey(proof.Key)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+	var value []byte
+	if storageHash != types.EmptyRootHash && storageHash != (common.Hash{}) || len(proof.Proof) > 0 {
+		value, err = verifyProof(storageHash, crypto.Keccak256(key[:]), proof.Proof)
+		if err != nil {
+			return fmt.Errorf("storage key %s: %w", proof.Key, err)
+		}
+	}
+	// slots are stored as the RLP of their trimmed value, zero ones not at all
+	proven := new(big.Int)
+	if value != nil {
+		var content []byte
+		if err := rlp.DecodeBytes(value, &content); err != nil {
+			return fmt.Errorf("%w: storage key %s: %v", ErrInvalidProof, proof.Key, err)
+		}
+		proven.SetBytes(content)
+	}
+	if proof.Value == nil || proof.Value.ToInt().Cmp(proven) != 0 {
+		return fmt.Errorf("%w: storage key %s: value %v, proven %v", ErrInvalidProof, proof.Key, proof.Value, proven)
+	}
+	return nil
+}
+
+// decodeStorageKey parses a storage key the way eth_getProof does: hex of up
+// to 32 bytes, left padded.
+func decodeStorageKey(key string) (common.Hash, error) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(key, "0x"), "0X")
+	if len(digits)%2 == 1 {
+		digits = "0" + digits
+	}
+	if len(digits) > 2*common.HashLength {
+		return common.Hash{}, fmt.Errorf("storage key %q longer than 32 bytes", key)
+	}
+	b, err := hex.DecodeString(digits)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("invalid storage key %q: %v", key, err)
+	}
+	return common.BytesToHash(b), nil
+}
+
+// GetVerifiedProof is GetProof for nodes that are not trusted. It fetches
+// the header of the block, checks that it hashes to its hash, and returns the
+// account and storage values only if their proofs verify against its state
+// root. Give the block by hash, from a trusted source, to trust the result as
+// much as that hash.
+func (e *Eth) GetVerifiedProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*AccountResult, error) {
+	header, err := e.header(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// the proof is requested at the hash of the header, not at a number
+	// whose block may change in between
+	result, err := e.GetProof(ctx, address, storageKeys, rpc.BlockNumberOrHashWithHash(header.Hash(), false))
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("%w: no proof of %v", ErrInvalidProof, address)
+	}
+	if result.Address != address {
+		return nil, fmt.Errorf("%w: proof of %v, want %v", ErrInvalidProof, result.Address, address)
+	}
+	if err := VerifyAccountProof(header.Root, result); err != nil {
+		return nil, err
+	}
+	if len(result.StorageProof) != len(storageKeys) {
+		return nil, fmt.Errorf("%w: %d storage proofs, want %d", ErrInvalidProof, len(result.StorageProof), len(storageKeys))
+	}
+	for i, proof := range result.StorageProof {
+		want, err := decodeStorageKey(storageKeys[i])
+		if err != nil {
+			return nil, err
+		}
+		if key, err := decodeStorageKey(proof.Key); err != nil || key != want {
+			return nil, fmt.Errorf("%w: storage proof %d of key %s, want %s", ErrInvalidProof, i, proof.Key, storageKeys[i])
+		}
+		if err := VerifyStorageProof(result.StorageHash, proof); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// header fetches the header of a block and checks that it hashes to the hash
+// it comes with and, when asked by hash, to that hash.
+func (e *Eth) header(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	var fields map[string]interface{}
+	var err error
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		fields, err = e.GetHeaderByHash(ctx, hash)
+	} else {
+		number, _ := blockNrOrHash.Number()
+		fields, err = e.GetHeaderByNumber(ctx, number)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		return nil, errors.New("header not found")
+	}
+	header, err := decodeHeader(fields)
+	if err != nil {
+		return nil, err
+	}
+	if hash, ok := blockNrOrHash.Hash(); ok && header.Hash() != hash {
+		return nil, fmt.Errorf("%w: header hashes to %v, want %v", ErrInvalidProof, header.Hash(), hash)
+	}
+	return header, nil
+}
+
+// decodeHeader decodes a header or block as returned by the node and checks
+// that it hashes to the hash it comes with.
+func decodeHeader(fields map[string]interface{}) (*types.Header, error) {
+	encoded, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	header := new(types.Header)
+	if err := json.Unmarshal(encoded, header); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if reported, ok := fields["hash"].(string); ok && common.HexToHash(reported) != header.Hash() {
+		return nil, fmt.Errorf("%w: header hashes to %v, reported %v", ErrInvalidProof, header.Hash(), reported)
+	}
+	return header, nil
+}
@@ -0,0 +1,106 @@
+package web3_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+func TestVerifyProof(t *testing.T) {
+	node := web3test.New(t)
+	w := node.Web3
+	ctx := context.Background()
+	value := common.HexToHash("0x2a")
+	contract, _ := deployStore(t, node, value)
+
+	header, err := w.Eth.GetHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := common.HexToHash(header["hash"].(string))
+	root := common.HexToHash(header["stateRoot"].(string))
+	at := rpc.BlockNumberOrHashWithHash(hash, false)
+
+	for _, block := range []rpc.BlockNumberOrHash{at, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)} {
+		result, err := w.Eth.GetVerifiedProof(ctx, contract, []string{"0x0", "0x1"}, block)
+		if err != nil {
+			t.Fatalf("GetVerifiedProof at %v: %v", block, err)
+		}
+		if result.StorageProof[0].Value.ToInt().Cmp(value.Big()) != 0 || result.StorageProof[1].Value.ToInt().Sign() != 0 {
+			t.Errorf("GetVerifiedProof storage = %+v", result.StorageProof)
+		}
+	}
+
+	// accounts missing from the state are proven empty, and the storage of
+	// accounts without any zero
+	missing := common.HexToAddress("0x00000000000000000000000000000000000000ee")
+	if result, err := w.Eth.GetVerifiedProof(ctx, missing, nil, at); err != nil || result.Balance.ToInt().Sign() != 0 {
+		t.Errorf("GetVerifiedProof of a missing account = %+v %v", result, err)
+	}
+	for _, account := range []common.Address{missing, node.Account} {
+		result, err := w.Eth.GetVerifiedProof(ctx, account, []string{"0x0", "0x1"}, at)
+		if err != nil {
+			t.Fatalf("GetVerifiedProof of the storage of %v: %v", account, err)
+		}
+		for _, proof := range result.StorageProof {
+			if proof.Value.ToInt().Sign() != 0 || len(proof.Proof) != 0 {
+				t.Errorf("storage of %v = %+v", account, proof)
+			}
+			proof.Value = (*hexutil.Big)(big.NewInt(1))
+			if err := web3.VerifyStorageProof(result.StorageHash, proof); !errors.Is(err, web3.ErrInvalidProof) {
+				t.Errorf("VerifyStorageProof of a value in the storage of %v = %v, want %v", account, err, web3.ErrInvalidProof)
+			}
+		}
+	}
+	if _, err := w.Eth.GetVerifiedProof(ctx, contract, nil, rpc.BlockNumberOrHashWithHash(common.HexToHash("0x01"), false)); err == nil {
+		t.Error("GetVerifiedProof at an unknown block succeeded")
+	}
+
+	for name, tamper := range map[string]func(*web3.AccountResult){
+		"nonce":        func(r *web3.AccountResult) { r.Nonce++ },
+		"balance":      func(r *web3.AccountResult) { r.Balance = (*hexutil.Big)(big.NewInt(1)) },
+		"code hash":    func(r *web3.AccountResult) { r.CodeHash[0] ^= 1 },
+		"storage hash": func(r *web3.AccountResult) { r.StorageHash[0] ^= 1 },
+		"address":      func(r *web3.AccountResult) { r.Address = missing },
+		"proof":        func(r *web3.AccountResult) { r.AccountProof = r.AccountProof[1:] },
+	} {
+		result, err := w.Eth.GetProof(ctx, contract, nil, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := web3.VerifyAccountProof(root, result); err != nil {
+			t.Fatalf("VerifyAccountProof: %v", err)
+		}
+		tamper(result)
+		if err := web3.VerifyAccountProof(root, result); !errors.Is(err, web3.ErrInvalidProof) {
+			t.Errorf("VerifyAccountProof with a tampered %s = %v, want %v", name, err, web3.ErrInvalidProof)
+		}
+	}
+
+	for name, tamper := range map[string]func(*web3.StorageResult){
+		"value": func(p *web3.StorageResult) { p.Value = (*hexutil.Big)(big.NewInt(7)) },
+		"key":   func(p *web3.StorageResult) { p.Key = "0x1" },
+		"proof": func(p *web3.StorageResult) { p.Proof = p.Proof[:len(p.Proof)-1] },
+	} {
+		result, err := w.Eth.GetProof(ctx, contract, []string{"0x00"}, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof := result.StorageProof[0]
+		if err := web3.VerifyStorageProof(result.StorageHash, proof); err != nil {
+			t.Fatalf("VerifyStorageProof: %v", err)
+		}
+		tamper(&proof)
+		if err := web3.VerifyStorageProof(result.StorageHash, proof); !errors.Is(err, web3.ErrInvalidProof) {
+			t.Errorf("VerifyStorageProof with a tampered %s = %v, want %v", name, err, web3.ErrInvalidProof)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package web3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// QuorumResponse is the answer of a single endpoint to a quorum request.
+type QuorumResponse struct {
+	Endpoint string
+	Result   json.RawMessage
+	Err      error
+}
+
+// DivergenceError is returned by a QuorumClient when no value was returned by
+// a majority of the queried endpoints.
+type DivergenceError struct {
+	Method    string
+	Quorum    int
+	Responses []QuorumResponse
+}
+
+func (e *DivergenceError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: no majority among %d endpoints:", e.Method, e.Quorum)
+	for _, r := range e.Responses {
+		if r.Err != nil {
+			fmt.Fprintf(&b, " %s=error(%v)", r.Endpoint, r.Err)
+		} else {
+			fmt.Fprintf(&b, " %s=%s", r.Endpoint, r.Result)
+		}
+	}
+	return b.String()
+}
+
+// quorumBypass lists the methods that change node state or depend on state
+// kept by a single node. They are sent to one endpoint only.
+var quorumBypass = map[string]bool{
+	"eth_sendTransaction":             true,
+	"eth_sendRawTransaction":          true,
+	"eth_submitTransaction":           true,
+	"eth_resend":                      true,
+	"eth_sign":                        true,
+	"eth_signTransaction":             true,
+	"eth_fillTransaction":             true,
+	"eth_newFilter":                   true,
+	"eth_newBlockFilter":              true,
+	"eth_newPendingTransactionFilter": true,
+	"eth_getFilterChanges":            true,
+	"eth_getFilterLogs":               true,
+	"eth_uninstallFilter":             true,
+	"eth_getWork":                     true,
+	"eth_submitWork":                  true,
+	"eth_submitHashrate":              true,
+}
+
+// QuorumClient sends every read request to K endpoints at once, compares the
+// canonicalized JSON results and only returns a value that a strict majority
+// of them agreed on. It implements Client.
+type QuorumClient struct {
+	k         int
+	endpoints []*Endpoint
+	m         *MultiClient
+}
+
+// NewQuorumClient returns a client that queries the first k of the given
+// endpoints for every request.
+func NewQuorumClient(k int, endpoints ...*Endpoint) (*QuorumClient, error) {
+	if k < 1 || k > len(endpoints) {
+		return nil, fmt.Errorf("quorum of %d out of %d endpoints", k, len(endpoints))
+	}
+	q := &QuorumClient{}
+	q.k = k
+	q.endpoints = endpoints
+	return q, nil
+}
+
+// Quorum returns a QuorumClient that queries k of the endpoints of m, picked
+// by the strategy of m among the healthy ones. Requests fail with
+// ErrNoHealthyEndpoint while fewer than k endpoints are healthy.
+func (m *MultiClient) Quorum(k int) (*QuorumClient, error) {
+	q, err := NewQuorumClient(k, m.endpoints...)
+	if err != nil {
+		return nil, err
+	}
+	q.m = m
+	return q, nil
+}
+
+// Quorum returns a copy of the Eth namespace whose read methods are answered
+// by a majority of k endpoints. The namespace must be backed by a MultiClient.
+// The copy keeps the signer and the capability gate of e.
+func (e *Eth) Quorum(k int) (*Eth, error) {
+	m, ok := unwrapClient(e.c).(*MultiClient)
+	if !ok {
+		return nil, fmt.Errorf("quorum reads need a *MultiClient, have %T", unwrapClient(e.c))
+	}
+	q, err := m.Quorum(k)
+	if err != nil {
+		return nil, err
+	}
+	quorum := NewEth(&gatedClient{q, e.gate})
+	quorum.gate = e.gate
+	quorum.signer = e.signer
+	return quorum, nil
+}
+
+// pick returns the k endpoints to query. Ejected endpoints never vote.
+func (q *QuorumClient) pick() ([]*Endpoint, error) {
+	if q.m == nil {
+		return q.endpoints[:q.k], nil
+	}
+	healthy, _ := q.m.partition()
+	if len(healthy) < q.k {
+		return nil, fmt.Errorf("%w: %d healthy endpoints for a quorum of %d", ErrNoHealthyEndpoint, len(healthy), q.k)
+	}
+	return healthy[:q.k], nil
+}
+
+// CallContext sends the request to K endpoints and decodes the majority
+// result into result. A *DivergenceError is returned if there is no majority.
+func (q *QuorumClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	endpoints, err := q.pick()
+	if err != nil {
+		return err
+	}
+	if quorumBypass[method] {
+		return endpoints[0].c.CallContext(ctx, result, method, args...)
+	}
+	responses := make([]QuorumResponse, len(endpoints))
+	var wg sync.WaitGroup
+	for i, e := range endpoints {
+		wg.Add(1)
+		go func(i int, e *Endpoint) {
+			defer wg.Done()
+			var raw json.RawMessage
+			err := e.c.CallContext(ctx, &raw, method, args...)
+			responses[i] = QuorumResponse{Endpoint: e.Name, Result: raw, Err: err}
+		}(i, e)
+	}
+	wg.Wait()
+	return q.decide(method, responses, result)
+}
+
+// BatchCallContext sends the batch to K endpoints and settles every element
+// on its own majority. Elements without a majority get a *DivergenceError.
+func (q *QuorumClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	endpoints, err := q.pick()
+	if err != nil {
+		return err
+	}
+	batches := make([][]rpc.BatchElem, len(endpoints))
+	errs := make([]error, len(endpoints))
+	var wg sync.WaitGroup
+	for i, e := range endpoints {
+		batches[i] = make([]rpc.BatchElem, len(b))
+		for j, elem := range b {
+			batches[i][j] = rpc.BatchElem{Method: elem.Method, Args: elem.Args, Result: new(json.RawMessage)}
+		}
+		wg.Add(1)
+		go func(i int, e *Endpoint) {
+			defer wg.Done()
+			errs[i] = e.c.BatchCallContext(ctx, batches[i])
+		}(i, e)
+	}
+	wg.Wait()
+	for j := range b {
+		responses := make([]QuorumResponse, len(endpoints))
+		for i, e := range endpoints {
+			err := errs[i]
+			if err == nil {
+				err = batches[i][j].Error
+			}
+			responses[i] = QuorumResponse{Endpoint: e.Name, Result: *batches[i][j].Result.(*json.RawMessage), Err: err}
+		}
+		b[j].Error = q.decide(b[j].Method, responses, b[j].Result)
+	}
+	return nil
+}
+
+// decide looks for a strict majority among the responses and decodes it into
+// result. JSON-RPC errors take part in the vote, so that a call every node
+// rejects returns that rejection. Transport errors never count.
+func (q *QuorumClient) decide(method string, responses []QuorumResponse, result interface{}) error {
+	votes := make(map[string][]int)
+	var order []string
+	for i, r := range responses {
+		var key string
+		if r.Err != nil {
+			var rpcErr rpc.Error
+			if !errors.As(r.Err, &rpcErr) {
+				continue
+			}
+			key = fmt.Sprintf("error:%d:%s", rpcErr.ErrorCode(), rpcErr.Error())
+		} else {
+			canon, err := CanonicalJSON(r.Result)
+			if err != nil {
+				continue
+			}
+			key = "result:" + string(canon)
+		}
+		if _, ok := votes[key]; !ok {
+			order = append(order, key)
+		}
+		votes[key] = append(votes[key], i)
+	}
+	for _, key := range order {
+		if len(votes[key])*2 <= len(responses) {
+			continue
+		}
+		winner := responses[votes[key][0]]
+		if winner.Err != nil {
+			return winner.Err
+		}
+		if result == nil || len(winner.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(winner.Result, result)
+	}
+	return &DivergenceError{Method: method, Quorum: len(responses), Responses: responses}
+}
+
+// CanonicalJSON re-encodes raw with sorted object keys and lower-cased hex
+// strings, so that values differing only in formatting or address checksums
+// compare equal.
+func CanonicalJSON(raw json.RawMessage) ([]byte, error) {
+	if len(raw) == 0 {
+		return []byte("null"), nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(canonicalValue(v))
+}
+
+func canonicalValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = canonicalValue(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = canonicalValue(e)
+		}
+	case string:
+		if strings.HasPrefix(v, "0x") || strings.HasPrefix(v, "0X") {
+			return strings.ToLower(v)
+		}
+	}
+	return v
+}
@@ -0,0 +1,245 @@
+package web3_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+)
+
+// voteService answers test_value with its configured JSON value or
+// JSON-RPC error.
+type voteService struct {
+	value json.RawMessage
+	err   error
+}
+
+func (s *voteService) Value() (json.RawMessage, error) {
+	return s.value, s.err
+}
+
+func newVoters(t *testing.T, services ...*voteService) ([]*rpc.Server, []*web3.Endpoint) {
+	var (
+		servers   []*rpc.Server
+		endpoints []*web3.Endpoint
+	)
+	for i, s := range services {
+		server := rpc.NewServer()
+		if err := server.RegisterName("test", s); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(server.Stop)
+		client := rpc.DialInProc(server)
+		t.Cleanup(client.Close)
+		servers = append(servers, server)
+		endpoints = append(endpoints, web3.NewEndpoint(string(rune('a'+i)), client))
+	}
+	return servers, endpoints
+}
+
+func TestQuorumClient(t *testing.T) {
+	addr := common.HexToAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+	lower := json.RawMessage(`{"b":2,"a":"` + strings.ToLower(addr.Hex()) + `"}`)
+	checksummed := json.RawMessage(`{"a":"` + addr.Hex() + `","b":2}`)
+	other := json.RawMessage(`{"a":"0x00","b":3}`)
+	rejected := errors.New("rejected")
+
+	for _, test := range []struct {
+		name     string
+		k        int
+		services []*voteService
+		down     []int
+		want     json.RawMessage
+		wantErr  string
+		diverged bool
+	}{
+		{
+			name:     "formatting differences agree",
+			k:        3,
+			services: []*voteService{{value: lower}, {value: other}, {value: checksummed}},
+			want:     checksummed,
+		},
+		{
+			name:     "tie",
+			k:        2,
+			services: []*voteService{{value: lower}, {value: other}, {value: other}},
+			diverged: true,
+		},
+		{
+			name:     "no two agree",
+			k:        3,
+			services: []*voteService{{value: lower}, {value: other}, {value: json.RawMessage(`null`)}},
+			diverged: true,
+		},
+		{
+			name:     "only the first k endpoints vote",
+			k:        1,
+			services: []*voteService{{value: other}, {value: lower}, {value: lower}},
+			want:     other,
+		},
+		{
+			name:     "JSON-RPC errors vote",
+			k:        3,
+			services: []*voteService{{err: rejected}, {value: lower}, {err: rejected}},
+			wantErr:  "rejected",
+		},
+		{
+			name:     "transport errors do not vote",
+			k:        3,
+			services: []*voteService{{value: lower}, {value: checksummed}, {value: other}},
+			down:     []int{2},
+			want:     lower,
+		},
+		{
+			name:     "a majority of all k endpoints is needed",
+			k:        3,
+			services: []*voteService{{value: lower}, {value: other}, {value: lower}},
+			down:     []int{0},
+			diverged: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			servers, endpoints := newVoters(t, test.services...)
+			for _, i := range test.down {
+				servers[i].Stop()
+			}
+			q, err := web3.NewQuorumClient(test.k, endpoints...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var result json.RawMessage
+			err = q.CallContext(context.Background(), &result, "test_value")
+			var divergence *web3.DivergenceError
+			switch {
+			case test.diverged:
+				if !errors.As(err, &divergence) || divergence.Method != "test_value" || divergence.Quorum != test.k || len(divergence.Responses) != test.k {
+					t.Errorf("CallContext = %s %v, want a divergence among %d", result, err, test.k)
+				}
+			case test.wantErr != "":
+				var rpcErr rpc.Error
+				if !errors.As(err, &rpcErr) || err.Error() != test.wantErr {
+					t.Errorf("CallContext error = %v, want %q", err, test.wantErr)
+				}
+			default:
+				want, _ := web3.CanonicalJSON(test.want)
+				got, _ := web3.CanonicalJSON(result)
+				if err != nil || string(got) != string(want) {
+					t.Errorf("CallContext = %s %v, want %s", result, err, test.want)
+				}
+			}
+		})
+	}
+
+	if _, err := web3.NewQuorumClient(4, make([]*web3.Endpoint, 3)...); err == nil {
+		t.Error("NewQuorumClient accepted a quorum larger than the endpoints")
+	}
+}
+
+func TestMultiClientQuorumSkipsEjected(t *testing.T) {
+	value := json.RawMessage(`"0xab"`)
+	servers, endpoints := newVoters(t, &voteService{value: value}, &voteService{value: value}, &voteService{value: value})
+	m := web3.NewMultiClient(web3.MultiClientConfig{Strategy: web3.PrimaryBackup}, endpoints...)
+	defer m.Close()
+	q, err := m.Quorum(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// a failed request ejects the primary, the others vote
+	servers[0].Stop()
+	if err := m.CallContext(ctx, nil, "test_value"); err != nil {
+		t.Fatal(err)
+	}
+	var result json.RawMessage
+	if err := q.CallContext(ctx, &result, "test_value"); err != nil || string(result) != string(value) {
+		t.Errorf("CallContext = %s %v, want %s", result, err, value)
+	}
+
+	// too few healthy endpoints left
+	servers[1].Stop()
+	if err := m.CallContext(ctx, nil, "test_value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.CallContext(ctx, &result, "test_value"); !errors.Is(err, web3.ErrNoHealthyEndpoint) {
+		t.Errorf("CallContext with one healthy endpoint = %v, want %v", err, web3.ErrNoHealthyEndpoint)
+	}
+	if err := q.BatchCallContext(ctx, []rpc.BatchElem{{Method: "test_value", Result: &result}}); !errors.Is(err, web3.ErrNoHealthyEndpoint) {
+		t.Errorf("BatchCallContext with one healthy endpoint = %v, want %v", err, web3.ErrNoHealthyEndpoint)
+	}
+}
+
+func TestQuorumClientBatch(t *testing.T) {
+	_, endpoints := newVoters(t, &voteService{value: json.RawMessage(`"0xAB"`)}, &voteService{value: json.RawMessage(`"0xab"`)}, &voteService{value: json.RawMessage(`"0xcd"`)})
+	q, err := web3.NewQuorumClient(3, endpoints...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var value string
+	batch := []rpc.BatchElem{
+		{Method: "test_value", Result: &value},
+		{Method: "test_missing", Result: new(string)},
+	}
+	if err := q.BatchCallContext(context.Background(), batch); err != nil {
+		t.Fatal(err)
+	}
+	if batch[0].Error != nil || (value != "0xAB" && value != "0xab") {
+		t.Errorf("batch element = %q %v", value, batch[0].Error)
+	}
+	if !errors.Is(web3.ClassifyError(batch[1].Error), web3.ErrMethodNotFound) {
+		t.Errorf("batch element every endpoint rejects: %v", batch[1].Error)
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	for _, test := range []struct{ in, want string }{
+		{``, `null`},
+		{`{"b":[1,{"d":"0XAB","c":"Ab"}],"a":1.50}`, `{"a":1.50,"b":[1,{"c":"Ab","d":"0xab"}]}`},
+		{`"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"`, `"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"`},
+		{`123456789012345678901234567890`, `123456789012345678901234567890`},
+	} {
+		got, err := web3.CanonicalJSON(json.RawMessage(test.in))
+		if err != nil || string(got) != test.want {
+			t.Errorf("CanonicalJSON(%s) = %s %v, want %s", test.in, got, err, test.want)
+		}
+	}
+	if _, err := web3.CanonicalJSON(json.RawMessage(`{`)); err == nil {
+		t.Error("CanonicalJSON of invalid JSON succeeded")
+	}
+}
+
+func TestEthQuorumKeepsSignerAndGate(t *testing.T) {
+	// the endpoints serve no eth namespace
+	servers, endpoints := newVoters(t, &voteService{}, &voteService{})
+	m := web3.NewMultiClient(web3.MultiClientConfig{}, endpoints...)
+	defer m.Close()
+	w := web3.NewWeb3(m)
+	// another node signs
+	signer := web3.NewEth(rpc.DialInProc(servers[0]))
+
+	q, err := w.Eth.WithSigner(signer).Quorum(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Signer() != signer {
+		t.Error("Quorum dropped the signer")
+	}
+	if _, err := w.Capabilities(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if q.Available() {
+		t.Error("Quorum dropped the capability gate")
+	}
+	if _, err := q.ChainID(context.Background()); !errors.Is(err, web3.ErrNamespaceUnavailable) {
+		t.Errorf("ChainID = %v, want %v", err, web3.ErrNamespaceUnavailable)
+	}
+	if _, err := web3.NewWeb3(rpc.DialInProc(servers[1])).Eth.Quorum(1); err == nil {
+		t.Error("Quorum without a MultiClient succeeded")
+	}
+}
@@ -2,20 +2,25 @@ package web3
 
 import (
 	"context"
-
-	"github.com/ethereum/go-ethereum/rpc"
 )
 
 type Rpc struct {
-	c *rpc.Client
+	c    Client
+	gate *capabilityGate
 }
 
-func NewRpc(c *rpc.Client) *Rpc {
+func NewRpc(c Client) *Rpc {
 	e := &Rpc{}
-	e.c = c
+	e.c = withCallErrors(c)
 	return e
 }
 
+// Available reports whether the node serves the rpc namespace. It is true
+// until Web3.Capabilities has probed the node.
+func (r *Rpc) Available() bool {
+	return r.gate.available("rpc")
+}
+
 // Modules returns the list of RPC services with their version number
 // from RPCService
 // from web3ext.go
@@ -0,0 +1,22 @@
+package web3_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+func TestRpcDevNode(t *testing.T) {
+	w := web3test.NewWeb3(t)
+
+	modules, err := w.Rpc.Modules(context.Background())
+	if err != nil {
+		t.Fatalf("Modules: %v", err)
+	}
+	for _, namespace := range []string{"admin", "debug", "eth", "miner", "net", "personal", "txpool", "web3"} {
+		if _, ok := modules[namespace]; !ok {
+			t.Errorf("Modules = %v, missing %s", modules, namespace)
+		}
+	}
+}
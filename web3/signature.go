@@ -0,0 +1,109 @@
+package web3
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// signHash signs a 32-byte hash with the v value of 27 or 28 that wallets and
+// ecrecover use.
+func signHash(key *ecdsa.PrivateKey, hash common.Hash) ([]byte, error) {
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		return nil, err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig, nil
+}
+
+// recoverHash returns the address that signed a 32-byte hash. The v value of
+// sig may be 27 or 28 as well as 0 or 1. Malleable signatures with an s value
+// in the upper half of the curve order are rejected, as by EIP-2.
+func recoverHash(hash common.Hash, sig []byte) (common.Address, error) {
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("invalid signature length %d, want %d", len(sig), crypto.SignatureLength)
+	}
+	sig = common.CopyBytes(sig)
+	if v := sig[crypto.RecoveryIDOffset]; v == 27 || v == 28 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	v := sig[crypto.RecoveryIDOffset]
+	if v != 0 && v != 1 {
+		return common.Address{}, fmt.Errorf("invalid signature recovery id %d", v)
+	}
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])
+	if !crypto.ValidateSignatureValues(v, r, s, true) {
+		return common.Address{}, fmt.Errorf("invalid signature values")
+	}
+	pub, err := crypto.SigToPub(hash[:], sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// SignMessage signs a message with a local key the way eth_sign and
+// personal_sign do, hashing it with accounts.TextHash. The signature has a v
+// value of 27 or 28.
+func SignMessage(key *ecdsa.PrivateKey, message []byte) ([]byte, error) {
+	return signHash(key, common.BytesToHash(accounts.TextHash(message)))
+}
+
+// RecoverMessage returns the address that signed a message with eth_sign or
+// personal_sign, without sending the message to a node. The v value of sig may
+// be 27 or 28 as well as 0 or 1.
+func RecoverMessage(message, sig []byte) (common.Address, error) {
+	return recoverHash(common.BytesToHash(accounts.TextHash(message)), sig)
+}
+
+// eip1271MagicValue is the selector of isValidSignature(bytes32,bytes), which
+// contract wallets return for the signatures they accept.
+var eip1271MagicValue = []byte{0x16, 0x26, 0xba, 0x7e}
+
+// IsValidSignature asks a contract wallet whether sig is a valid signature of
+// hash on its behalf, calling its EIP-1271 isValidSignature at the given block,
+// the latest if nil. It is false for contracts that revert and for accounts
+// without code.
+func (e *Eth) IsValidSignature(ctx context.Context, wallet common.Address, hash common.Hash, sig []byte, blockNrOrHash *rpc.BlockNumberOrHash) (bool, error) {
+	padded := (len(sig) + 31) / 32 * 32
+	input := make(hexutil.Bytes, 0, 4+3*32+padded)
+	input = append(input, eip1271MagicValue...)
+	input = append(input, hash[:]...)
+	input = append(input, common.LeftPadBytes(big.NewInt(64).Bytes(), 32)...)
+	input = append(input, common.LeftPadBytes(big.NewInt(int64(len(sig))).Bytes(), 32)...)
+	input = append(input, common.RightPadBytes(sig, padded)...)
+	result, err := e.Call(ctx, TransactionArgs{To: &wallet, Input: &input}, blockNrOrHash, nil, nil)
+	if errors.Is(err, ErrExecutionReverted) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(result) >= 4 && bytes.Equal(result[:4], eip1271MagicValue), nil
+}
+
+// VerifySignature reports whether sig is a signature of hash by signer, be it
+// an account, checked locally, or a contract wallet, asked with
+// IsValidSignature at the latest block.
+func (e *Eth) VerifySignature(ctx context.Context, signer common.Address, hash common.Hash, sig []byte) (bool, error) {
+	if recovered, err := recoverHash(hash, sig); err == nil && recovered == signer {
+		return true, nil
+	}
+	return e.IsValidSignature(ctx, signer, hash, sig, nil)
+}
+
+// VerifyMessage is VerifySignature for a message signed with eth_sign or
+// personal_sign.
+func (e *Eth) VerifyMessage(ctx context.Context, signer common.Address, message, sig []byte) (bool, error) {
+	return e.VerifySignature(ctx, signer, common.BytesToHash(accounts.TextHash(message)), sig)
+}
@@ -0,0 +1,485 @@
+package web3
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+//go:embed signatures.txt
+var bundledSignatures string
+
+// ErrUnknownSignature is returned when a SignatureDB has no signature for a
+// selector or event topic, or none that decodes the data.
+var ErrUnknownSignature = errors.New("unknown signature")
+
+type signatureKind int
+
+const (
+	signatureFunction signatureKind = iota
+	signatureEvent
+	signatureError
+)
+
+// Sources of signatures, the better known ones winning over the others when
+// several decode the same data.
+const (
+	sourceBundled = iota
+	sourceText
+	sourceABI
+)
+
+// signatureEntry is a function, event or error known to a SignatureDB.
+type signatureEntry struct {
+	kind      signatureKind
+	name      string
+	signature string // canonical, such as "transfer(address,uint256)"
+	inputs    abi.Arguments
+	outputs   abi.Arguments // of functions from ABIs only
+	source    int
+	// guessIndexed is set for events declared without indexed parameters,
+	// whose leading parameters are then assumed to be indexed.
+	guessIndexed bool
+}
+
+// SignatureDB resolves 4-byte selectors and event topics to the signatures
+// of functions, errors and events, to decode calldata, return data and logs
+// without the ABI of the contract. Signatures come from declarations, such
+// as "transfer(address to, uint256 amount)", from ABIs and from solc
+// artifacts. It is safe for concurrent use.
+//
+// Several signatures can share a selector, on purpose or not. Decoding picks
+// the one that decodes the data exactly, re-encoding to the same bytes, and
+// then prefers signatures from ABIs and artifacts over declarations and
+// declarations over the bundled ones; the others that decode are reported as
+// alternatives.
+type SignatureDB struct {
+	mu        sync.RWMutex
+	selectors map[[4]byte][]*signatureEntry // functions and errors
+	topics    map[common.Hash][]*signatureEntry
+}
+
+// NewSignatureDB returns an empty signature database.
+func NewSignatureDB() *SignatureDB {
+	return &SignatureDB{
+		selectors: make(map[[4]byte][]*signatureEntry),
+		topics:    make(map[common.Hash][]*signatureEntry),
+	}
+}
+
+// DefaultSignatureDB returns a new signature database loaded with the bundled
+// signatures: those of the ERC-20, ERC-721 and ERC-1155 tokens, Permit2,
+// WETH, Multicall3, Uniswap, ENS and of the Solidity Error and Panic errors.
+func DefaultSignatureDB() *SignatureDB {
+	db := NewSignatureDB()
+	if err := db.load(strings.NewReader(bundledSignatures), sourceBundled); err != nil {
+		panic(fmt.Sprintf("web3: invalid bundled signatures: %v", err))
+	}
+	return db
+}
+
+// AddSignature adds a function, event or error declaration in Solidity
+// syntax, with or without parameter names and keyword, functions being the
+// default: "transfer(address,uint256)", "event Transfer(address indexed
+// from, address indexed to, uint256 value)" or "error Unauthorized(address)".
+// Tuples are written as parenthesized parameter lists.
+func (db *SignatureDB) AddSignature(declaration string) error {
+	entry, err := parseDeclaration(declaration)
+	if err != nil {
+		return err
+	}
+	entry.source = sourceText
+	db.add(entry)
+	return nil
+}
+
+// LoadSignatures adds the declarations read from r, one per line, see
+// AddSignature. Empty lines and lines starting with # are skipped.
+func (db *SignatureDB) LoadSignatures(r io.Reader) error {
+	return db.load(r, sourceText)
+}
+
+func (db *SignatureDB) load(r io.Reader, source int) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		entry, err := parseDeclaration(text)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		entry.source = source
+		db.add(entry)
+	}
+	return scanner.Err()
+}
+
+// AddABI adds the functions, events and errors of a contract ABI in JSON.
+func (db *SignatureDB) AddABI(r io.Reader) error {
+	parsed, err := abi.JSON(r)
+	if err != nil {
+		return err
+	}
+	db.addABI(&parsed)
+	return nil
+}
+
+// AddArtifact adds the ABIs found in a solc output: the JSON artifacts of
+// Hardhat and Foundry, with an "abi" field, as well as the standard JSON and
+// the combined JSON outputs of solc, holding the ABIs of several contracts.
+func (db *SignatureDB) AddArtifact(r io.Reader) error {
+	var artifact interface{}
+	if err := json.NewDecoder(r).Decode(&artifact); err != nil {
+		return fmt.Errorf("invalid artifact: %w", err)
+	}
+	found := 0
+	var walk func(v interface{}) error
+	walk = func(v interface{}) error {
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := object[key]
+			if key != "abi" {
+				if err := walk(value); err != nil {
+					return err
+				}
+				continue
+			}
+			// combined JSON outputs of old solc versions hold the ABI as a string
+			encoded, ok := value.(string)
+			if !ok {
+				raw, err := json.Marshal(value)
+				if err != nil {
+					return err
+				}
+				encoded = string(raw)
+			}
+			parsed, err := abi.JSON(strings.NewReader(encoded))
+			if err != nil {
+				return fmt.Errorf("invalid artifact ABI: %w", err)
+			}
+			db.addABI(&parsed)
+			found++
+		}
+		return nil
+	}
+	if err := walk(artifact); err != nil {
+		return err
+	}
+	if found == 0 {
+		return errors.New("invalid artifact: no ABI found")
+	}
+	return nil
+}
+
+func (db *SignatureDB) addABI(parsed *abi.ABI) {
+	for _, method := range parsed.Methods {
+		db.add(&signatureEntry{kind: signatureFunction, name: method.RawName, signature: method.Sig, inputs: method.Inputs, outputs: method.Outputs, source: sourceABI})
+	}
+	for _, event := range parsed.Events {
+		if !event.Anonymous {
+			db.add(&signatureEntry{kind: signatureEvent, name: event.RawName, signature: event.Sig, inputs: event.Inputs, source: sourceABI})
+		}
+	}
+	for _, e := range parsed.Errors {
+		db.add(&signatureEntry{kind: signatureError, name: e.Name, signature: e.Sig, inputs: e.Inputs, source: sourceABI})
+	}
+}
+
+func (db *SignatureDB) add(entry *signatureEntry) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if entry.kind == signatureEvent {
+		topic := entry.topic()
+		db.topics[topic] = addEntry(db.topics[topic], entry)
+	} else {
+		selector := entry.selector()
+		db.selectors[selector] = addEntry(db.selectors[selector], entry)
+	}
+}
+
+// addEntry adds an entry to those of a selector or topic, replacing an entry
+// of the same signature, and indexed parameters for events, from a source
+// that is not better known. It returns a new slice rather than writing to
+// entries, which decoders read without holding the lock.
+func addEntry(entries []*signatureEntry, entry *signatureEntry) []*signatureEntry {
+	for i, existing := range entries {
+		if existing.kind == entry.kind && existing.signature == entry.signature && existing.layout() == entry.layout() {
+			if entry.source < existing.source {
+				return entries
+			}
+			updated := append([]*signatureEntry(nil), entries...)
+			updated[i] = entry
+			return updated
+		}
+	}
+	updated := make([]*signatureEntry, len(entries), len(entries)+1)
+	copy(updated, entries)
+	return append(updated, entry)
+}
+
+func (e *signatureEntry) selector() [4]byte {
+	var selector [4]byte
+	copy(selector[:], e.topic().Bytes())
+	return selector
+}
+
+func (e *signatureEntry) topic() common.Hash {
+	return crypto.Keccak256Hash([]byte(e.signature))
+}
+
+// layout tells apart events of the same signature and different indexed
+// parameters, such as the Transfer events of ERC-20 and ERC-721 tokens.
+func (e *signatureEntry) layout() string {
+	if e.kind != signatureEvent || e.guessIndexed {
+		return ""
+	}
+	var layout strings.Builder
+	for _, input := range e.inputs {
+		if input.Indexed {
+			layout.WriteByte('i')
+		} else {
+			layout.WriteByte('-')
+		}
+	}
+	return layout.String()
+}
+
+// Signatures returns the signatures known for a 4-byte selector, of functions
+// and errors, in the order they are preferred.
+func (db *SignatureDB) Signatures(selector [4]byte) []string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return entrySignatures(db.selectors[selector])
+}
+
+// EventSignatures returns the signatures known for an event topic.
+func (db *SignatureDB) EventSignatures(topic common.Hash) []string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return entrySignatures(db.topics[topic])
+}
+
+func entrySignatures(entries []*signatureEntry) []string {
+	entries = rankEntries(entries)
+	signatures := make([]string, 0, len(entries))
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if !seen[entry.signature] {
+			seen[entry.signature] = true
+			signatures = append(signatures, entry.signature)
+		}
+	}
+	return signatures
+}
+
+// parseDeclaration parses a function, event or error declaration.
+func parseDeclaration(declaration string) (*signatureEntry, error) {
+	text := strings.TrimSpace(declaration)
+	entry := &signatureEntry{kind: signatureFunction}
+	for keyword, kind := range map[string]signatureKind{"function ": signatureFunction, "event ": signatureEvent, "error ": signatureError} {
+		if strings.HasPrefix(text, keyword) {
+			entry.kind, text = kind, strings.TrimSpace(text[len(keyword):])
+		}
+	}
+	open := strings.IndexByte(text, '(')
+	if open <= 0 || !isIdentifier(strings.TrimSpace(text[:open])) {
+		return nil, fmt.Errorf("invalid declaration %q", declaration)
+	}
+	entry.name = strings.TrimSpace(text[:open])
+	closing := matchingParen(text, open)
+	if closing < 0 {
+		return nil, fmt.Errorf("invalid declaration %q: unbalanced parentheses", declaration)
+	}
+	params, err := parseParams(text[open+1 : closing])
+	if err != nil {
+		return nil, fmt.Errorf("invalid declaration %q: %w", declaration, err)
+	}
+	// what follows is ignored, such as the modifiers and returns of functions,
+	// but anonymous events have no topic to be found by
+	if entry.kind == signatureEvent && strings.Contains(text[closing:], "anonymous") {
+		return nil, fmt.Errorf("invalid declaration %q: anonymous events are not supported", declaration)
+	}
+
+	entry.guessIndexed = entry.kind == signatureEvent
+	for i, param := range params {
+		typ, err := abi.NewType(param.Type, "", param.Components)
+		if err != nil {
+			return nil, fmt.Errorf("invalid declaration %q: %w", declaration, err)
+		}
+		entry.inputs = append(entry.inputs, abi.Argument{Name: param.Name, Type: typ, Indexed: param.Indexed})
+		if param.Indexed {
+			if entry.kind != signatureEvent {
+				return nil, fmt.Errorf("invalid declaration %q: parameter %d indexed outside of an event", declaration, i)
+			}
+			entry.guessIndexed = false
+		}
+	}
+	switch entry.kind {
+	case signatureEvent:
+		entry.signature = abi.NewEvent(entry.name, entry.name, false, entry.inputs).Sig
+	case signatureError:
+		entry.signature = abi.NewError(entry.name, entry.inputs).Sig
+	default:
+		entry.signature = abi.NewMethod(entry.name, entry.name, abi.Function, "", false, false, entry.inputs, nil).Sig
+	}
+	return entry, nil
+}
+
+// parseParams parses a comma separated parameter list, in which tuples are
+// parenthesized parameter lists.
+func parseParams(list string) ([]abi.ArgumentMarshaling, error) {
+	var params []abi.ArgumentMarshaling
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+	for i, text := range splitParams(list) {
+		text = strings.TrimSpace(text)
+		var param abi.ArgumentMarshaling
+		if strings.HasPrefix(text, "tuple(") {
+			text = text[len("tuple"):]
+		}
+		var rest string
+		if strings.HasPrefix(text, "(") {
+			closing := matchingParen(text, 0)
+			if closing < 0 {
+				return nil, errors.New("unbalanced parentheses")
+			}
+			components, err := parseParams(text[1:closing])
+			if err != nil {
+				return nil, err
+			}
+			for j := range components {
+				if components[j].Name == "" {
+					components[j].Name = fmt.Sprintf("field%d", j)
+				}
+			}
+			suffix, tail, _ := strings.Cut(text[closing+1:], " ")
+			param.Type, param.Components, rest = "tuple"+suffix, components, tail
+		} else {
+			typ, tail, _ := strings.Cut(text, " ")
+			param.Type, rest = canonicalType(typ), tail
+			if !validElementary(param.Type) {
+				return nil, fmt.Errorf("invalid type %q", typ)
+			}
+		}
+		for _, word := range strings.Fields(rest) {
+			switch word {
+			case "indexed":
+				param.Indexed = true
+			case "memory", "calldata", "storage", "payable":
+			default:
+				if param.Name != "" || !isIdentifier(word) {
+					return nil, fmt.Errorf("invalid parameter %d %q", i, text)
+				}
+				param.Name = word
+			}
+		}
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+// canonicalType expands the aliases of elementary types.
+func canonicalType(typ string) string {
+	base, suffix := typ, ""
+	if i := strings.IndexByte(typ, '['); i >= 0 {
+		base, suffix = typ[:i], typ[i:]
+	}
+	switch base {
+	case "uint", "int":
+		base += "256"
+	case "byte":
+		base = "bytes1"
+	}
+	return base + suffix
+}
+
+// validElementary reports whether an elementary type, possibly an array, has
+// a valid size, which abi.NewType does not check.
+func validElementary(typ string) bool {
+	base, _, _ := strings.Cut(typ, "[")
+	for _, prefix := range []string{"uint", "int", "bytes"} {
+		if digits := strings.TrimPrefix(base, prefix); digits != base && digits != "" {
+			size, err := strconv.Atoi(digits)
+			if err != nil {
+				return false
+			}
+			if prefix == "bytes" {
+				return 1 <= size && size <= 32
+			}
+			return 8 <= size && size <= 256 && size%8 == 0
+		}
+	}
+	return true
+}
+
+// splitParams splits a parameter list at its top-level commas.
+func splitParams(list string) []string {
+	var params []string
+	depth, start := 0, 0
+	for i, c := range list {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				params = append(params, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(params, list[start:])
+}
+
+// matchingParen returns the index of the parenthesis closing the one at open,
+// or -1.
+func matchingParen(text string, open int) int {
+	depth := 0
+	for i := open; i < len(text); i++ {
+		switch text[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if !(c == '_' || c == '$' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,241 @@
+package web3_test
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/moonfdd/web3-go/web3"
+)
+
+// calldata returns the calldata of a call to signature with ABI-encoded
+// words.
+func calldata(signature string, words ...[]byte) []byte {
+	data := crypto.Keccak256([]byte(signature))[:4]
+	for _, word := range words {
+		data = append(data, common.LeftPadBytes(word, 32)...)
+	}
+	return data
+}
+
+func TestSignatureDBCalldata(t *testing.T) {
+	db := web3.DefaultSignatureDB()
+	to := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+
+	call, err := db.DecodeCalldata(calldata("transfer(address,uint256)", to.Bytes(), big.NewInt(1000).Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "transfer(to: " + to.Hex() + ", amount: 1000)"; call.String() != want || call.Signature != "transfer(address,uint256)" {
+		t.Errorf("DecodeCalldata = %s %s, want %s", call, call.Signature, want)
+	}
+	if _, err := db.DecodeCalldata(calldata("unknown(uint256)", []byte{1})); !errors.Is(err, web3.ErrUnknownSignature) {
+		t.Errorf("DecodeCalldata of an unknown selector = %v, want %v", err, web3.ErrUnknownSignature)
+	}
+	if _, err := db.DecodeCalldata(calldata("transfer(address,uint256)", to.Bytes())); !errors.Is(err, web3.ErrUnknownSignature) {
+		t.Errorf("DecodeCalldata of truncated data = %v, want %v", err, web3.ErrUnknownSignature)
+	}
+
+	// tuples, arrays and dynamic types
+	if err := db.AddSignature("function batch((address target, bytes data)[] calls, string note) external returns (bool)"); err != nil {
+		t.Fatal(err)
+	}
+	input := hexutil.MustDecode("0x" +
+		"0000000000000000000000000000000000000000000000000000000000000040" + // calls
+		"0000000000000000000000000000000000000000000000000000000000000100" + // note
+		"0000000000000000000000000000000000000000000000000000000000000001" + // 1 call
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"00000000000000000000000000000000000000000000000000000000000000bb" + // target
+		"0000000000000000000000000000000000000000000000000000000000000040" +
+		"0000000000000000000000000000000000000000000000000000000000000002" + // data
+		"abcd000000000000000000000000000000000000000000000000000000000000" +
+		"0000000000000000000000000000000000000000000000000000000000000002" + // note
+		"6869000000000000000000000000000000000000000000000000000000000000")
+	call, err = db.DecodeCalldata(append(crypto.Keccak256([]byte("batch((address,bytes)[],string)"))[:4], input...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `batch(calls: [(` + to.Hex() + `, 0xabcd)], note: "hi")`; call.String() != want {
+		t.Errorf("DecodeCalldata = %s, want %s", call, want)
+	}
+
+	for _, declaration := range []string{"transfer", "f(uint257)", "f(address", "event E(uint256) anonymous", "f(uint256 indexed)"} {
+		if err := db.AddSignature(declaration); err == nil {
+			t.Errorf("AddSignature(%q) succeeded", declaration)
+		}
+	}
+}
+
+func TestSignatureDBCollisions(t *testing.T) {
+	db := web3.NewSignatureDB()
+	if err := db.LoadSignatures(strings.NewReader("# burn and collate_propagate_storage share selector 0x42966c68\nburn(uint256 amount)\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddABI(strings.NewReader(`[{"type":"function","name":"collate_propagate_storage","inputs":[{"name":"","type":"bytes16"}],"outputs":[]}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if signatures := db.Signatures([4]byte{0x42, 0x96, 0x6c, 0x68}); len(signatures) != 2 || signatures[0] != "collate_propagate_storage(bytes16)" {
+		t.Errorf("Signatures = %v", signatures)
+	}
+
+	// an amount is not a valid bytes16, which is left aligned
+	call, err := db.DecodeCalldata(calldata("burn(uint256)", big.NewInt(5).Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call.Signature != "burn(uint256)" || len(call.Alternatives) != 1 || call.Alternatives[0] != "collate_propagate_storage(bytes16)" {
+		t.Errorf("DecodeCalldata of an amount = %s, alternatives %v", call.Signature, call.Alternatives)
+	}
+	// both decode exactly, the signature of the ABI wins
+	word := common.RightPadBytes([]byte{0xff}, 32)
+	if call, err := db.DecodeCalldata(calldata("burn(uint256)", word)); err != nil || call.Signature != "collate_propagate_storage(bytes16)" {
+		t.Errorf("DecodeCalldata of bytes16 = %v %v", call, err)
+	}
+}
+
+func TestSignatureDBLogs(t *testing.T) {
+	db := web3.DefaultSignatureDB()
+	from := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	to := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	transfer := crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+	erc20 := &types.Log{Topics: []common.Hash{transfer, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())}, Data: common.LeftPadBytes([]byte{7}, 32)}
+	erc721 := &types.Log{Topics: []common.Hash{transfer, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes()), common.HexToHash("0x09")}}
+	unknown := &types.Log{Topics: []common.Hash{common.HexToHash("0x01")}}
+	events := db.DecodeLogs([]*types.Log{erc20, erc721, unknown})
+	if want := "Transfer(from: " + from.Hex() + ", to: " + to.Hex() + ", value: 7)"; events[0] == nil || events[0].String() != want {
+		t.Errorf("DecodeLog of an ERC-20 transfer = %v, want %s", events[0], want)
+	}
+	if want := "Transfer(from: " + from.Hex() + ", to: " + to.Hex() + ", tokenId: 9)"; events[1] == nil || events[1].String() != want || !events[1].Args[2].Indexed {
+		t.Errorf("DecodeLog of an ERC-721 transfer = %v, want %s", events[1], want)
+	}
+	if events[2] != nil {
+		t.Errorf("DecodeLog of an unknown event = %v", events[2])
+	}
+
+	// events declared without indexed parameters have their leading ones
+	// indexed, and dynamic indexed ones are hashed
+	if err := db.AddSignature("event Named(string name, uint256 value)"); err != nil {
+		t.Fatal(err)
+	}
+	named := &types.Log{Topics: []common.Hash{crypto.Keccak256Hash([]byte("Named(string,uint256)")), crypto.Keccak256Hash([]byte("alice"))}, Data: common.LeftPadBytes([]byte{1}, 32)}
+	event, err := db.DecodeLog(named)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Args[0].Value != named.Topics[1] || event.Args[1].Value.(*big.Int).Int64() != 1 {
+		t.Errorf("DecodeLog = %v", event)
+	}
+}
+
+func TestSignatureDBConcurrentAdd(t *testing.T) {
+	db := web3.DefaultSignatureDB()
+	// re-adds bundled signatures, from a better source
+	const erc20ABI = `[
+		{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+		{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]}
+	]`
+	to := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	input := calldata("transfer(address,uint256)", to.Bytes(), []byte{1})
+	log := &types.Log{Topics: []common.Hash{crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")), {}, common.BytesToHash(to.Bytes())}, Data: common.LeftPadBytes([]byte{1}, 32)}
+
+	done := make(chan error)
+	go func() {
+		for i := 0; i < 100; i++ {
+			if err := db.AddABI(strings.NewReader(erc20ABI)); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	for i := 0; i < 100; i++ {
+		if _, err := db.DecodeCalldata(input); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.DecodeLog(log); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSignatureDBArtifacts(t *testing.T) {
+	const counterABI = `[
+		{"type":"function","name":"count","inputs":[],"outputs":[{"name":"value","type":"uint256"}]},
+		{"type":"error","name":"TooLarge","inputs":[{"name":"limit","type":"uint256"}]}
+	]`
+	for name, artifact := range map[string]string{
+		"hardhat":  `{"contractName":"Counter","abi":` + counterABI + `,"bytecode":"0x"}`,
+		"standard": `{"contracts":{"Counter.sol":{"Counter":{"abi":` + counterABI + `}}}}`,
+		"combined": `{"contracts":{"Counter.sol:Counter":{"abi":` + jsonString(counterABI) + `}}}`,
+	} {
+		db := web3.NewSignatureDB()
+		if err := db.AddArtifact(strings.NewReader(artifact)); err != nil {
+			t.Fatalf("AddArtifact of a %s artifact: %v", name, err)
+		}
+		call, err := db.DecodeCalldata(calldata("count()"))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if outputs, err := call.DecodeOutput(common.LeftPadBytes([]byte{3}, 32)); err != nil || outputs[0].Name != "value" || outputs[0].Value.(*big.Int).Int64() != 3 {
+			t.Errorf("%s: DecodeOutput = %v %v", name, outputs, err)
+		}
+		if revert, err := db.DecodeRevert(calldata("TooLarge(uint256)", []byte{10})); err != nil || revert.String() != "TooLarge(limit: 10)" {
+			t.Errorf("%s: DecodeRevert = %v %v", name, revert, err)
+		}
+	}
+	if err := web3.NewSignatureDB().AddArtifact(strings.NewReader(`{"bytecode":"0x"}`)); err == nil {
+		t.Error("AddArtifact without ABI succeeded")
+	}
+}
+
+func jsonString(s string) string {
+	return `"` + strings.NewReplacer(`"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(s) + `"`
+}
+
+func TestSignatureDBTraces(t *testing.T) {
+	db := web3.DefaultSignatureDB()
+	token := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	to := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	reason := append(crypto.Keccak256([]byte("Error(string)"))[:4], hexutil.MustDecode("0x"+
+		"0000000000000000000000000000000000000000000000000000000000000020"+
+		"0000000000000000000000000000000000000000000000000000000000000004"+
+		"6e6f706500000000000000000000000000000000000000000000000000000000")...)
+	frame := &web3.CallFrame{
+		Type:  "CALL",
+		To:    &token,
+		Input: calldata("transfer(address,uint256)", to.Bytes(), []byte{5}),
+		Error: "execution reverted",
+		Calls: []*web3.CallFrame{
+			{Type: "STATICCALL", To: &token, Input: calldata("balanceOf(address)", to.Bytes()), Output: common.LeftPadBytes([]byte{1}, 32)},
+			{Type: "CALL", To: &to, Value: (*hexutil.Big)(big.NewInt(2)), Input: calldata("unknown()"), Output: reason, Error: "execution reverted"},
+		},
+	}
+	want := "CALL " + token.Hex() + " transfer(to: " + to.Hex() + ", amount: 5) !execution reverted\n" +
+		"  STATICCALL " + token.Hex() + " balanceOf(account: " + to.Hex() + ") -> 0x" + common.Bytes2Hex(common.LeftPadBytes([]byte{1}, 32)) + "\n" +
+		"  CALL " + to.Hex() + " " + common.Bytes2Hex(calldata("unknown()")) + "(0 bytes) value 2 !execution reverted Error(message: \"nope\")\n"
+	if got := db.FormatCallFrame(frame); got != want {
+		t.Errorf("FormatCallFrame =\n%s\nwant\n%s", got, want)
+	}
+	flat := db.FormatFlatTraces(web3.FlattenCallFrame(frame))
+	if lines := strings.Split(strings.TrimSpace(flat), "\n"); len(lines) != 3 || !strings.Contains(lines[0], "transfer(to: ") || !strings.HasPrefix(lines[1], "  STATICCALL") {
+		t.Errorf("FormatFlatTraces =\n%s", flat)
+	}
+
+	tx := &web3.RPCTransaction{To: &token, Input: frame.Input}
+	if call, err := db.DecodeTransaction(tx); err != nil || call.Name != "transfer" {
+		t.Errorf("DecodeTransaction = %v %v", call, err)
+	}
+	if call, err := db.DecodeTransaction(&web3.RPCTransaction{To: &to}); err != nil || call != nil {
+		t.Errorf("DecodeTransaction of a transfer = %v %v, want nil", call, err)
+	}
+}
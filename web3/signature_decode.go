@@ -0,0 +1,440 @@
+package web3
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DecodedArg is a decoded argument of a call, error or event.
+type DecodedArg struct {
+	Name string // empty if the signature has no parameter names
+	Type string
+	// Value is as decoded by go-ethereum's accounts/abi: *big.Int for large
+	// integers, common.Address, [N]byte, anonymous structs for tuples... The
+	// dynamic indexed arguments of events are the common.Hash of the topic.
+	Value   interface{}
+	Indexed bool
+}
+
+// DecodedCall is calldata, or revert data of an error, decoded with a
+// SignatureDB.
+type DecodedCall struct {
+	Name      string
+	Signature string
+	Args      []DecodedArg
+	// Alternatives are the other signatures of the selector that decode the
+	// data, less likely to be the right one.
+	Alternatives []string
+
+	outputs abi.Arguments
+}
+
+// String formats the call as "transfer(to: 0x…, amount: 1000)".
+func (c *DecodedCall) String() string {
+	return formatDecoded(c.Name, c.Args)
+}
+
+// DecodeOutput decodes the return data of the call, when its signature comes
+// from an ABI that has its outputs.
+func (c *DecodedCall) DecodeOutput(output []byte) ([]DecodedArg, error) {
+	if c.outputs == nil {
+		return nil, fmt.Errorf("%w: no outputs known for %s", ErrUnknownSignature, c.Signature)
+	}
+	values, err := c.outputs.Unpack(output)
+	if err != nil {
+		return nil, err
+	}
+	return decodedArgs(c.outputs, values), nil
+}
+
+// DecodedEvent is a log decoded with a SignatureDB.
+type DecodedEvent struct {
+	Name         string
+	Signature    string
+	Args         []DecodedArg
+	Alternatives []string
+}
+
+// String formats the event as "Transfer(from: 0x…, to: 0x…, value: 1000)".
+func (e *DecodedEvent) String() string {
+	return formatDecoded(e.Name, e.Args)
+}
+
+// candidate is an entry that decodes some data.
+type candidate struct {
+	entry *signatureEntry
+	args  []DecodedArg
+	exact bool // the arguments encode back to the same data
+}
+
+// rankEntries orders the entries of a selector or topic by source, the better
+// known first.
+func rankEntries(entries []*signatureEntry) []*signatureEntry {
+	ranked := append([]*signatureEntry(nil), entries...)
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].source > ranked[j].source })
+	return ranked
+}
+
+// best returns the preferred candidate and the signatures of the others.
+func best(candidates []candidate) (candidate, []string) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].exact != candidates[j].exact {
+			return candidates[i].exact
+		}
+		return candidates[i].entry.source > candidates[j].entry.source
+	})
+	var alternatives []string
+	for _, c := range candidates[1:] {
+		if c.entry.signature != candidates[0].entry.signature {
+			alternatives = append(alternatives, c.entry.signature)
+		}
+	}
+	return candidates[0], alternatives
+}
+
+// DecodeCalldata decodes the input of a call to a function known to the
+// database. It returns ErrUnknownSignature if no known function decodes it.
+func (db *SignatureDB) DecodeCalldata(input []byte) (*DecodedCall, error) {
+	return db.decodeSelector(input, signatureFunction)
+}
+
+// DecodeRevert decodes the output of a reverted call: a require message as
+// Error(string), a Panic(uint256) or a custom error known to the database.
+func (db *SignatureDB) DecodeRevert(output []byte) (*DecodedCall, error) {
+	return db.decodeSelector(output, signatureError)
+}
+
+func (db *SignatureDB) decodeSelector(data []byte, kind signatureKind) (*DecodedCall, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%w: %d bytes of data", ErrUnknownSignature, len(data))
+	}
+	var selector [4]byte
+	copy(selector[:], data)
+	db.mu.RLock()
+	entries := db.selectors[selector]
+	db.mu.RUnlock()
+
+	var candidates []candidate
+	for _, entry := range entries {
+		if entry.kind != kind {
+			continue
+		}
+		values, err := entry.inputs.Unpack(data[4:])
+		if err != nil {
+			continue
+		}
+		packed, err := entry.inputs.Pack(values...)
+		candidates = append(candidates, candidate{entry, decodedArgs(entry.inputs, values), err == nil && bytes.Equal(packed, data[4:])})
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: selector %x", ErrUnknownSignature, selector)
+	}
+	c, alternatives := best(candidates)
+	return &DecodedCall{Name: c.entry.name, Signature: c.entry.signature, Args: c.args, Alternatives: alternatives, outputs: c.entry.outputs}, nil
+}
+
+// DecodeTransaction decodes the input of a transaction, such as one of
+// TxPool.Content or Eth.PendingTransactions. Plain transfers and contract
+// creations have no call and return nil.
+func (db *SignatureDB) DecodeTransaction(tx *RPCTransaction) (*DecodedCall, error) {
+	if tx.To == nil || len(tx.Input) == 0 {
+		return nil, nil
+	}
+	return db.DecodeCalldata(tx.Input)
+}
+
+// DecodeLog decodes a log, such as one of Eth.GetLogs, with the events known
+// to the database. Events of the same signature and different indexed
+// parameters are told apart by the number of topics of the log.
+func (db *SignatureDB) DecodeLog(log *types.Log) (*DecodedEvent, error) {
+	if len(log.Topics) == 0 {
+		return nil, fmt.Errorf("%w: log without topics", ErrUnknownSignature)
+	}
+	db.mu.RLock()
+	entries := db.topics[log.Topics[0]]
+	db.mu.RUnlock()
+
+	var candidates []candidate
+	for _, entry := range entries {
+		if args, exact, ok := decodeEvent(entry, log); ok {
+			candidates = append(candidates, candidate{entry, args, exact})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: event topic %v", ErrUnknownSignature, log.Topics[0])
+	}
+	c, alternatives := best(candidates)
+	return &DecodedEvent{Name: c.entry.name, Signature: c.entry.signature, Args: c.args, Alternatives: alternatives}, nil
+}
+
+// DecodeLogs decodes logs with DecodeLog, the events of unknown logs being
+// nil.
+func (db *SignatureDB) DecodeLogs(logs []*types.Log) []*DecodedEvent {
+	events := make([]*DecodedEvent, len(logs))
+	for i, log := range logs {
+		events[i], _ = db.DecodeLog(log)
+	}
+	return events
+}
+
+// decodeEvent decodes a log with an event if its topics match its indexed
+// parameters.
+func decodeEvent(entry *signatureEntry, log *types.Log) ([]DecodedArg, bool, bool) {
+	inputs := entry.inputs
+	topics := log.Topics[1:]
+	if entry.guessIndexed {
+		if len(topics) > len(inputs) {
+			return nil, false, false
+		}
+		inputs = append(abi.Arguments(nil), inputs...)
+		for i := range inputs {
+			inputs[i].Indexed = i < len(topics)
+		}
+	}
+	var indexed, data abi.Arguments
+	for _, input := range inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		} else {
+			data = append(data, input)
+		}
+	}
+	if len(indexed) != len(topics) {
+		return nil, false, false
+	}
+	values, err := data.Unpack(log.Data)
+	if err != nil {
+		return nil, false, false
+	}
+	packed, err := data.Pack(values...)
+	exact := err == nil && bytes.Equal(packed, log.Data) && !entry.guessIndexed
+
+	args := make([]DecodedArg, 0, len(inputs))
+	nextTopic, nextValue := 0, 0
+	for _, input := range inputs {
+		arg := DecodedArg{Name: input.Name, Type: input.Type.String(), Indexed: input.Indexed}
+		if input.Indexed {
+			topic := topics[nextTopic]
+			nextTopic++
+			arg.Value = topic
+			if isStaticElementary(input.Type) {
+				value, err := abi.Arguments{{Type: input.Type}}.Unpack(topic[:])
+				if err != nil {
+					return nil, false, false
+				}
+				arg.Value = value[0]
+			}
+		} else {
+			arg.Value = values[nextValue]
+			nextValue++
+		}
+		args = append(args, arg)
+	}
+	return args, exact, true
+}
+
+// isStaticElementary reports whether indexed arguments of a type are in their
+// topic as such, rather than hashed.
+func isStaticElementary(t abi.Type) bool {
+	switch t.T {
+	case abi.IntTy, abi.UintTy, abi.BoolTy, abi.AddressTy, abi.FixedBytesTy, abi.HashTy:
+		return true
+	}
+	return false
+}
+
+func decodedArgs(arguments abi.Arguments, values []interface{}) []DecodedArg {
+	args := make([]DecodedArg, len(arguments))
+	for i, argument := range arguments {
+		args[i] = DecodedArg{Name: argument.Name, Type: argument.Type.String(), Value: values[i]}
+	}
+	return args
+}
+
+func formatDecoded(name string, args []DecodedArg) string {
+	var b strings.Builder
+	b.WriteString(name + "(")
+	for i, arg := range args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if arg.Name != "" {
+			b.WriteString(arg.Name + ": ")
+		}
+		b.WriteString(FormatABIValue(arg.Value))
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// FormatABIValue formats a value decoded by go-ethereum's accounts/abi the
+// way it is written in Solidity: decimal integers, checksummed addresses,
+// hex bytes, quoted strings, [..] arrays and (..) tuples.
+func FormatABIValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case *big.Int:
+		return v.String()
+	case common.Address:
+		return v.Hex()
+	case common.Hash:
+		return v.Hex()
+	case []byte:
+		return hexutil.Encode(v)
+	case string:
+		return fmt.Sprintf("%q", v)
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Array, reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return hexutil.Encode(b)
+		}
+		items := make([]string, rv.Len())
+		for i := range items {
+			items[i] = FormatABIValue(rv.Index(i).Interface())
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case reflect.Struct:
+		fields := make([]string, rv.NumField())
+		for i := range fields {
+			fields[i] = FormatABIValue(rv.Field(i).Interface())
+		}
+		return "(" + strings.Join(fields, ", ") + ")"
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return "null"
+		}
+		return FormatABIValue(rv.Elem().Interface())
+	}
+	return fmt.Sprint(value)
+}
+
+// FormatCallFrame formats a callTracer frame, such as those of
+// Debug.TraceTransaction, as an indented call tree, one call per line with
+// its input, output and revert reason decoded with the database when known.
+func (db *SignatureDB) FormatCallFrame(frame *CallFrame) string {
+	var b strings.Builder
+	var write func(frame *CallFrame, depth int)
+	write = func(frame *CallFrame, depth int) {
+		var value *big.Int
+		if frame.Value != nil {
+			value = frame.Value.ToInt()
+		}
+		failure, output := frame.Error, []byte(frame.Output)
+		if frame.RevertReason != "" {
+			// decoded by the node already
+			failure, output = failure+": "+frame.RevertReason, nil
+		}
+		b.WriteString(strings.Repeat("  ", depth))
+		b.WriteString(db.formatTraceCall(frame.Type, frame.To, value, frame.Input, output, failure))
+		b.WriteString("\n")
+		for _, call := range frame.Calls {
+			write(call, depth+1)
+		}
+	}
+	write(frame, 0)
+	return b.String()
+}
+
+// FormatFlatTraces formats the flat traces of a transaction, such as those of
+// Trace.Transaction or Debug.TraceTransactionFlat, as FormatCallFrame does.
+func (db *SignatureDB) FormatFlatTraces(traces []*FlatTrace) string {
+	var b strings.Builder
+	for _, trace := range traces {
+		var value *big.Int
+		if trace.Action.Value != nil {
+			value = trace.Action.Value.ToInt()
+		}
+		var output []byte
+		if trace.Result != nil {
+			output = trace.Result.Output
+		}
+		b.WriteString(strings.Repeat("  ", len(trace.TraceAddress)))
+		switch trace.Type {
+		case "call":
+			b.WriteString(db.formatTraceCall(trace.Action.CallType, trace.Action.To, value, trace.Action.Input, output, trace.Error))
+		case "create":
+			var created *common.Address
+			if trace.Result != nil {
+				created = trace.Result.Address
+			}
+			b.WriteString(db.formatTraceCall(trace.Action.CreationMethod, created, value, trace.Action.Init, nil, trace.Error))
+		default:
+			fmt.Fprintf(&b, "%s", strings.ToUpper(trace.Type))
+			if trace.Action.Address != nil {
+				fmt.Fprintf(&b, " %v", trace.Action.Address.Hex())
+			}
+			if trace.Action.RefundAddress != nil {
+				fmt.Fprintf(&b, " refund %v", trace.Action.RefundAddress.Hex())
+			}
+			if trace.Action.Author != nil {
+				fmt.Fprintf(&b, " %s %v", trace.Action.RewardType, trace.Action.Author.Hex())
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// formatTraceCall formats a call of a trace as
+// "CALL 0x… transfer(to: 0x…, amount: 1) value 5 -> (true)".
+func (db *SignatureDB) formatTraceCall(typ string, to *common.Address, value *big.Int, input, output []byte, failure string) string {
+	typ = strings.ToUpper(typ)
+	if typ == "" {
+		typ = "CALL"
+	}
+	var b strings.Builder
+	b.WriteString(typ)
+	if to != nil {
+		b.WriteString(" " + to.Hex())
+	}
+	creation := strings.HasPrefix(typ, "CREATE")
+	var call *DecodedCall
+	switch {
+	case creation:
+		fmt.Fprintf(&b, " (%d bytes of code)", len(input))
+	case len(input) == 0:
+	default:
+		var err error
+		if call, err = db.DecodeCalldata(input); err == nil {
+			b.WriteString(" " + call.String())
+		} else if len(input) >= 4 {
+			fmt.Fprintf(&b, " %x(%d bytes)", input[:4], len(input)-4)
+		} else {
+			fmt.Fprintf(&b, " %x", input)
+		}
+	}
+	if value != nil && value.Sign() > 0 {
+		fmt.Fprintf(&b, " value %v", value)
+	}
+	switch {
+	case failure != "":
+		b.WriteString(" !" + failure)
+		if reason, err := db.DecodeRevert(output); err == nil {
+			b.WriteString(" " + reason.String())
+		}
+	case creation || len(output) == 0:
+	case call != nil:
+		if outputs, err := call.DecodeOutput(output); err == nil {
+			b.WriteString(" -> " + formatDecoded("", outputs))
+			break
+		}
+		fallthrough
+	default:
+		b.WriteString(" -> " + hexutil.Encode(output))
+	}
+	return b.String()
+}
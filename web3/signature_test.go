@@ -0,0 +1,145 @@
+package web3_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+func TestRecoverMessageDevNode(t *testing.T) {
+	node := web3test.New(t)
+	ctx := context.Background()
+	message := []byte("hello web3-go")
+
+	for name, sign := range map[string]func() (hexutil.Bytes, error){
+		"eth_sign": func() (hexutil.Bytes, error) {
+			return node.Web3.Eth.Sign(ctx, node.Account, message)
+		},
+		"personal_sign": func() (hexutil.Bytes, error) {
+			return node.Web3.Personal.Sign(ctx, message, node.Account, web3test.Password)
+		},
+	} {
+		sig, err := sign()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if signer, err := web3.RecoverMessage(message, sig); err != nil || signer != node.Account {
+			t.Errorf("RecoverMessage of %s = %v %v, want %v", name, signer, err, node.Account)
+		}
+		sig[64] -= 27
+		if signer, err := web3.RecoverMessage(message, sig); err != nil || signer != node.Account {
+			t.Errorf("RecoverMessage of %s with v of 0 or 1 = %v %v, want %v", name, signer, err, node.Account)
+		}
+	}
+
+	local, err := web3.SignMessage(node.Key, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer, err := node.Web3.Personal.EcRecover(ctx, message, local); err != nil || signer != node.Account {
+		t.Errorf("EcRecover of SignMessage = %v %v, want %v", signer, err, node.Account)
+	}
+	// the malleable twin of a signature recovers the same key with ecrecover
+	n := crypto.S256().Params().N
+	twin := common.CopyBytes(local)
+	new(big.Int).Sub(n, new(big.Int).SetBytes(local[32:64])).FillBytes(twin[32:64])
+	twin[64] ^= 1
+	if signer, err := web3.RecoverMessage(message, twin); err == nil {
+		t.Errorf("RecoverMessage accepted a high s value, signer %v", signer)
+	}
+	local[64] = 29
+	if _, err := web3.RecoverMessage(message, local); err == nil {
+		t.Error("RecoverMessage accepted a v of 29")
+	}
+	if _, err := web3.RecoverMessage(message, local[:64]); err == nil {
+		t.Error("RecoverMessage accepted a 64-byte signature")
+	}
+}
+
+// walletService is a stub node whose only contract is an EIP-1271 wallet
+// accepting the signatures of its owner.
+type walletService struct {
+	wallet common.Address
+	owner  common.Address
+	calls  int
+}
+
+type revertError struct{}
+
+func (revertError) Error() string  { return "execution reverted" }
+func (revertError) ErrorCode() int { return 3 }
+
+func (s *walletService) Call(args map[string]json.RawMessage, block, overrides, blockOverrides *json.RawMessage) (hexutil.Bytes, error) {
+	s.calls++
+	var to common.Address
+	var input hexutil.Bytes
+	json.Unmarshal(args["to"], &to)
+	json.Unmarshal(args["input"], &input)
+	if to != s.wallet {
+		return hexutil.Bytes{}, nil
+	}
+	if len(input) < 4+3*32 || !bytes.Equal(input[:4], []byte{0x16, 0x26, 0xba, 0x7e}) {
+		return nil, revertError{}
+	}
+	hash, sig := input[4:36], input[4+3*32:]
+	if new(big.Int).SetBytes(input[4+2*32:4+3*32]).Uint64() != 65 || len(sig) < 65 {
+		return nil, revertError{}
+	}
+	pub, err := crypto.SigToPub(hash, append(common.CopyBytes(sig[:64]), sig[64]-27))
+	if err != nil || crypto.PubkeyToAddress(*pub) != s.owner {
+		return common.LeftPadBytes(nil, 32), nil
+	}
+	return common.RightPadBytes([]byte{0x16, 0x26, 0xba, 0x7e}, 32), nil
+}
+
+func TestVerifySignatureContractWallet(t *testing.T) {
+	owner, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	service := &walletService{wallet: common.HexToAddress("0x00000000000000000000000000000000000000aa"), owner: crypto.PubkeyToAddress(owner.PublicKey)}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", service); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	client := rpc.DialInProc(server)
+	t.Cleanup(client.Close)
+	w := web3.NewWeb3(client)
+	ctx := context.Background()
+
+	message := []byte("pay 1 ETH")
+	hash := common.BytesToHash(accounts.TextHash(message))
+	sig, _ := web3.SignMessage(owner, message)
+	forged, _ := web3.SignMessage(other, message)
+
+	if ok, err := w.Eth.IsValidSignature(ctx, service.wallet, hash, sig, nil); err != nil || !ok {
+		t.Errorf("IsValidSignature of the owner = %v %v, want true", ok, err)
+	}
+	if ok, err := w.Eth.IsValidSignature(ctx, service.wallet, hash, forged, nil); err != nil || ok {
+		t.Errorf("IsValidSignature of another key = %v %v, want false", ok, err)
+	}
+	if ok, err := w.Eth.IsValidSignature(ctx, service.wallet, hash, sig[:10], nil); err != nil || ok {
+		t.Errorf("IsValidSignature of a reverting call = %v %v, want false", ok, err)
+	}
+	if ok, err := w.Eth.VerifyMessage(ctx, service.wallet, message, sig); err != nil || !ok {
+		t.Errorf("VerifyMessage for the wallet = %v %v, want true", ok, err)
+	}
+
+	calls := service.calls
+	if ok, err := w.Eth.VerifyMessage(ctx, crypto.PubkeyToAddress(other.PublicKey), message, forged); err != nil || !ok || service.calls != calls {
+		t.Errorf("VerifyMessage for an account = %v %v after %d calls, want true without calls", ok, err, service.calls-calls)
+	}
+	if ok, err := w.Eth.VerifyMessage(ctx, crypto.PubkeyToAddress(other.PublicKey), message, sig); err != nil || ok {
+		t.Errorf("VerifyMessage of another account = %v %v, want false", ok, err)
+	}
+}
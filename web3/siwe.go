@@ -0,0 +1,308 @@
+package web3
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Errors of SiweMessage.Validate and of the SIWE verification functions.
+var (
+	ErrSiweDomain       = errors.New("siwe: domain mismatch")
+	ErrSiweNonce        = errors.New("siwe: nonce mismatch")
+	ErrSiweChainID      = errors.New("siwe: chain id mismatch")
+	ErrSiweExpired      = errors.New("siwe: message expired")
+	ErrSiweNotYetValid  = errors.New("siwe: message not yet valid")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+const siwePreamble = " wants you to sign in with your Ethereum account:"
+
+// SiweMessage is an EIP-4361 Sign-In with Ethereum message.
+type SiweMessage struct {
+	Scheme         string // optional, such as "https"
+	Domain         string
+	Address        common.Address
+	Statement      string // optional
+	URI            string
+	Version        string
+	ChainID        uint64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string // optional
+	Resources      []string
+}
+
+// NewSiweNonce returns a random nonce of 16 alphanumeric characters.
+func NewSiweNonce() (string, error) {
+	const alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	nonce := make([]byte, 16)
+	for i := range nonce {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+		nonce[i] = alphabet[n.Int64()]
+	}
+	return string(nonce), nil
+}
+
+// siweLines reads the lines of a message one tagged field at a time.
+type siweLines struct {
+	lines []string
+	next  int
+}
+
+// field returns the value of the next line if it starts with tag.
+func (l *siweLines) field(tag string) (string, bool) {
+	if l.next < len(l.lines) && strings.HasPrefix(l.lines[l.next], tag) {
+		l.next++
+		return l.lines[l.next-1][len(tag):], true
+	}
+	return "", false
+}
+
+func (l *siweLines) required(tag string) (string, error) {
+	value, ok := l.field(tag)
+	if !ok {
+		return "", fmt.Errorf("siwe: missing %q line", strings.TrimSuffix(tag, ": "))
+	}
+	return value, nil
+}
+
+func (l *siweLines) time(tag string) (*time.Time, error) {
+	value, ok := l.field(tag)
+	if !ok {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, fmt.Errorf("siwe: invalid %s: %w", strings.TrimSuffix(tag, ": "), err)
+	}
+	return &t, nil
+}
+
+// ParseSiweMessage parses an EIP-4361 message. The address must be EIP-55
+// checksummed and the nonce at least 8 alphanumeric characters long.
+func ParseSiweMessage(message string) (*SiweMessage, error) {
+	l := &siweLines{lines: strings.Split(message, "\n")}
+	m := &SiweMessage{}
+
+	header := l.lines[0]
+	if !strings.HasSuffix(header, siwePreamble) {
+		return nil, errors.New("siwe: missing preamble")
+	}
+	l.next++
+	m.Domain = strings.TrimSuffix(header, siwePreamble)
+	if scheme, domain, ok := strings.Cut(m.Domain, "://"); ok {
+		m.Scheme, m.Domain = scheme, domain
+	}
+	if m.Domain == "" || strings.ContainsAny(m.Domain, " /") {
+		return nil, fmt.Errorf("siwe: invalid domain %q", m.Domain)
+	}
+
+	address, ok := l.field("")
+	if !ok || !common.IsHexAddress(address) || common.HexToAddress(address).Hex() != address {
+		return nil, fmt.Errorf("siwe: invalid address %q, want it EIP-55 checksummed", address)
+	}
+	m.Address = common.HexToAddress(address)
+
+	// the statement is optional and surrounded by empty lines
+	for l.next < len(l.lines) && l.lines[l.next] == "" {
+		l.next++
+	}
+	if l.next < len(l.lines) && !strings.HasPrefix(l.lines[l.next], "URI: ") {
+		m.Statement = l.lines[l.next]
+		l.next++
+		if empty, ok := l.field(""); !ok || empty != "" {
+			return nil, errors.New("siwe: statement spans several lines")
+		}
+	}
+
+	var err error
+	if m.URI, err = l.required("URI: "); err != nil {
+		return nil, err
+	}
+	if m.Version, err = l.required("Version: "); err != nil {
+		return nil, err
+	}
+	if m.Version != "1" {
+		return nil, fmt.Errorf("siwe: unsupported version %q", m.Version)
+	}
+	chainID, err := l.required("Chain ID: ")
+	if err != nil {
+		return nil, err
+	}
+	if m.ChainID, err = strconv.ParseUint(chainID, 10, 64); err != nil {
+		return nil, fmt.Errorf("siwe: invalid chain id %q", chainID)
+	}
+	if m.Nonce, err = l.required("Nonce: "); err != nil {
+		return nil, err
+	}
+	if !validSiweNonce(m.Nonce) {
+		return nil, fmt.Errorf("siwe: invalid nonce %q", m.Nonce)
+	}
+	issuedAt, err := l.time("Issued At: ")
+	if err != nil {
+		return nil, err
+	}
+	if issuedAt == nil {
+		return nil, errors.New(`siwe: missing "Issued At" line`)
+	}
+	m.IssuedAt = *issuedAt
+	if m.ExpirationTime, err = l.time("Expiration Time: "); err != nil {
+		return nil, err
+	}
+	if m.NotBefore, err = l.time("Not Before: "); err != nil {
+		return nil, err
+	}
+	m.RequestID, _ = l.field("Request ID: ")
+	if _, ok := l.field("Resources:"); ok {
+		for {
+			resource, ok := l.field("- ")
+			if !ok {
+				break
+			}
+			m.Resources = append(m.Resources, resource)
+		}
+	}
+	if l.next < len(l.lines) {
+		return nil, fmt.Errorf("siwe: unexpected line %q", l.lines[l.next])
+	}
+	return m, nil
+}
+
+func validSiweNonce(nonce string) bool {
+	if len(nonce) < 8 {
+		return false
+	}
+	for _, c := range nonce {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the message in the form that is signed. Times are formatted
+// with RFC 3339, so they may differ from those of a parsed message: verify the
+// signature of the text that was signed.
+func (m *SiweMessage) String() string {
+	var b strings.Builder
+	if m.Scheme != "" {
+		b.WriteString(m.Scheme + "://")
+	}
+	b.WriteString(m.Domain + siwePreamble + "\n")
+	b.WriteString(m.Address.Hex() + "\n\n")
+	if m.Statement != "" {
+		b.WriteString(m.Statement + "\n")
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "URI: %s\nVersion: %s\nChain ID: %d\nNonce: %s\nIssued At: %s", m.URI, m.Version, m.ChainID, m.Nonce, m.IssuedAt.Format(time.RFC3339Nano))
+	if m.ExpirationTime != nil {
+		b.WriteString("\nExpiration Time: " + m.ExpirationTime.Format(time.RFC3339Nano))
+	}
+	if m.NotBefore != nil {
+		b.WriteString("\nNot Before: " + m.NotBefore.Format(time.RFC3339Nano))
+	}
+	if m.RequestID != "" {
+		b.WriteString("\nRequest ID: " + m.RequestID)
+	}
+	if len(m.Resources) > 0 {
+		b.WriteString("\nResources:")
+		for _, resource := range m.Resources {
+			b.WriteString("\n- " + resource)
+		}
+	}
+	return b.String()
+}
+
+// SiweVerifyOptions are what a SIWE message is checked against. Domain and
+// Nonce are required, a zero ChainID is not checked and Time defaults to now.
+type SiweVerifyOptions struct {
+	Domain  string
+	Nonce   string
+	ChainID uint64
+	Time    time.Time
+}
+
+// Validate checks the domain, nonce, chain ID and validity period of the
+// message, but not its signature.
+func (m *SiweMessage) Validate(opts SiweVerifyOptions) error {
+	if opts.Domain == "" {
+		return fmt.Errorf("%w: no domain to check against", ErrSiweDomain)
+	}
+	if opts.Domain != m.Domain {
+		return fmt.Errorf("%w: %q, want %q", ErrSiweDomain, m.Domain, opts.Domain)
+	}
+	if opts.Nonce == "" {
+		return fmt.Errorf("%w: no nonce to check against", ErrSiweNonce)
+	}
+	if opts.Nonce != m.Nonce {
+		return ErrSiweNonce
+	}
+	if opts.ChainID != 0 && opts.ChainID != m.ChainID {
+		return fmt.Errorf("%w: %d, want %d", ErrSiweChainID, m.ChainID, opts.ChainID)
+	}
+	now := opts.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if m.ExpirationTime != nil && !now.Before(*m.ExpirationTime) {
+		return ErrSiweExpired
+	}
+	if m.NotBefore != nil && now.Before(*m.NotBefore) {
+		return ErrSiweNotYetValid
+	}
+	return nil
+}
+
+// VerifySiweMessage parses and validates a SIWE message and checks that it
+// was signed by its address with personal_sign. Only account signatures are
+// verified, Eth.VerifySiweMessage also accepts contract wallets.
+func VerifySiweMessage(message string, sig []byte, opts SiweVerifyOptions) (*SiweMessage, error) {
+	m, err := ParseSiweMessage(message)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Validate(opts); err != nil {
+		return nil, err
+	}
+	signer, err := RecoverMessage([]byte(message), sig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if signer != m.Address {
+		return nil, fmt.Errorf("%w: signed by %v", ErrInvalidSignature, signer)
+	}
+	return m, nil
+}
+
+// VerifySiweMessage is VerifySiweMessage for accounts and contract wallets,
+// the latter being asked with EIP-1271 isValidSignature.
+func (e *Eth) VerifySiweMessage(ctx context.Context, message string, sig []byte, opts SiweVerifyOptions) (*SiweMessage, error) {
+	m, err := ParseSiweMessage(message)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Validate(opts); err != nil {
+		return nil, err
+	}
+	valid, err := e.VerifyMessage(ctx, m.Address, []byte(message), sig)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrInvalidSignature
+	}
+	return m, nil
+}
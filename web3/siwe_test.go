@@ -0,0 +1,155 @@
+package web3_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+)
+
+// siweExample is the example message of EIP-4361.
+const siweExample = `service.org wants you to sign in with your Ethereum account:
+0xe5A12547fe4E872D192E3eCecb76F2Ce1aeA4946
+
+I accept the ServiceOrg Terms of Service: https://service.org/tos
+
+URI: https://service.org/login
+Version: 1
+Chain ID: 1
+Nonce: 32891757
+Issued At: 2021-09-30T16:25:24Z
+Expiration Time: 2021-10-30T16:25:24Z
+Resources:
+- ipfs://bafybeiemxf5abjwjbikoz4mc3a3dla6ual3jsgpdr4cjr3oz3evfyavhwq/
+- https://example.com/my-web2-claim.json`
+
+func TestParseSiweMessage(t *testing.T) {
+	m, err := web3.ParseSiweMessage(siweExample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Domain != "service.org" || m.Address != common.HexToAddress("0xe5A12547fe4E872D192E3eCecb76F2Ce1aeA4946") ||
+		m.Statement != "I accept the ServiceOrg Terms of Service: https://service.org/tos" || m.ChainID != 1 ||
+		m.Nonce != "32891757" || m.ExpirationTime == nil || m.NotBefore != nil || len(m.Resources) != 2 {
+		t.Errorf("ParseSiweMessage = %+v", m)
+	}
+	if m.String() != siweExample {
+		t.Errorf("String =\n%s\nwant\n%s", m.String(), siweExample)
+	}
+
+	// without a statement nor resources, with a scheme and a request id
+	m.Scheme, m.Statement, m.Resources, m.RequestID = "https", "", nil, "42"
+	again, err := web3.ParseSiweMessage(m.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != m.String() || again.Scheme != "https" || again.Statement != "" || again.RequestID != "42" {
+		t.Errorf("ParseSiweMessage(%q) = %+v", m.String(), again)
+	}
+
+	for name, message := range map[string]string{
+		"lowercase address": strings.Replace(siweExample, "0xe5A12547fe4E872D192E3eCecb76F2Ce1aeA4946", "0xe5a12547fe4e872d192e3ececb76f2ce1aea4946", 1),
+		"short nonce":       strings.Replace(siweExample, "Nonce: 32891757", "Nonce: 1234", 1),
+		"version":           strings.Replace(siweExample, "Version: 1", "Version: 2", 1),
+		"missing uri":       strings.Replace(siweExample, "URI: https://service.org/login\n", "", 1),
+		"time":              strings.Replace(siweExample, "2021-09-30T16:25:24Z", "yesterday", 1),
+		"preamble":          strings.Replace(siweExample, "wants you", "would like you", 1),
+		"trailing line":     siweExample + "\nextra",
+	} {
+		if _, err := web3.ParseSiweMessage(message); err == nil {
+			t.Errorf("ParseSiweMessage with a bad %s succeeded", name)
+		}
+	}
+}
+
+func TestSiweValidate(t *testing.T) {
+	m, err := web3.ParseSiweMessage(siweExample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	during := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	notBefore := during.Add(time.Hour)
+	for _, test := range []struct {
+		opts      web3.SiweVerifyOptions
+		notBefore *time.Time
+		want      error
+	}{
+		{opts: web3.SiweVerifyOptions{Domain: "service.org", Nonce: "32891757", ChainID: 1, Time: during}},
+		{opts: web3.SiweVerifyOptions{Domain: "service.org", Nonce: "32891757", Time: during}},
+		{opts: web3.SiweVerifyOptions{Domain: "evil.org", Nonce: "32891757", Time: during}, want: web3.ErrSiweDomain},
+		{opts: web3.SiweVerifyOptions{Nonce: "32891757", Time: during}, want: web3.ErrSiweDomain},
+		{opts: web3.SiweVerifyOptions{Domain: "service.org", Nonce: "00000000", Time: during}, want: web3.ErrSiweNonce},
+		{opts: web3.SiweVerifyOptions{Domain: "service.org", Time: during}, want: web3.ErrSiweNonce},
+		{opts: web3.SiweVerifyOptions{Domain: "service.org", Nonce: "32891757", ChainID: 5, Time: during}, want: web3.ErrSiweChainID},
+		{opts: web3.SiweVerifyOptions{Domain: "service.org", Nonce: "32891757"}, want: web3.ErrSiweExpired},
+		{opts: web3.SiweVerifyOptions{Domain: "service.org", Nonce: "32891757", Time: during}, notBefore: &notBefore, want: web3.ErrSiweNotYetValid},
+	} {
+		m.NotBefore = test.notBefore
+		if err := m.Validate(test.opts); !errors.Is(err, test.want) {
+			t.Errorf("Validate(%+v) = %v, want %v", test.opts, err, test.want)
+		}
+	}
+}
+
+func TestVerifySiweMessage(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	nonce, err := web3.NewSiweNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued := time.Now().UTC().Truncate(time.Second)
+	m := &web3.SiweMessage{
+		Domain:    "example.com",
+		Address:   crypto.PubkeyToAddress(key.PublicKey),
+		Statement: "Sign in",
+		URI:       "https://example.com",
+		Version:   "1",
+		ChainID:   1,
+		Nonce:     nonce,
+		IssuedAt:  issued,
+	}
+	message := m.String()
+	sig, _ := web3.SignMessage(key, []byte(message))
+	opts := web3.SiweVerifyOptions{Domain: "example.com", Nonce: nonce, ChainID: 1}
+
+	if verified, err := web3.VerifySiweMessage(message, sig, opts); err != nil || verified.Address != m.Address {
+		t.Errorf("VerifySiweMessage = %+v %v", verified, err)
+	}
+	other, _ := crypto.GenerateKey()
+	forged, _ := web3.SignMessage(other, []byte(message))
+	if _, err := web3.VerifySiweMessage(message, forged, opts); !errors.Is(err, web3.ErrInvalidSignature) {
+		t.Errorf("VerifySiweMessage of another key = %v, want %v", err, web3.ErrInvalidSignature)
+	}
+
+	// a contract wallet owned by key signs in through EIP-1271
+	service := &walletService{wallet: common.HexToAddress("0x00000000000000000000000000000000000000aa"), owner: m.Address}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", service); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	client := rpc.DialInProc(server)
+	t.Cleanup(client.Close)
+	w := web3.NewWeb3(client)
+
+	m.Address = service.wallet
+	message = m.String()
+	sig, _ = web3.SignMessage(key, []byte(message))
+	if _, err := web3.VerifySiweMessage(message, sig, opts); !errors.Is(err, web3.ErrInvalidSignature) {
+		t.Errorf("VerifySiweMessage of a contract wallet = %v, want %v", err, web3.ErrInvalidSignature)
+	}
+	if verified, err := w.Eth.VerifySiweMessage(context.Background(), message, sig, opts); err != nil || verified.Address != service.wallet {
+		t.Errorf("Eth.VerifySiweMessage of a contract wallet = %+v %v", verified, err)
+	}
+	forged, _ = web3.SignMessage(other, []byte(message))
+	if _, err := w.Eth.VerifySiweMessage(context.Background(), message, forged, opts); !errors.Is(err, web3.ErrInvalidSignature) {
+		t.Errorf("Eth.VerifySiweMessage of another key = %v, want %v", err, web3.ErrInvalidSignature)
+	}
+}
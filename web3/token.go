@@ -0,0 +1,247 @@
+package web3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrInvalidAmount is returned by ParseUnits for malformed amounts.
+var ErrInvalidAmount = errors.New("invalid token amount")
+
+// Selectors of the ERC-20 functions.
+var (
+	tokenNameSelector        = []byte{0x06, 0xfd, 0xde, 0x03} // name()
+	tokenSymbolSelector      = []byte{0x95, 0xd8, 0x9b, 0x41} // symbol()
+	tokenDecimalsSelector    = []byte{0x31, 0x3c, 0xe5, 0x67} // decimals()
+	tokenTotalSupplySelector = []byte{0x18, 0x16, 0x0d, 0xdd} // totalSupply()
+	tokenBalanceOfSelector   = []byte{0x70, 0xa0, 0x82, 0x31} // balanceOf(address)
+	tokenAllowanceSelector   = []byte{0xdd, 0x62, 0xed, 0x3e} // allowance(address,address)
+)
+
+var tokenStringArgs abi.Arguments
+
+func init() {
+	typ, err := abi.NewType("string", "", nil)
+	if err != nil {
+		panic(err)
+	}
+	tokenStringArgs = abi.Arguments{{Type: typ}}
+}
+
+// TokensConfig configures a Tokens client. Zero values get defaults.
+type TokensConfig struct {
+	// Block is the block token state is read at, the latest by default.
+	Block *rpc.BlockNumberOrHash
+	// LogRange is the number of blocks of every eth_getLogs request of
+	// Transfers, 2000 by default. It is halved for the ranges a node refuses.
+	LogRange uint64
+}
+
+// Tokens reads ERC-20, ERC-721 and ERC-1155 tokens through Eth.Call and
+// Eth.GetLogs.
+type Tokens struct {
+	eth    *Eth
+	config TokensConfig
+}
+
+func NewTokens(w *Web3, config TokensConfig) *Tokens {
+	if config.LogRange == 0 {
+		config.LogRange = 2000
+	}
+	t := &Tokens{}
+	t.eth = w.Eth
+	t.config = config
+	return t
+}
+
+// call calls token with the selector followed by the 32 byte words args.
+func (t *Tokens) call(ctx context.Context, token common.Address, selector []byte, args ...[]byte) ([]byte, error) {
+	input := common.CopyBytes(selector)
+	for _, arg := range args {
+		input = append(input, common.LeftPadBytes(arg, 32)...)
+	}
+	data := hexutil.Bytes(input)
+	return t.eth.Call(ctx, TransactionArgs{To: &token, Input: &data}, t.config.Block, nil, nil)
+}
+
+// callWord calls token and returns the first word of its result.
+func (t *Tokens) callWord(ctx context.Context, token common.Address, selector []byte, args ...[]byte) ([]byte, error) {
+	result, err := t.call(ctx, token, selector, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) < 32 {
+		return nil, fmt.Errorf("%s returned %d bytes", token.Hex(), len(result))
+	}
+	return result[:32], nil
+}
+
+func (t *Tokens) callUint(ctx context.Context, token common.Address, selector []byte, args ...[]byte) (*big.Int, error) {
+	word, err := t.callWord(ctx, token, selector, args...)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(word), nil
+}
+
+// TokenMetadata is the ERC-20 metadata of a token.
+type TokenMetadata struct {
+	Name     string
+	Symbol   string
+	Decimals uint8
+}
+
+// Format formats amount with the decimals and symbol of the token.
+func (m *TokenMetadata) Format(amount *big.Int) string {
+	if m.Symbol == "" {
+		return FormatUnits(amount, m.Decimals)
+	}
+	return FormatUnits(amount, m.Decimals) + " " + m.Symbol
+}
+
+// Metadata returns the name, symbol and decimals of a token. They are
+// optional in ERC-20, so the ones the token does not implement are left
+// empty. Name and symbol may be returned as strings or, by older tokens such
+// as MKR, as bytes32.
+func (t *Tokens) Metadata(ctx context.Context, token common.Address) (*TokenMetadata, error) {
+	metadata := &TokenMetadata{}
+	var err error
+	if metadata.Name, err = t.optionalString(ctx, token, tokenNameSelector); err != nil {
+		return nil, err
+	}
+	if metadata.Symbol, err = t.optionalString(ctx, token, tokenSymbolSelector); err != nil {
+		return nil, err
+	}
+	result, err := t.call(ctx, token, tokenDecimalsSelector)
+	if err != nil && !errors.Is(err, ErrExecutionReverted) {
+		return nil, err
+	}
+	if len(result) >= 32 {
+		if metadata.Decimals, err = decodeDecimals(token, result[:32]); err != nil {
+			return nil, err
+		}
+	}
+	return metadata, nil
+}
+
+func (t *Tokens) optionalString(ctx context.Context, token common.Address, selector []byte) (string, error) {
+	result, err := t.call(ctx, token, selector)
+	if errors.Is(err, ErrExecutionReverted) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return decodeTokenString(result)
+}
+
+// decodeTokenString decodes a string result, or a bytes32 one padded with
+// zeros.
+func decodeTokenString(result []byte) (string, error) {
+	switch {
+	case len(result) == 0:
+		return "", nil
+	case len(result) == 32:
+		return string(bytes.TrimRight(result, "\x00")), nil
+	}
+	values, err := tokenStringArgs.Unpack(result)
+	if err != nil {
+		return "", fmt.Errorf("invalid string: %w", err)
+	}
+	return values[0].(string), nil
+}
+
+// Decimals returns the decimals of an ERC-20 token.
+func (t *Tokens) Decimals(ctx context.Context, token common.Address) (uint8, error) {
+	word, err := t.callWord(ctx, token, tokenDecimalsSelector)
+	if err != nil {
+		return 0, err
+	}
+	return decodeDecimals(token, word)
+}
+
+func decodeDecimals(token common.Address, word []byte) (uint8, error) {
+	decimals := new(big.Int).SetBytes(word)
+	if !decimals.IsUint64() || decimals.Uint64() > 255 {
+		return 0, fmt.Errorf("%s returned %v decimals", token.Hex(), decimals)
+	}
+	return uint8(decimals.Uint64()), nil
+}
+
+// TotalSupply returns the total supply of an ERC-20 token.
+func (t *Tokens) TotalSupply(ctx context.Context, token common.Address) (*big.Int, error) {
+	return t.callUint(ctx, token, tokenTotalSupplySelector)
+}
+
+// BalanceOf returns the ERC-20 balance of owner, or its number of ERC-721
+// tokens.
+func (t *Tokens) BalanceOf(ctx context.Context, token, owner common.Address) (*big.Int, error) {
+	return t.callUint(ctx, token, tokenBalanceOfSelector, owner.Bytes())
+}
+
+// Allowance returns the amount of ERC-20 tokens of owner spender may
+// transfer.
+func (t *Tokens) Allowance(ctx context.Context, token, owner, spender common.Address) (*big.Int, error) {
+	return t.callUint(ctx, token, tokenAllowanceSelector, owner.Bytes(), spender.Bytes())
+}
+
+// FormatUnits formats an amount of the smallest unit of a token as a decimal
+// of its whole units, without trailing zeros: 1500000 with 6 decimals is
+// "1.5".
+func FormatUnits(amount *big.Int, decimals uint8) string {
+	if amount == nil {
+		return "0"
+	}
+	digits := new(big.Int).Abs(amount).String()
+	sign := ""
+	if amount.Sign() < 0 {
+		sign = "-"
+	}
+	if decimals == 0 {
+		return sign + digits
+	}
+	if len(digits) <= int(decimals) {
+		digits = strings.Repeat("0", int(decimals)-len(digits)+1) + digits
+	}
+	whole, fraction := digits[:len(digits)-int(decimals)], strings.TrimRight(digits[len(digits)-int(decimals):], "0")
+	if fraction == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + fraction
+}
+
+// ParseUnits parses a decimal amount of whole units, such as "1.5", to the
+// smallest unit of a token with decimals. Amounts with more fractional digits
+// than decimals are rejected.
+func ParseUnits(s string, decimals uint8) (*big.Int, error) {
+	value := strings.TrimSpace(s)
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+	whole, fraction, _ := strings.Cut(value, ".")
+	if whole == "" && fraction == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if len(fraction) > int(decimals) {
+		return nil, fmt.Errorf("%w: %q has more than %d decimals", ErrInvalidAmount, s, decimals)
+	}
+	digits := whole + fraction + strings.Repeat("0", int(decimals)-len(fraction))
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+		}
+	}
+	amount, _ := new(big.Int).SetString(digits, 10)
+	if negative {
+		amount.Neg(amount)
+	}
+	return amount, nil
+}
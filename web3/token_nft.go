@@ -0,0 +1,185 @@
+package web3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ERC-165 interface IDs of the token standards.
+var (
+	InterfaceERC165             = [4]byte{0x01, 0xff, 0xc9, 0xa7}
+	InterfaceERC721             = [4]byte{0x80, 0xac, 0x58, 0xcd}
+	InterfaceERC721Metadata     = [4]byte{0x5b, 0x5e, 0x13, 0x9f}
+	InterfaceERC1155            = [4]byte{0xd9, 0xb6, 0x7a, 0x26}
+	InterfaceERC1155MetadataURI = [4]byte{0x0e, 0x89, 0x34, 0x1c}
+)
+
+// Selectors of the ERC-721 and ERC-1155 functions.
+var (
+	tokenOwnerOfSelector        = []byte{0x63, 0x52, 0x21, 0x1e} // ownerOf(uint256)
+	tokenURISelector            = []byte{0xc8, 0x7b, 0x56, 0xdd} // tokenURI(uint256)
+	tokenBalanceOfIDSelector    = []byte{0x00, 0xfd, 0xd5, 0x8e} // balanceOf(address,uint256)
+	tokenBalanceOfBatchSelector = []byte{0x4e, 0x12, 0x73, 0xf4} // balanceOfBatch(address[],uint256[])
+	tokenURI1155Selector        = []byte{0x0e, 0x89, 0x34, 0x1c} // uri(uint256)
+)
+
+var (
+	tokenBatchArgs    abi.Arguments
+	tokenUint256sArgs abi.Arguments
+)
+
+func init() {
+	arg := func(t string) abi.Argument {
+		typ, err := abi.NewType(t, "", nil)
+		if err != nil {
+			panic(err)
+		}
+		return abi.Argument{Type: typ}
+	}
+	tokenBatchArgs = abi.Arguments{arg("address[]"), arg("uint256[]")}
+	tokenUint256sArgs = abi.Arguments{arg("uint256[]")}
+}
+
+// TokenStandard is the standard a token implements.
+type TokenStandard string
+
+const (
+	TokenUnknown TokenStandard = ""
+	TokenERC20   TokenStandard = "ERC-20"
+	TokenERC721  TokenStandard = "ERC-721"
+	TokenERC1155 TokenStandard = "ERC-1155"
+)
+
+// supportsInterface calls supportsInterface of token, a revert or a short
+// result meaning false.
+func (t *Tokens) supportsInterface(ctx context.Context, token common.Address, id [4]byte) (bool, error) {
+	result, err := t.call(ctx, token, erc165Selector, common.RightPadBytes(id[:], 32))
+	if errors.Is(err, ErrExecutionReverted) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(result) >= 32 && result[31] == 1, nil
+}
+
+// SupportsInterface reports whether token implements an interface, following
+// the ERC-165 detection: the token must first claim ERC-165 and deny the
+// invalid 0xffffffff interface.
+func (t *Tokens) SupportsInterface(ctx context.Context, token common.Address, id [4]byte) (bool, error) {
+	if ok, err := t.supportsInterface(ctx, token, InterfaceERC165); err != nil || !ok {
+		return false, err
+	}
+	if ok, err := t.supportsInterface(ctx, token, [4]byte{0xff, 0xff, 0xff, 0xff}); err != nil || ok {
+		return false, err
+	}
+	if id == InterfaceERC165 {
+		return true, nil
+	}
+	return t.supportsInterface(ctx, token, id)
+}
+
+// DetectStandard returns the standard of token: ERC-721 or ERC-1155 if it
+// claims their interface, ERC-20, which predates ERC-165, if it answers
+// totalSupply, TokenUnknown otherwise.
+func (t *Tokens) DetectStandard(ctx context.Context, token common.Address) (TokenStandard, error) {
+	erc165, err := t.SupportsInterface(ctx, token, InterfaceERC165)
+	if err != nil {
+		return TokenUnknown, err
+	}
+	if erc165 {
+		for _, standard := range []struct {
+			id       [4]byte
+			standard TokenStandard
+		}{{InterfaceERC721, TokenERC721}, {InterfaceERC1155, TokenERC1155}} {
+			ok, err := t.supportsInterface(ctx, token, standard.id)
+			if err != nil {
+				return TokenUnknown, err
+			}
+			if ok {
+				return standard.standard, nil
+			}
+		}
+	}
+	result, err := t.call(ctx, token, tokenTotalSupplySelector)
+	if errors.Is(err, ErrExecutionReverted) {
+		return TokenUnknown, nil
+	}
+	if err != nil {
+		return TokenUnknown, err
+	}
+	if len(result) != 32 {
+		return TokenUnknown, nil
+	}
+	return TokenERC20, nil
+}
+
+// OwnerOf returns the owner of an ERC-721 token.
+func (t *Tokens) OwnerOf(ctx context.Context, token common.Address, id *big.Int) (common.Address, error) {
+	word, err := t.callWord(ctx, token, tokenOwnerOfSelector, id.Bytes())
+	if err != nil {
+		return common.Address{}, err
+	}
+	return common.BytesToAddress(word), nil
+}
+
+// TokenURI returns the ERC-721 metadata URI of a token.
+func (t *Tokens) TokenURI(ctx context.Context, token common.Address, id *big.Int) (string, error) {
+	result, err := t.call(ctx, token, tokenURISelector, id.Bytes())
+	if err != nil {
+		return "", err
+	}
+	return unpackString(result)
+}
+
+// BalanceOfID returns the ERC-1155 balance of owner of the token id.
+func (t *Tokens) BalanceOfID(ctx context.Context, token, owner common.Address, id *big.Int) (*big.Int, error) {
+	return t.callUint(ctx, token, tokenBalanceOfIDSelector, owner.Bytes(), id.Bytes())
+}
+
+// BalanceOfBatch returns the ERC-1155 balances of owners[i] of the token
+// ids[i].
+func (t *Tokens) BalanceOfBatch(ctx context.Context, token common.Address, owners []common.Address, ids []*big.Int) ([]*big.Int, error) {
+	if len(owners) != len(ids) {
+		return nil, fmt.Errorf("%d owners for %d ids", len(owners), len(ids))
+	}
+	packed, err := tokenBatchArgs.Pack(owners, ids)
+	if err != nil {
+		return nil, err
+	}
+	input := hexutil.Bytes(append(common.CopyBytes(tokenBalanceOfBatchSelector), packed...))
+	result, err := t.eth.Call(ctx, TransactionArgs{To: &token, Input: &input}, t.config.Block, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	values, err := tokenUint256sArgs.Unpack(result)
+	if err != nil {
+		return nil, fmt.Errorf("invalid balanceOfBatch result: %w", err)
+	}
+	balances := values[0].([]*big.Int)
+	if len(balances) != len(ids) {
+		return nil, fmt.Errorf("balanceOfBatch returned %d balances for %d ids", len(balances), len(ids))
+	}
+	return balances, nil
+}
+
+// URI returns the ERC-1155 metadata URI of the token id, with the {id}
+// parameter replaced by the id as 64 lower case hex digits.
+func (t *Tokens) URI(ctx context.Context, token common.Address, id *big.Int) (string, error) {
+	result, err := t.call(ctx, token, tokenURI1155Selector, id.Bytes())
+	if err != nil {
+		return "", err
+	}
+	uri, err := unpackString(result)
+	if err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(uri, "{id}", fmt.Sprintf("%064x", id)), nil
+}
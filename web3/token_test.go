@@ -0,0 +1,371 @@
+package web3_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+)
+
+var (
+	erc20Token   = common.HexToAddress("0x0000000000000000000000000000000000000020")
+	mkrToken     = common.HexToAddress("0x0000000000000000000000000000000000000021")
+	erc721Token  = common.HexToAddress("0x0000000000000000000000000000000000000721")
+	erc1155Token = common.HexToAddress("0x0000000000000000000000000000000000001155")
+	tokenAlice   = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	tokenBob     = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+)
+
+func word(v int64) []byte {
+	return common.LeftPadBytes(big.NewInt(v).Bytes(), 32)
+}
+
+// tokenService is a stub node with an ERC-20 token, a bytes32 metadata token,
+// an ERC-721 token and an ERC-1155 token.
+type tokenService struct {
+	logs []*types.Log
+	// maxRange is the widest block range GetLogs answers.
+	maxRange int64
+	// fail is returned by GetLogs if set.
+	fail error
+	// requests are the block ranges of the GetLogs requests.
+	requests [][2]int64
+}
+
+func (s *tokenService) Call(args map[string]json.RawMessage, block, overrides, blockOverrides *json.RawMessage) (hexutil.Bytes, error) {
+	var to common.Address
+	var input hexutil.Bytes
+	json.Unmarshal(args["to"], &to)
+	json.Unmarshal(args["input"], &input)
+	if len(input) < 4 {
+		return nil, offchainRevert{}
+	}
+	sel, data := string(input[:4]), input[4:]
+	var first, second []byte
+	if len(data) >= 32 {
+		first = data[:32]
+	}
+	if len(data) >= 64 {
+		second = data[32:64]
+	}
+	switch {
+	case sel == selector("supportsInterface(bytes4)") && (to == erc721Token || to == erc1155Token):
+		id := [4]byte(first[:4])
+		supported := id == web3.InterfaceERC165 ||
+			to == erc721Token && (id == web3.InterfaceERC721 || id == web3.InterfaceERC721Metadata) ||
+			to == erc1155Token && (id == web3.InterfaceERC1155 || id == web3.InterfaceERC1155MetadataURI)
+		if supported {
+			return word(1), nil
+		}
+		return word(0), nil
+	case to == erc20Token:
+		switch sel {
+		case selector("name()"):
+			return abiArgs("string").Pack("Test Token")
+		case selector("symbol()"):
+			return abiArgs("string").Pack("TT")
+		case selector("decimals()"):
+			return word(6), nil
+		case selector("totalSupply()"):
+			return word(1_000_000_000), nil
+		case selector("balanceOf(address)"):
+			if common.BytesToAddress(first) == tokenAlice {
+				return word(1_500_000), nil
+			}
+			return word(0), nil
+		case selector("allowance(address,address)"):
+			if common.BytesToAddress(first) == tokenAlice && common.BytesToAddress(second) == tokenBob {
+				return word(250_000), nil
+			}
+			return word(0), nil
+		}
+	case to == mkrToken:
+		switch sel {
+		case selector("name()"):
+			return common.RightPadBytes([]byte("Maker"), 32), nil
+		case selector("symbol()"):
+			return common.RightPadBytes([]byte("MKR"), 32), nil
+		case selector("decimals()"):
+			return word(18), nil
+		}
+	case to == erc721Token:
+		switch sel {
+		case selector("name()"):
+			return abiArgs("string").Pack("Kitties")
+		case selector("ownerOf(uint256)"):
+			if new(big.Int).SetBytes(first).Int64() == 7 {
+				return common.LeftPadBytes(tokenAlice.Bytes(), 32), nil
+			}
+		case selector("tokenURI(uint256)"):
+			return abiArgs("string").Pack("ipfs://kitty/" + new(big.Int).SetBytes(first).String())
+		case selector("totalSupply()"):
+			return word(1), nil
+		}
+	case to == erc1155Token:
+		switch sel {
+		case selector("balanceOf(address,uint256)"):
+			return word(new(big.Int).SetBytes(second).Int64() * 10), nil
+		case selector("balanceOfBatch(address[],uint256[])"):
+			values, err := abiArgs("address[]", "uint256[]").Unpack(data)
+			if err != nil {
+				return nil, err
+			}
+			var balances []*big.Int
+			for _, id := range values[1].([]*big.Int) {
+				balances = append(balances, new(big.Int).Mul(id, big.NewInt(10)))
+			}
+			return abiArgs("uint256[]").Pack(balances)
+		case selector("uri(uint256)"):
+			return abiArgs("string").Pack("https://game.example/{id}.json")
+		}
+	}
+	return nil, offchainRevert{}
+}
+
+func (s *tokenService) BlockNumber() hexutil.Uint64 {
+	return 100
+}
+
+func (s *tokenService) GetLogs(crit filters.FilterCriteria) ([]*types.Log, error) {
+	s.requests = append(s.requests, [2]int64{crit.FromBlock.Int64(), crit.ToBlock.Int64()})
+	if s.fail != nil {
+		return nil, s.fail
+	}
+	if new(big.Int).Sub(crit.ToBlock, crit.FromBlock).Int64()+1 > s.maxRange {
+		return nil, errors.New("query returned more than 10000 results")
+	}
+	var logs []*types.Log
+	for _, log := range s.logs {
+		if int64(log.BlockNumber) < crit.FromBlock.Int64() || int64(log.BlockNumber) > crit.ToBlock.Int64() {
+			continue
+		}
+		if len(crit.Addresses) > 0 && !containsAddress(crit.Addresses, log.Address) {
+			continue
+		}
+		if matchTopics(crit.Topics, log.Topics) {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func matchTopics(filter [][]common.Hash, topics []common.Hash) bool {
+	if len(filter) > len(topics) {
+		return false
+	}
+	for i, alternatives := range filter {
+		if len(alternatives) == 0 {
+			continue
+		}
+		match := false
+		for _, topic := range alternatives {
+			match = match || topic == topics[i]
+		}
+		if !match {
+			return false
+		}
+	}
+	return true
+}
+
+func newTokenService(t *testing.T, s *tokenService) *web3.Web3 {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", s); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	client := rpc.DialInProc(server)
+	t.Cleanup(client.Close)
+	return web3.NewWeb3(client)
+}
+
+func TestTokens(t *testing.T) {
+	w := newTokenService(t, &tokenService{})
+	tokens := web3.NewTokens(w, web3.TokensConfig{})
+	ctx := context.Background()
+
+	metadata, err := tokens.Metadata(ctx, erc20Token)
+	if err != nil || *metadata != (web3.TokenMetadata{Name: "Test Token", Symbol: "TT", Decimals: 6}) {
+		t.Errorf("Metadata = %+v %v", metadata, err)
+	}
+	if metadata, err := tokens.Metadata(ctx, mkrToken); err != nil || *metadata != (web3.TokenMetadata{Name: "Maker", Symbol: "MKR", Decimals: 18}) {
+		t.Errorf("Metadata of bytes32 metadata = %+v %v", metadata, err)
+	}
+	if metadata, err := tokens.Metadata(ctx, erc721Token); err != nil || *metadata != (web3.TokenMetadata{Name: "Kitties"}) {
+		t.Errorf("Metadata of partial metadata = %+v %v", metadata, err)
+	}
+	balance, err := tokens.BalanceOf(ctx, erc20Token, tokenAlice)
+	if err != nil || metadata.Format(balance) != "1.5 TT" {
+		t.Errorf("BalanceOf = %v %v", balance, err)
+	}
+	if allowance, err := tokens.Allowance(ctx, erc20Token, tokenAlice, tokenBob); err != nil || allowance.Int64() != 250_000 {
+		t.Errorf("Allowance = %v %v", allowance, err)
+	}
+	if supply, err := tokens.TotalSupply(ctx, erc20Token); err != nil || supply.Int64() != 1_000_000_000 {
+		t.Errorf("TotalSupply = %v %v", supply, err)
+	}
+	if _, err := tokens.Decimals(ctx, erc721Token); !errors.Is(err, web3.ErrExecutionReverted) {
+		t.Errorf("Decimals of a token without decimals: %v, want %v", err, web3.ErrExecutionReverted)
+	}
+
+	if owner, err := tokens.OwnerOf(ctx, erc721Token, big.NewInt(7)); err != nil || owner != tokenAlice {
+		t.Errorf("OwnerOf = %v %v", owner, err)
+	}
+	if _, err := tokens.OwnerOf(ctx, erc721Token, big.NewInt(8)); !errors.Is(err, web3.ErrExecutionReverted) {
+		t.Errorf("OwnerOf of a missing token: %v", err)
+	}
+	if uri, err := tokens.TokenURI(ctx, erc721Token, big.NewInt(7)); err != nil || uri != "ipfs://kitty/7" {
+		t.Errorf("TokenURI = %q %v", uri, err)
+	}
+
+	if balance, err := tokens.BalanceOfID(ctx, erc1155Token, tokenAlice, big.NewInt(3)); err != nil || balance.Int64() != 30 {
+		t.Errorf("BalanceOfID = %v %v", balance, err)
+	}
+	balances, err := tokens.BalanceOfBatch(ctx, erc1155Token, []common.Address{tokenAlice, tokenBob}, []*big.Int{big.NewInt(1), big.NewInt(2)})
+	if err != nil || len(balances) != 2 || balances[0].Int64() != 10 || balances[1].Int64() != 20 {
+		t.Errorf("BalanceOfBatch = %v %v", balances, err)
+	}
+	if uri, err := tokens.URI(ctx, erc1155Token, big.NewInt(0x4cce)); err != nil || uri != "https://game.example/0000000000000000000000000000000000000000000000000000000000004cce.json" {
+		t.Errorf("URI = %q %v", uri, err)
+	}
+}
+
+func TestTokensDetectStandard(t *testing.T) {
+	w := newTokenService(t, &tokenService{})
+	tokens := web3.NewTokens(w, web3.TokensConfig{})
+	ctx := context.Background()
+
+	for token, want := range map[common.Address]web3.TokenStandard{
+		erc20Token:   web3.TokenERC20,
+		mkrToken:     web3.TokenUnknown,
+		erc721Token:  web3.TokenERC721,
+		erc1155Token: web3.TokenERC1155,
+	} {
+		if standard, err := tokens.DetectStandard(ctx, token); err != nil || standard != want {
+			t.Errorf("DetectStandard(%v) = %q %v, want %q", token, standard, err, want)
+		}
+	}
+	if ok, err := tokens.SupportsInterface(ctx, erc721Token, web3.InterfaceERC721Metadata); err != nil || !ok {
+		t.Errorf("SupportsInterface = %v %v", ok, err)
+	}
+	if ok, err := tokens.SupportsInterface(ctx, erc20Token, web3.InterfaceERC721); err != nil || ok {
+		t.Errorf("SupportsInterface of a token without ERC-165 = %v %v", ok, err)
+	}
+}
+
+func TestTokensTransfers(t *testing.T) {
+	topic := func(addr common.Address) common.Hash { return common.BytesToHash(addr.Bytes()) }
+	operator := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	batch, err := abiArgs("uint256[]", "uint256[]").Pack([]*big.Int{big.NewInt(1), big.NewInt(2)}, []*big.Int{big.NewInt(5), big.NewInt(6)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &tokenService{maxRange: 10, logs: []*types.Log{
+		{Address: erc20Token, BlockNumber: 3, Index: 0, Topics: []common.Hash{web3.TransferTopic, topic(tokenAlice), topic(tokenBob)}, Data: word(1_000_000)},
+		{Address: erc1155Token, BlockNumber: 3, Index: 1, Topics: []common.Hash{web3.TransferBatchTopic, topic(operator), topic(tokenAlice), topic(tokenBob)}, Data: batch},
+		{Address: erc721Token, BlockNumber: 40, Index: 0, Topics: []common.Hash{web3.TransferTopic, topic(tokenAlice), topic(tokenBob), common.BigToHash(big.NewInt(7))}},
+		{Address: erc1155Token, BlockNumber: 90, Index: 2, Topics: []common.Hash{web3.TransferSingleTopic, topic(operator), topic(tokenAlice), topic(tokenBob)}, Data: append(word(3), word(4)...)},
+		// from bob
+		{Address: erc20Token, BlockNumber: 95, Index: 0, Topics: []common.Hash{web3.TransferTopic, topic(tokenBob), topic(tokenAlice)}, Data: word(1)},
+		// not a transfer of any standard
+		{Address: erc20Token, BlockNumber: 96, Index: 0, Topics: []common.Hash{web3.TransferTopic, topic(tokenAlice), topic(tokenBob)}},
+	}}
+	w := newTokenService(t, s)
+	tokens := web3.NewTokens(w, web3.TokensConfig{LogRange: 40})
+
+	transfers, err := tokens.Transfers(context.Background(), web3.TransferFilter{From: []common.Address{tokenAlice}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type transfer struct {
+		standard web3.TokenStandard
+		token    common.Address
+		id       int64
+		value    int64
+		block    uint64
+	}
+	want := []transfer{
+		{web3.TokenERC20, erc20Token, -1, 1_000_000, 3},
+		{web3.TokenERC1155, erc1155Token, 1, 5, 3},
+		{web3.TokenERC1155, erc1155Token, 2, 6, 3},
+		{web3.TokenERC721, erc721Token, 7, 1, 40},
+		{web3.TokenERC1155, erc1155Token, 3, 4, 90},
+	}
+	if len(transfers) != len(want) {
+		t.Fatalf("Transfers returned %d transfers, want %d", len(transfers), len(want))
+	}
+	for i, tr := range transfers {
+		id := int64(-1)
+		if tr.ID != nil {
+			id = tr.ID.Int64()
+		}
+		got := transfer{tr.Standard, tr.Token, id, tr.Value.Int64(), tr.Log.BlockNumber}
+		if got != want[i] || tr.From != tokenAlice || tr.To != tokenBob {
+			t.Errorf("transfer %d = %+v from %v to %v, want %+v", i, got, tr.From, tr.To, want[i])
+		}
+		if tr.Standard == web3.TokenERC1155 && tr.Operator != operator {
+			t.Errorf("transfer %d operator = %v", i, tr.Operator)
+		}
+	}
+	// 101 blocks, 40 and 20 at a time refused and split down to 10, every
+	// range requested for Transfer and for the ERC-1155 events
+	requests := [][2]int64{{0, 39}, {0, 19}}
+	for first := int64(0); first <= 100; first += 10 {
+		end := min(first+9, 100)
+		requests = append(requests, [2]int64{first, end}, [2]int64{first, end})
+	}
+	if !reflect.DeepEqual(s.requests, requests) {
+		t.Errorf("GetLogs requests = %v\nwant %v", s.requests, requests)
+	}
+
+	// other errors are returned without splitting the range
+	s.fail, s.requests = errors.New("header not found"), nil
+	if _, err := tokens.Transfers(context.Background(), web3.TransferFilter{}); err == nil || err.Error() != "transfer logs of blocks 0 to 39: eth_getLogs: header not found" {
+		t.Errorf("Transfers error = %v", err)
+	}
+	if len(s.requests) != 1 {
+		t.Errorf("GetLogs requests = %v, want 1", s.requests)
+	}
+}
+
+func TestFormatUnits(t *testing.T) {
+	for _, test := range []struct {
+		amount   string
+		decimals uint8
+		want     string
+	}{
+		{"0", 18, "0"},
+		{"1500000", 6, "1.5"},
+		{"1", 18, "0.000000000000000001"},
+		{"-1234500", 3, "-1234.5"},
+		{"1000000000000000000000", 18, "1000"},
+		{"42", 0, "42"},
+	} {
+		amount, _ := new(big.Int).SetString(test.amount, 10)
+		if got := web3.FormatUnits(amount, test.decimals); got != test.want {
+			t.Errorf("FormatUnits(%s, %d) = %q, want %q", test.amount, test.decimals, got, test.want)
+		}
+		parsed, err := web3.ParseUnits(test.want, test.decimals)
+		if err != nil || parsed.Cmp(amount) != 0 {
+			t.Errorf("ParseUnits(%q, %d) = %v %v, want %s", test.want, test.decimals, parsed, err, test.amount)
+		}
+	}
+	if amount, err := web3.ParseUnits(".5", 2); err != nil || amount.Int64() != 50 {
+		t.Errorf("ParseUnits(.5) = %v %v", amount, err)
+	}
+	for _, s := range []string{"", ".", "1.234", "1e3", "1.2.3", "0x10", "--1"} {
+		if amount, err := web3.ParseUnits(s, 2); !errors.Is(err, web3.ErrInvalidAmount) {
+			t.Errorf("ParseUnits(%q) = %v %v, want %v", s, amount, err, web3.ErrInvalidAmount)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package web3
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
+)
+
+// Topics of the token transfer events.
+var (
+	// Transfer(address,address,uint256) of ERC-20 and ERC-721
+	TransferTopic = common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	// TransferSingle(address,address,address,uint256,uint256) of ERC-1155
+	TransferSingleTopic = common.HexToHash("0xc3d58168c5ae7397731d063d5bbf3d657854427343f4c083240f7aacaa2d0f62")
+	// TransferBatch(address,address,address,uint256[],uint256[]) of ERC-1155
+	TransferBatchTopic = common.HexToHash("0x4a39dc06d4c0dbc64b70af90fd698a233a518aa5d07e595d983b8c0526c8f7fb")
+)
+
+var tokenTransferBatchArgs abi.Arguments
+
+func init() {
+	typ, err := abi.NewType("uint256[]", "", nil)
+	if err != nil {
+		panic(err)
+	}
+	tokenTransferBatchArgs = abi.Arguments{{Type: typ}, {Type: typ}}
+}
+
+// TransferFilter selects the transfers returned by Transfers. Empty fields
+// match everything.
+type TransferFilter struct {
+	// Tokens are the token contracts.
+	Tokens []common.Address
+	// From and To are the senders and recipients.
+	From []common.Address
+	To   []common.Address
+	// FromBlock and ToBlock are the first and last blocks scanned, ToBlock
+	// being the latest block if zero.
+	FromBlock uint64
+	ToBlock   uint64
+}
+
+// TokenTransfer is a transfer of tokens. A TransferBatch event of ERC-1155
+// is one TokenTransfer per token id.
+type TokenTransfer struct {
+	Standard TokenStandard
+	Token    common.Address
+	// Operator is the account that made an ERC-1155 transfer.
+	Operator common.Address
+	From     common.Address
+	To       common.Address
+	// ID is the token id of ERC-721 and ERC-1155 transfers, nil for ERC-20.
+	ID *big.Int
+	// Value is the amount transferred, 1 for ERC-721.
+	Value *big.Int
+	Log   *types.Log
+}
+
+// Transfers scans the logs of the blocks of filter for ERC-20, ERC-721 and
+// ERC-1155 transfers and returns them in chain order. The blocks are
+// requested TokensConfig.LogRange at a time. Ranges the node refuses with an
+// ErrLimitExceeded error, for too many results or too wide a range, are split
+// in halves; other errors are returned.
+func (t *Tokens) Transfers(ctx context.Context, filter TransferFilter) ([]*TokenTransfer, error) {
+	last := filter.ToBlock
+	if last == 0 {
+		latest, err := t.eth.BlockNumber(ctx)
+		if err != nil {
+			return nil, err
+		}
+		last = latest
+	}
+	var transfers []*TokenTransfer
+	span := t.config.LogRange
+	for first := filter.FromBlock; first <= last; {
+		end := last
+		if last-first >= span {
+			end = first + span - 1
+		}
+		logs, err := t.transferLogs(ctx, filter, first, end)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if end > first && ClassifyError(err) == ErrLimitExceeded {
+				span = (end - first + 1) / 2
+				continue
+			}
+			return nil, fmt.Errorf("transfer logs of blocks %d to %d: %w", first, end, err)
+		}
+		for _, log := range logs {
+			transfers = append(transfers, decodeTransfers(log)...)
+		}
+		if end == last {
+			break
+		}
+		first = end + 1
+	}
+	return transfers, nil
+}
+
+// transferLogs returns the transfer logs of the blocks first to last. The
+// sender and recipient are the first two indexed arguments of Transfer and
+// the last two of the ERC-1155 events, so they are requested separately.
+func (t *Tokens) transferLogs(ctx context.Context, filter TransferFilter, first, last uint64) ([]*types.Log, error) {
+	from, to := addressTopics(filter.From), addressTopics(filter.To)
+	queries := [][][]common.Hash{
+		{{TransferTopic}, from, to},
+		{{TransferSingleTopic, TransferBatchTopic}, nil, from, to},
+	}
+	var logs []*types.Log
+	for _, topics := range queries {
+		result, err := t.eth.GetLogs(ctx, filters.FilterCriteria{
+			FromBlock: new(big.Int).SetUint64(first),
+			ToBlock:   new(big.Int).SetUint64(last),
+			Addresses: filter.Tokens,
+			Topics:    topics,
+		})
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, result...)
+	}
+	sort.Slice(logs, func(i, j int) bool {
+		if logs[i].BlockNumber != logs[j].BlockNumber {
+			return logs[i].BlockNumber < logs[j].BlockNumber
+		}
+		return logs[i].Index < logs[j].Index
+	})
+	return logs, nil
+}
+
+func addressTopics(addrs []common.Address) []common.Hash {
+	var topics []common.Hash
+	for _, addr := range addrs {
+		topics = append(topics, common.BytesToHash(addr.Bytes()))
+	}
+	return topics
+}
+
+// decodeTransfers decodes a transfer log. Logs with the topic of a transfer
+// event but another layout are skipped.
+func decodeTransfers(log *types.Log) []*TokenTransfer {
+	if len(log.Topics) == 0 {
+		return nil
+	}
+	transfer := TokenTransfer{Token: log.Address, Log: log}
+	switch topics := log.Topics; {
+	case topics[0] == TransferTopic && len(topics) == 3 && len(log.Data) == 32:
+		transfer.Standard = TokenERC20
+		transfer.From = common.BytesToAddress(topics[1][:])
+		transfer.To = common.BytesToAddress(topics[2][:])
+		transfer.Value = new(big.Int).SetBytes(log.Data)
+		return []*TokenTransfer{&transfer}
+	case topics[0] == TransferTopic && len(topics) == 4 && len(log.Data) == 0:
+		transfer.Standard = TokenERC721
+		transfer.From = common.BytesToAddress(topics[1][:])
+		transfer.To = common.BytesToAddress(topics[2][:])
+		transfer.ID = new(big.Int).SetBytes(topics[3][:])
+		transfer.Value = big.NewInt(1)
+		return []*TokenTransfer{&transfer}
+	case topics[0] == TransferSingleTopic && len(topics) == 4 && len(log.Data) == 64:
+		transfer.Standard = TokenERC1155
+		transfer.Operator = common.BytesToAddress(topics[1][:])
+		transfer.From = common.BytesToAddress(topics[2][:])
+		transfer.To = common.BytesToAddress(topics[3][:])
+		transfer.ID = new(big.Int).SetBytes(log.Data[:32])
+		transfer.Value = new(big.Int).SetBytes(log.Data[32:])
+		return []*TokenTransfer{&transfer}
+	case topics[0] == TransferBatchTopic && len(topics) == 4:
+		values, err := tokenTransferBatchArgs.Unpack(log.Data)
+		if err != nil {
+			return nil
+		}
+		ids, amounts := values[0].([]*big.Int), values[1].([]*big.Int)
+		if len(ids) != len(amounts) {
+			return nil
+		}
+		transfers := make([]*TokenTransfer, len(ids))
+		for i := range ids {
+			transfers[i] = &TokenTransfer{
+				Standard: TokenERC1155,
+				Token:    log.Address,
+				Operator: common.BytesToAddress(topics[1][:]),
+				From:     common.BytesToAddress(topics[2][:]),
+				To:       common.BytesToAddress(topics[3][:]),
+				ID:       ids[i],
+				Value:    amounts[i],
+				Log:      log,
+			}
+		}
+		return transfers
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package web3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// CallFrame is a frame of the output of the geth callTracer.
+type CallFrame struct {
+	Type         string          `json:"type"`
+	From         common.Address  `json:"from"`
+	To           *common.Address `json:"to,omitempty"`
+	Value        *hexutil.Big    `json:"value,omitempty"`
+	Gas          hexutil.Uint64  `json:"gas"`
+	GasUsed      hexutil.Uint64  `json:"gasUsed"`
+	Input        hexutil.Bytes   `json:"input"`
+	Output       hexutil.Bytes   `json:"output,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	RevertReason string          `json:"revertReason,omitempty"`
+	Calls        []*CallFrame    `json:"calls,omitempty"`
+}
+
+// parityErrors maps geth VM errors to the wording of Parity-style traces.
+var parityErrors = map[string]string{
+	"execution reverted":                        "Reverted",
+	"out of gas":                                "Out of gas",
+	"invalid jump destination":                  "Bad jump destination",
+	"max call depth exceeded":                   "Call stack limit reached",
+	"write protection":                          "Mutable Call In Static Context",
+	"contract creation code storage out of gas": "Out of gas",
+	"insufficient balance for transfer":         "Insufficient balance",
+}
+
+func parityError(err string) string {
+	if mapped, ok := parityErrors[err]; ok {
+		return mapped
+	}
+	switch {
+	case strings.HasPrefix(err, "invalid opcode"):
+		return "Bad instruction"
+	case strings.HasPrefix(err, "stack underflow"):
+		return "Stack underflow"
+	case strings.HasPrefix(err, "stack limit reached"):
+		return "Out of stack"
+	}
+	return err
+}
+
+// FlattenCallFrame turns a callTracer call tree into flat traces, in the
+// depth-first order Parity-style nodes list them. Gas figures are copied as
+// geth reports them, so those of the top level call include the intrinsic
+// gas that Parity-style nodes leave out. The block and transaction fields
+// are left for the caller to fill in.
+func FlattenCallFrame(frame *CallFrame) []*FlatTrace {
+	var traces []*FlatTrace
+	flattenCallFrame(frame, []int{}, &traces)
+	return traces
+}
+
+func flattenCallFrame(frame *CallFrame, address []int, traces *[]*FlatTrace) {
+	trace := &FlatTrace{
+		Subtraces:    len(frame.Calls),
+		TraceAddress: address,
+	}
+	from, gas, value := frame.From, frame.Gas, frame.Value
+	if value == nil {
+		value = new(hexutil.Big)
+	}
+	kind := strings.ToLower(frame.Type)
+	switch kind {
+	case "create", "create2":
+		trace.Type = FlatTraceCreate
+		trace.Action = TraceAction{From: &from, Gas: &gas, Init: frame.Input, Value: value, CreationMethod: kind}
+		if frame.Error == "" {
+			trace.Result = &TraceResult{GasUsed: frame.GasUsed, Address: frame.To, Code: frame.Output}
+		}
+	case "selfdestruct":
+		trace.Type = FlatTraceSuicide
+		trace.Action = TraceAction{Address: &from, RefundAddress: frame.To, Balance: value}
+	default:
+		trace.Type = FlatTraceCall
+		trace.Action = TraceAction{CallType: kind, From: &from, To: frame.To, Gas: &gas, Input: frame.Input, Value: value}
+		if frame.Error == "" {
+			trace.Result = &TraceResult{GasUsed: frame.GasUsed, Output: frame.Output}
+		}
+	}
+	if frame.Error != "" {
+		trace.Error = parityError(frame.Error)
+	}
+	*traces = append(*traces, trace)
+	for i, call := range frame.Calls {
+		child := make([]int, len(address)+1)
+		copy(child, address)
+		child[len(address)] = i
+		flattenCallFrame(call, child, traces)
+	}
+}
+
+// decodeCallFrame converts the result of a debug_trace* call made with the
+// callTracer into a CallFrame.
+func decodeCallFrame(result interface{}) (*CallFrame, error) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	var frame *CallFrame
+	if err := json.Unmarshal(raw, &frame); err != nil {
+		return nil, err
+	}
+	if frame == nil {
+		return nil, fmt.Errorf("empty call trace")
+	}
+	return frame, nil
+}
+
+func callTracerConfig() *tracers.TraceConfig {
+	tracer := "callTracer"
+	return &tracers.TraceConfig{Tracer: &tracer}
+}
+
+// TraceTransactionFlat traces a transaction with the callTracer and returns
+// it as flat traces, like Trace.Transaction does on Erigon and Nethermind.
+func (d *Debug) TraceTransactionFlat(ctx context.Context, hash common.Hash) ([]*FlatTrace, error) {
+	result, err := d.TraceTransaction(ctx, hash, callTracerConfig())
+	if err != nil {
+		return nil, err
+	}
+	frame, err := decodeCallFrame(result)
+	if err != nil {
+		return nil, err
+	}
+	traces := FlattenCallFrame(frame)
+	for _, trace := range traces {
+		trace.TransactionHash = &hash
+	}
+	return traces, nil
+}
+
+// TraceBlockByNumberFlat traces every transaction of a block with the
+// callTracer and returns them as flat traces, like Trace.Block does without
+// the reward traces.
+func (d *Debug) TraceBlockByNumberFlat(ctx context.Context, number rpc.BlockNumber) ([]*FlatTrace, error) {
+	results, err := d.TraceBlockByNumber(ctx, number, callTracerConfig())
+	if err != nil {
+		return nil, err
+	}
+	var traces []*FlatTrace
+	for i, result := range results {
+		if result.Error != "" {
+			return nil, fmt.Errorf("trace of transaction %d: %s", i, result.Error)
+		}
+		frame, err := decodeCallFrame(result.Result)
+		if err != nil {
+			return nil, err
+		}
+		position, hash := uint64(i), result.TxHash
+		for _, trace := range FlattenCallFrame(frame) {
+			trace.TransactionHash = &hash
+			trace.TransactionPosition = &position
+			if number >= 0 {
+				n := uint64(number)
+				trace.BlockNumber = &n
+			}
+			traces = append(traces, trace)
+		}
+	}
+	return traces, nil
+}
@@ -0,0 +1,325 @@
+package web3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// TraceType selects what the trace_call and trace_replay* methods return.
+type TraceType string
+
+const (
+	TraceTypeTrace     TraceType = "trace"
+	TraceTypeVMTrace   TraceType = "vmTrace"
+	TraceTypeStateDiff TraceType = "stateDiff"
+)
+
+// Flat trace types.
+const (
+	FlatTraceCall    = "call"
+	FlatTraceCreate  = "create"
+	FlatTraceSuicide = "suicide"
+	FlatTraceReward  = "reward"
+)
+
+// TraceAction is the action of a flat trace. Which fields are set depends on
+// the trace type: call traces have CallType, From, To, Gas, Input and Value,
+// create traces From, Gas, Init, Value and CreationMethod, suicide traces
+// Address, RefundAddress and Balance and reward traces Author, RewardType
+// and Value.
+type TraceAction struct {
+	CallType       string          `json:"callType,omitempty"`
+	From           *common.Address `json:"from,omitempty"`
+	To             *common.Address `json:"to,omitempty"`
+	Gas            *hexutil.Uint64 `json:"gas,omitempty"`
+	Input          hexutil.Bytes   `json:"input,omitempty"`
+	Init           hexutil.Bytes   `json:"init,omitempty"`
+	Value          *hexutil.Big    `json:"value,omitempty"`
+	CreationMethod string          `json:"creationMethod,omitempty"`
+	Address        *common.Address `json:"address,omitempty"`
+	RefundAddress  *common.Address `json:"refundAddress,omitempty"`
+	Balance        *hexutil.Big    `json:"balance,omitempty"`
+	Author         *common.Address `json:"author,omitempty"`
+	RewardType     string          `json:"rewardType,omitempty"`
+}
+
+// TraceResult is the result of a successful call or create trace. Create
+// traces have Address and Code instead of Output.
+type TraceResult struct {
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Output  hexutil.Bytes   `json:"output,omitempty"`
+	Address *common.Address `json:"address,omitempty"`
+	Code    hexutil.Bytes   `json:"code,omitempty"`
+}
+
+// FlatTrace is a single call frame in the Parity/OpenEthereum format served
+// by Erigon, Nethermind and Reth. TraceAddress is the path of the frame in the
+// call tree, the top level call having an empty one. Failed frames have a nil
+// Result and an Error such as "Reverted".
+type FlatTrace struct {
+	Action              TraceAction  `json:"action"`
+	BlockHash           *common.Hash `json:"blockHash,omitempty"`
+	BlockNumber         *uint64      `json:"blockNumber,omitempty"`
+	Result              *TraceResult `json:"result"`
+	Error               string       `json:"error,omitempty"`
+	Subtraces           int          `json:"subtraces"`
+	TraceAddress        []int        `json:"traceAddress"`
+	TransactionHash     *common.Hash `json:"transactionHash,omitempty"`
+	TransactionPosition *uint64      `json:"transactionPosition,omitempty"`
+	Type                string       `json:"type"`
+}
+
+// DiffKind tells how a value changed in a state diff.
+type DiffKind string
+
+const (
+	DiffSame    DiffKind = "="
+	DiffBorn    DiffKind = "+"
+	DiffDied    DiffKind = "-"
+	DiffChanged DiffKind = "*"
+)
+
+// Diff is the change of a single value. From is empty for born values, To for
+// died ones and both for unchanged ones. The values are hex encoded as the
+// node sent them: quantities for balances and nonces, bytes for code and
+// 32-byte words for storage.
+type Diff struct {
+	Kind DiffKind
+	From string
+	To   string
+}
+
+func (d *Diff) UnmarshalJSON(input []byte) error {
+	var same string
+	if err := json.Unmarshal(input, &same); err == nil {
+		if same != string(DiffSame) {
+			return fmt.Errorf("invalid state diff %q", same)
+		}
+		*d = Diff{Kind: DiffSame}
+		return nil
+	}
+	var fields map[DiffKind]json.RawMessage
+	if err := json.Unmarshal(input, &fields); err != nil {
+		return err
+	}
+	if len(fields) != 1 {
+		return fmt.Errorf("invalid state diff %s", input)
+	}
+	for kind, value := range fields {
+		*d = Diff{Kind: kind}
+		switch kind {
+		case DiffBorn:
+			return json.Unmarshal(value, &d.To)
+		case DiffDied:
+			return json.Unmarshal(value, &d.From)
+		case DiffChanged:
+			var change struct {
+				From string `json:"from"`
+				To   string `json:"to"`
+			}
+			if err := json.Unmarshal(value, &change); err != nil {
+				return err
+			}
+			d.From, d.To = change.From, change.To
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid state diff %s", input)
+}
+
+func (d Diff) MarshalJSON() ([]byte, error) {
+	switch d.Kind {
+	case DiffSame, "":
+		return json.Marshal(DiffSame)
+	case DiffBorn:
+		return json.Marshal(map[DiffKind]string{DiffBorn: d.To})
+	case DiffDied:
+		return json.Marshal(map[DiffKind]string{DiffDied: d.From})
+	default:
+		return json.Marshal(map[DiffKind]map[string]string{d.Kind: {"from": d.From, "to": d.To}})
+	}
+}
+
+// FromBig and ToBig decode the values of balance and nonce diffs. They are nil
+// where the value does not exist.
+func (d Diff) FromBig() *big.Int { return diffBig(d.From) }
+func (d Diff) ToBig() *big.Int   { return diffBig(d.To) }
+
+func diffBig(value string) *big.Int {
+	if value == "" {
+		return nil
+	}
+	n, ok := new(big.Int).SetString(value, 0)
+	if !ok {
+		return nil
+	}
+	return n
+}
+
+// AccountDiff is the change of an account caused by a transaction.
+type AccountDiff struct {
+	Balance Diff                 `json:"balance"`
+	Nonce   Diff                 `json:"nonce"`
+	Code    Diff                 `json:"code"`
+	Storage map[common.Hash]Diff `json:"storage"`
+}
+
+// StateDiff is the change of every account touched by a transaction.
+type StateDiff map[common.Address]*AccountDiff
+
+// VMTrace is the trace of the code executed by one call frame.
+type VMTrace struct {
+	Code hexutil.Bytes  `json:"code"`
+	Ops  []*VMOperation `json:"ops"`
+}
+
+// VMOperation is a single executed instruction. Sub is the trace of the frame
+// entered by CALL and CREATE instructions. Op and Idx are only sent by Erigon.
+type VMOperation struct {
+	Cost uint64               `json:"cost"`
+	Ex   *VMExecutedOperation `json:"ex"`
+	PC   uint64               `json:"pc"`
+	Sub  *VMTrace             `json:"sub"`
+	Op   string               `json:"op,omitempty"`
+	Idx  string               `json:"idx,omitempty"`
+}
+
+// VMExecutedOperation is the effect of an instruction: the words it pushed,
+// the memory and storage it wrote and the gas left.
+type VMExecutedOperation struct {
+	Mem   *VMMemoryDiff  `json:"mem"`
+	Push  []*hexutil.Big `json:"push"`
+	Store *VMStorageDiff `json:"store"`
+	Used  uint64         `json:"used"`
+}
+
+type VMMemoryDiff struct {
+	Off  uint64        `json:"off"`
+	Data hexutil.Bytes `json:"data"`
+}
+
+type VMStorageDiff struct {
+	Key *hexutil.Big `json:"key"`
+	Val *hexutil.Big `json:"val"`
+}
+
+// TraceResults is the result of trace_call, trace_callMany and the
+// trace_replay* methods. Only the parts asked for with TraceType are set.
+type TraceResults struct {
+	Output          hexutil.Bytes `json:"output"`
+	StateDiff       StateDiff     `json:"stateDiff"`
+	Trace           []*FlatTrace  `json:"trace"`
+	VMTrace         *VMTrace      `json:"vmTrace"`
+	TransactionHash *common.Hash  `json:"transactionHash,omitempty"`
+}
+
+// TraceFilterArgs selects the traces returned by trace_filter. Traces match
+// if their sender is in FromAddress and their recipient in ToAddress; empty
+// lists match everything. After and Count page through the matches.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock,omitempty"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock,omitempty"`
+	FromAddress []common.Address `json:"fromAddress,omitempty"`
+	ToAddress   []common.Address `json:"toAddress,omitempty"`
+	After       *uint64          `json:"after,omitempty"`
+	Count       *uint64          `json:"count,omitempty"`
+}
+
+// TraceCallRequest is one call of trace_callMany.
+type TraceCallRequest struct {
+	Args       TransactionArgs
+	TraceTypes []TraceType
+}
+
+func (r TraceCallRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{r.Args, r.TraceTypes})
+}
+
+// Block returns the traces of every transaction and the rewards of a block.
+// from TraceAPI
+// from erigon, nethermind
+// method
+func (t *Trace) Block(ctx context.Context, number rpc.BlockNumber) ([]*FlatTrace, error) {
+	var result []*FlatTrace
+	err := t.c.CallContext(ctx, &result, "trace_block", number)
+	return result, err
+}
+
+// Transaction returns the traces of a transaction.
+// from TraceAPI
+// from erigon, nethermind
+// method
+func (t *Trace) Transaction(ctx context.Context, hash common.Hash) ([]*FlatTrace, error) {
+	var result []*FlatTrace
+	err := t.c.CallContext(ctx, &result, "trace_transaction", hash)
+	return result, err
+}
+
+// Filter returns the traces matching args.
+// from TraceAPI
+// from erigon, nethermind
+// method
+func (t *Trace) Filter(ctx context.Context, args TraceFilterArgs) ([]*FlatTrace, error) {
+	var result []*FlatTrace
+	err := t.c.CallContext(ctx, &result, "trace_filter", args)
+	return result, err
+}
+
+// Call executes a call on top of the given block, the latest if it is nil,
+// and traces it.
+// from TraceAPI
+// from erigon, nethermind
+// method
+func (t *Trace) Call(ctx context.Context, args TransactionArgs, traceTypes []TraceType, blockNrOrHash *rpc.BlockNumberOrHash) (*TraceResults, error) {
+	var result *TraceResults
+	var err error
+	if blockNrOrHash == nil {
+		err = t.c.CallContext(ctx, &result, "trace_call", args, traceTypes)
+	} else {
+		err = t.c.CallContext(ctx, &result, "trace_call", args, traceTypes, blockNrOrHash)
+	}
+	return result, err
+}
+
+// CallMany executes the calls one after the other, each on the state left by
+// the previous one, and traces them.
+// from TraceAPI
+// from erigon, nethermind
+// method
+func (t *Trace) CallMany(ctx context.Context, calls []TraceCallRequest, blockNrOrHash *rpc.BlockNumberOrHash) ([]*TraceResults, error) {
+	var result []*TraceResults
+	var err error
+	if blockNrOrHash == nil {
+		err = t.c.CallContext(ctx, &result, "trace_callMany", calls)
+	} else {
+		err = t.c.CallContext(ctx, &result, "trace_callMany", calls, blockNrOrHash)
+	}
+	return result, err
+}
+
+// ReplayTransaction executes a mined transaction again and traces it.
+// from TraceAPI
+// from erigon, nethermind
+// method
+func (t *Trace) ReplayTransaction(ctx context.Context, hash common.Hash, traceTypes []TraceType) (*TraceResults, error) {
+	var result *TraceResults
+	err := t.c.CallContext(ctx, &result, "trace_replayTransaction", hash, traceTypes)
+	return result, err
+}
+
+// ReplayBlockTransactions executes every transaction of a block again and
+// traces them.
+// from TraceAPI
+// from erigon, nethermind
+// method
+func (t *Trace) ReplayBlockTransactions(ctx context.Context, number rpc.BlockNumber, traceTypes []TraceType) ([]*TraceResults, error) {
+	var result []*TraceResults
+	err := t.c.CallContext(ctx, &result, "trace_replayBlockTransactions", number, traceTypes)
+	return result, err
+}
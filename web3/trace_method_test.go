@@ -0,0 +1,231 @@
+package web3_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+// Responses of an Erigon node, shortened.
+const (
+	erigonTransactionTraces = `[
+  {"action":{"callType":"call","from":"0x83806d539d4ea1c140489a06660319c9a303f874","gas":"0x1a1f8","input":"0x","to":"0x1c39ba39e4735cb65978d4db400ddd70a72dc750","value":"0x7a16c911b4d00000"},
+   "blockHash":"0x7eb25504e4c202cf3d62fd585d3e238f592c780cca82dacb2ed3cb5b38883add","blockNumber":3068185,
+   "result":{"gasUsed":"0x2982","output":"0x"},"subtraces":1,"traceAddress":[],
+   "transactionHash":"0x17104ac9d3312d8c136b7f44d4b8b47852618065ebfa534bd2d3b5ef218ca1f3","transactionPosition":2,"type":"call"},
+  {"action":{"from":"0x1c39ba39e4735cb65978d4db400ddd70a72dc750","gas":"0x13e99","init":"0x6000","value":"0x0","creationMethod":"create"},
+   "blockHash":"0x7eb25504e4c202cf3d62fd585d3e238f592c780cca82dacb2ed3cb5b38883add","blockNumber":3068185,
+   "result":{"address":"0x2a65aca4d5fc5b5c859090a6c34d164135398226","code":"0x60","gasUsed":"0x3a"},"subtraces":0,"traceAddress":[0],
+   "transactionHash":"0x17104ac9d3312d8c136b7f44d4b8b47852618065ebfa534bd2d3b5ef218ca1f3","transactionPosition":2,"type":"create"}
+]`
+	erigonReplay = `{
+  "output":"0x",
+  "stateDiff":{
+    "0x2a65aca4d5fc5b5c859090a6c34d164135398226":{
+      "balance":{"*":{"from":"0x1","to":"0x2"}},
+      "code":{"+":"0x60"},
+      "nonce":"=",
+      "storage":{"0x0000000000000000000000000000000000000000000000000000000000000000":{"-":"0x000000000000000000000000000000000000000000000000000000000000002a"}}
+    }
+  },
+  "trace":[],
+  "vmTrace":{"code":"0x6000","ops":[
+    {"cost":3,"ex":{"mem":null,"push":["0x0"],"store":null,"used":99997},"pc":0,"sub":null,"op":"PUSH1","idx":"0-0"},
+    {"cost":5000,"ex":{"mem":{"off":0,"data":"0x2a"},"push":[],"store":{"key":"0x0","val":"0x2a"},"used":94997},"pc":2,"sub":{"code":"0x","ops":[]}}
+  ]}
+}`
+)
+
+// traceService answers like an Erigon node and keeps the params it got.
+type traceService struct {
+	params map[string]json.RawMessage
+}
+
+func (s *traceService) keep(method string, params ...interface{}) {
+	s.params[method], _ = json.Marshal(params)
+}
+
+func (s *traceService) Block(number rpc.BlockNumber) json.RawMessage {
+	s.keep("trace_block", number)
+	return json.RawMessage(erigonTransactionTraces)
+}
+
+func (s *traceService) Transaction(hash common.Hash) json.RawMessage {
+	s.keep("trace_transaction", hash)
+	return json.RawMessage(erigonTransactionTraces)
+}
+
+func (s *traceService) Filter(args json.RawMessage) json.RawMessage {
+	s.keep("trace_filter", args)
+	return json.RawMessage(erigonTransactionTraces)
+}
+
+func (s *traceService) Call(args json.RawMessage, traceTypes []string, block *rpc.BlockNumberOrHash) json.RawMessage {
+	s.keep("trace_call", traceTypes, block)
+	return json.RawMessage(erigonReplay)
+}
+
+func (s *traceService) CallMany(calls json.RawMessage, block *rpc.BlockNumberOrHash) json.RawMessage {
+	s.keep("trace_callMany", block)
+	return json.RawMessage("[" + erigonReplay + "," + erigonReplay + "]")
+}
+
+func (s *traceService) ReplayTransaction(hash common.Hash, traceTypes []string) json.RawMessage {
+	s.keep("trace_replayTransaction", hash, traceTypes)
+	return json.RawMessage(erigonReplay)
+}
+
+func (s *traceService) ReplayBlockTransactions(number rpc.BlockNumber, traceTypes []string) json.RawMessage {
+	s.keep("trace_replayBlockTransactions", number, traceTypes)
+	return json.RawMessage("[" + erigonReplay + "]")
+}
+
+func TestTraceErigonResponses(t *testing.T) {
+	service := &traceService{params: make(map[string]json.RawMessage)}
+	server := rpc.NewServer()
+	if err := server.RegisterName("trace", service); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	w := web3.NewWeb3(rpc.DialInProc(server))
+	ctx := context.Background()
+	hash := common.HexToHash("0x17104ac9d3312d8c136b7f44d4b8b47852618065ebfa534bd2d3b5ef218ca1f3")
+	all := []web3.TraceType{web3.TraceTypeTrace, web3.TraceTypeVMTrace, web3.TraceTypeStateDiff}
+
+	traces, err := w.Trace.Transaction(ctx, hash)
+	if err != nil {
+		t.Fatalf("Transaction: %v", err)
+	}
+	if len(traces) != 2 || traces[0].Type != web3.FlatTraceCall || traces[0].Action.CallType != "call" || traces[0].Subtraces != 1 {
+		t.Fatalf("Transaction = %+v", traces[0])
+	}
+	create := traces[1]
+	if create.Type != web3.FlatTraceCreate || create.Action.CreationMethod != "create" || *create.Result.Address != common.HexToAddress("0x2a65aca4d5fc5b5c859090a6c34d164135398226") ||
+		!reflect.DeepEqual(create.TraceAddress, []int{0}) || *create.BlockNumber != 3068185 || *create.TransactionPosition != 2 {
+		t.Errorf("create trace = %+v", create)
+	}
+	if _, err := w.Trace.Block(ctx, 3068185); err != nil {
+		t.Errorf("Block: %v", err)
+	}
+	from, count := rpc.BlockNumber(3068185), uint64(10)
+	filter := web3.TraceFilterArgs{FromBlock: &from, ToBlock: &from, ToAddress: []common.Address{{1}}, Count: &count}
+	if _, err := w.Trace.Filter(ctx, filter); err != nil {
+		t.Errorf("Filter: %v", err)
+	}
+	if got, want := string(service.params["trace_filter"]), `[{"fromBlock":"0x2ed119","toBlock":"0x2ed119","toAddress":["0x0100000000000000000000000000000000000000"],"count":10}]`; got != want {
+		t.Errorf("trace_filter params = %s, want %s", got, want)
+	}
+
+	replay, err := w.Trace.ReplayTransaction(ctx, hash, all)
+	if err != nil {
+		t.Fatalf("ReplayTransaction: %v", err)
+	}
+	account := replay.StateDiff[common.HexToAddress("0x2a65aca4d5fc5b5c859090a6c34d164135398226")]
+	if account == nil || account.Balance.Kind != web3.DiffChanged || account.Balance.FromBig().Int64() != 1 || account.Balance.ToBig().Int64() != 2 ||
+		account.Code.Kind != web3.DiffBorn || account.Code.To != "0x60" || account.Nonce.Kind != web3.DiffSame ||
+		account.Storage[common.Hash{}].Kind != web3.DiffDied {
+		t.Errorf("state diff = %+v", account)
+	}
+	encoded, err := json.Marshal(account.Balance)
+	if err != nil || string(encoded) != `{"*":{"from":"0x1","to":"0x2"}}` {
+		t.Errorf("Diff.MarshalJSON = %s %v", encoded, err)
+	}
+	ops := replay.VMTrace.Ops
+	if len(ops) != 2 || ops[0].Op != "PUSH1" || ops[0].Ex.Push[0].ToInt().Sign() != 0 || ops[1].Ex.Store.Val.ToInt().Int64() != 0x2a ||
+		ops[1].Ex.Mem.Data[0] != 0x2a || ops[1].Sub == nil {
+		t.Errorf("vmTrace = %+v", replay.VMTrace)
+	}
+	if string(service.params["trace_replayTransaction"]) != `["`+hash.Hex()+`",["trace","vmTrace","stateDiff"]]` {
+		t.Errorf("trace_replayTransaction params = %s", service.params["trace_replayTransaction"])
+	}
+
+	if _, err := w.Trace.ReplayBlockTransactions(ctx, rpc.LatestBlockNumber, all); err != nil {
+		t.Errorf("ReplayBlockTransactions: %v", err)
+	}
+	to := common.HexToAddress("0x2a65aca4d5fc5b5c859090a6c34d164135398226")
+	args := web3.TransactionArgs{To: &to}
+	if _, err := w.Trace.Call(ctx, args, all, nil); err != nil {
+		t.Errorf("Call: %v", err)
+	}
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	many, err := w.Trace.CallMany(ctx, []web3.TraceCallRequest{{Args: args, TraceTypes: all}, {Args: args, TraceTypes: all}}, &latest)
+	if err != nil || len(many) != 2 {
+		t.Errorf("CallMany = %d %v", len(many), err)
+	}
+}
+
+func TestFlattenCallFrame(t *testing.T) {
+	const callTrace = `{
+  "type":"CALL","from":"0x0000000000000000000000000000000000000001","to":"0x0000000000000000000000000000000000000002",
+  "value":"0x5","gas":"0x10000","gasUsed":"0x6000","input":"0x01","output":"0x02",
+  "calls":[
+    {"type":"DELEGATECALL","from":"0x0000000000000000000000000000000000000002","to":"0x0000000000000000000000000000000000000003",
+     "gas":"0x8000","gasUsed":"0x100","input":"0x03","error":"execution reverted","revertReason":"nope"},
+    {"type":"CREATE2","from":"0x0000000000000000000000000000000000000002","to":"0x0000000000000000000000000000000000000004",
+     "value":"0x0","gas":"0x7000","gasUsed":"0x200","input":"0x6000","output":"0x60",
+     "calls":[{"type":"SELFDESTRUCT","from":"0x0000000000000000000000000000000000000004","to":"0x0000000000000000000000000000000000000001","value":"0x1","gas":"0x0","gasUsed":"0x0","input":"0x"}]}
+  ]
+}`
+	var frame web3.CallFrame
+	if err := json.Unmarshal([]byte(callTrace), &frame); err != nil {
+		t.Fatal(err)
+	}
+	traces := web3.FlattenCallFrame(&frame)
+	if len(traces) != 4 {
+		t.Fatalf("%d traces, want 4", len(traces))
+	}
+	want := []struct {
+		kind, callType, err string
+		address             []int
+		subtraces           int
+	}{
+		{web3.FlatTraceCall, "call", "", []int{}, 2},
+		{web3.FlatTraceCall, "delegatecall", "Reverted", []int{0}, 0},
+		{web3.FlatTraceCreate, "", "", []int{1}, 1},
+		{web3.FlatTraceSuicide, "", "", []int{1, 0}, 0},
+	}
+	for i, w := range want {
+		got := traces[i]
+		if got.Type != w.kind || got.Action.CallType != w.callType || got.Error != w.err || !reflect.DeepEqual(got.TraceAddress, w.address) || got.Subtraces != w.subtraces {
+			t.Errorf("trace %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if traces[1].Result != nil || traces[1].Action.Value.ToInt().Sign() != 0 {
+		t.Errorf("reverted delegatecall = %+v", traces[1])
+	}
+	if traces[2].Action.CreationMethod != "create2" || *traces[2].Result.Address != common.HexToAddress("0x4") || traces[2].Result.Code.String() != "0x60" {
+		t.Errorf("create = %+v %+v", traces[2].Action, traces[2].Result)
+	}
+	if *traces[3].Action.Address != common.HexToAddress("0x4") || *traces[3].Action.RefundAddress != common.HexToAddress("0x1") || traces[3].Result != nil {
+		t.Errorf("suicide = %+v", traces[3].Action)
+	}
+}
+
+func TestDebugTraceFlatDevNode(t *testing.T) {
+	node := web3test.New(t)
+	contract, call := deployStore(t, node, common.HexToHash("0x2a"))
+	ctx := context.Background()
+
+	traces, err := node.Web3.Debug.TraceTransactionFlat(ctx, call.Hash())
+	if err != nil {
+		t.Fatalf("TraceTransactionFlat: %v", err)
+	}
+	if len(traces) != 1 || *traces[0].Action.To != contract || *traces[0].TransactionHash != call.Hash() || traces[0].Result == nil {
+		t.Errorf("TraceTransactionFlat = %+v", traces)
+	}
+	traces, err = node.Web3.Debug.TraceBlockByNumberFlat(ctx, 1)
+	if err != nil {
+		t.Fatalf("TraceBlockByNumberFlat: %v", err)
+	}
+	if len(traces) != 2 || traces[0].Type != web3.FlatTraceCreate || *traces[0].Result.Address != contract ||
+		*traces[1].TransactionPosition != 1 || *traces[1].BlockNumber != 1 || *traces[1].TransactionHash != call.Hash() {
+		t.Errorf("TraceBlockByNumberFlat = %+v %+v", traces[0], traces[1])
+	}
+}
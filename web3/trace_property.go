@@ -0,0 +1,18 @@
+package web3
+
+type Trace struct {
+	c    Client
+	gate *capabilityGate
+}
+
+func NewTrace(c Client) *Trace {
+	e := &Trace{}
+	e.c = withCallErrors(c)
+	return e
+}
+
+// Available reports whether the node serves the trace namespace. It is true
+// until Web3.Capabilities has probed the node.
+func (t *Trace) Available() bool {
+	return t.gate.available("trace")
+}
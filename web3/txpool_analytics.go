@@ -0,0 +1,301 @@
+package web3
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// PoolPriceBump is the minimum fee increase, in percent, geth requires to
+// replace a transaction of the pool.
+const PoolPriceBump = 10
+
+// NonceGap is a run of nonces missing between two transactions of a sender
+// in the pool, from First to Last inclusive. Transactions after a gap are
+// queued until it is filled.
+type NonceGap struct {
+	Sender common.Address `json:"sender"`
+	First  uint64         `json:"first"`
+	Last   uint64         `json:"last"`
+}
+
+// FeeDistribution is an ascending list of fees per gas.
+type FeeDistribution []*big.Int
+
+// Percentile returns the nearest-rank p-th percentile of the distribution,
+// p between 0 and 100, or nil if it is empty.
+func (d FeeDistribution) Percentile(p float64) *big.Int {
+	if len(d) == 0 {
+		return nil
+	}
+	rank := int(math.Ceil(p/100*float64(len(d)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(d) {
+		rank = len(d) - 1
+	}
+	return new(big.Int).Set(d[rank])
+}
+
+// ReplacementCandidate is a pending transaction that is unlikely to be
+// included soon, with the fees a replacement needs to be accepted by the
+// pool.
+type ReplacementCandidate struct {
+	Tx        *RPCTransaction `json:"tx"`
+	Reason    string          `json:"reason"`
+	MinFeeCap *big.Int        `json:"minFeeCap"`
+	MinTipCap *big.Int        `json:"minTipCap"`
+}
+
+// PoolReport is an analysis of the content of a transaction pool, see
+// AnalyzePool.
+type PoolReport struct {
+	Pending int `json:"pending"`
+	Queued  int `json:"queued"`
+	Senders int `json:"senders"`
+	// NonceGaps are the gaps between the nonces of each sender known to the
+	// pool. A gap below the lowest nonce of a sender cannot be seen here.
+	NonceGaps []NonceGap `json:"nonceGaps"`
+	// FeeCaps are the fee caps, or gas prices, of the pending transactions.
+	FeeCaps FeeDistribution `json:"feeCaps"`
+	// Tips are the effective tips of the pending transactions at the base
+	// fee, zero for those that cannot pay it.
+	Tips FeeDistribution `json:"tips"`
+	// Replacements are the pending transactions whose fee cap is below the
+	// base fee or whose effective tip is below the median.
+	Replacements []ReplacementCandidate `json:"replacements"`
+}
+
+// poolFees returns the fee cap and tip cap of a transaction, both its gas
+// price for legacy transactions.
+func poolFees(tx *RPCTransaction) (feeCap, tipCap *big.Int) {
+	feeCap, tipCap = new(big.Int), new(big.Int)
+	if tx.GasPrice != nil {
+		feeCap.Set(tx.GasPrice.ToInt())
+		tipCap.Set(tx.GasPrice.ToInt())
+	}
+	if tx.GasFeeCap != nil {
+		feeCap.Set(tx.GasFeeCap.ToInt())
+	}
+	if tx.GasTipCap != nil {
+		tipCap.Set(tx.GasTipCap.ToInt())
+	}
+	return feeCap, tipCap
+}
+
+// effectiveTip returns the tip a transaction pays at baseFee, which may be
+// negative if its fee cap is below it.
+func effectiveTip(feeCap, tipCap, baseFee *big.Int) *big.Int {
+	if baseFee == nil {
+		return new(big.Int).Set(tipCap)
+	}
+	tip := new(big.Int).Sub(feeCap, baseFee)
+	if tip.Cmp(tipCap) > 0 {
+		tip.Set(tipCap)
+	}
+	return tip
+}
+
+// bumpFee returns fee raised by PoolPriceBump percent, rounded up.
+func bumpFee(fee *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(100+PoolPriceBump))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+// AnalyzePool reports nonce gaps, fee distributions and replacement
+// candidates of the content of a pool. baseFee is the base fee of the next
+// block, nil before London.
+func AnalyzePool(content *PoolContent, baseFee *big.Int) *PoolReport {
+	report := &PoolReport{}
+	nonces := make(map[common.Address][]uint64)
+	for sender, txs := range content.Pending {
+		report.Pending += len(txs)
+		for nonce := range txs {
+			nonces[sender] = append(nonces[sender], nonce)
+		}
+	}
+	for sender, txs := range content.Queued {
+		report.Queued += len(txs)
+		for nonce := range txs {
+			nonces[sender] = append(nonces[sender], nonce)
+		}
+	}
+	senders := make([]common.Address, 0, len(nonces))
+	for sender := range nonces {
+		senders = append(senders, sender)
+	}
+	sortAddresses(senders)
+	report.Senders = len(senders)
+
+	type pendingTx struct {
+		tx             *RPCTransaction
+		feeCap, tipCap *big.Int
+		tip            *big.Int
+	}
+	var pending []pendingTx
+	for _, sender := range senders {
+		list := nonces[sender]
+		sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+		for i := 1; i < len(list); i++ {
+			if list[i] > list[i-1]+1 {
+				report.NonceGaps = append(report.NonceGaps, NonceGap{Sender: sender, First: list[i-1] + 1, Last: list[i] - 1})
+			}
+		}
+		for _, nonce := range list {
+			tx := content.Pending[sender][nonce]
+			if tx == nil {
+				continue
+			}
+			feeCap, tipCap := poolFees(tx)
+			pending = append(pending, pendingTx{tx: tx, feeCap: feeCap, tipCap: tipCap, tip: effectiveTip(feeCap, tipCap, baseFee)})
+		}
+	}
+
+	for _, p := range pending {
+		report.FeeCaps = append(report.FeeCaps, p.feeCap)
+		tip := p.tip
+		if tip.Sign() < 0 {
+			tip = new(big.Int)
+		}
+		report.Tips = append(report.Tips, tip)
+	}
+	sort.Slice(report.FeeCaps, func(i, j int) bool { return report.FeeCaps[i].Cmp(report.FeeCaps[j]) < 0 })
+	sort.Slice(report.Tips, func(i, j int) bool { return report.Tips[i].Cmp(report.Tips[j]) < 0 })
+
+	median := report.Tips.Percentile(50)
+	for _, p := range pending {
+		var reason string
+		switch {
+		case baseFee != nil && p.feeCap.Cmp(baseFee) < 0:
+			reason = "fee cap below base fee"
+		case p.tip.Cmp(median) < 0:
+			reason = "tip below median"
+		default:
+			continue
+		}
+		report.Replacements = append(report.Replacements, ReplacementCandidate{
+			Tx:        p.tx,
+			Reason:    reason,
+			MinFeeCap: bumpFee(p.feeCap),
+			MinTipCap: bumpFee(p.tipCap),
+		})
+	}
+	return report
+}
+
+// AnalyzePool fetches the content of the pool and analyzes it at the base
+// fee of the next block.
+func (w *Web3) AnalyzePool(ctx context.Context) (*PoolReport, error) {
+	content, err := w.TxPool.TypedContent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	baseFee, err := w.Eth.nextBaseFee(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return AnalyzePool(content, baseFee), nil
+}
+
+// nextBaseFee returns the base fee of the block after the latest, the last
+// one of FeeHistory, nil before London.
+func (e *Eth) nextBaseFee(ctx context.Context) (*big.Int, error) {
+	history, err := e.FeeHistory(ctx, 1, rpc.LatestBlockNumber, nil)
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		return nil, errors.New("no fee history")
+	}
+	if n := len(history.BaseFee); n > 0 && history.BaseFee[n-1] != nil && history.BaseFee[n-1].ToInt().Sign() > 0 {
+		return new(big.Int).Set(history.BaseFee[n-1].ToInt()), nil
+	}
+	return nil, nil
+}
+
+// PoolSample is the number of pending and queued transactions of a pool at
+// one point in time.
+type PoolSample struct {
+	Time    time.Time `json:"time"`
+	Pending int       `json:"pending"`
+	Queued  int       `json:"queued"`
+}
+
+// PoolMonitorConfig tunes a PoolMonitor. Zero values get defaults.
+type PoolMonitorConfig struct {
+	// Window is the number of recent samples kept, 360 by default.
+	Window int
+	// PollInterval is how often Run samples the pool, one second by default.
+	PollInterval time.Duration
+}
+
+// PoolMonitor samples txpool_status to follow the pending and queued counts
+// of a pool over time.
+type PoolMonitor struct {
+	txpool *TxPool
+	config PoolMonitorConfig
+
+	mu      sync.Mutex
+	samples []PoolSample
+}
+
+func NewPoolMonitor(w *Web3, config PoolMonitorConfig) *PoolMonitor {
+	if config.Window == 0 {
+		config.Window = 360
+	}
+	if config.PollInterval == 0 {
+		config.PollInterval = time.Second
+	}
+	m := &PoolMonitor{}
+	m.txpool = w.TxPool
+	m.config = config
+	return m
+}
+
+// Run samples the pool until ctx is cancelled.
+func (m *PoolMonitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := m.Sample(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sample takes one sample of the pool and records it.
+func (m *PoolMonitor) Sample(ctx context.Context) (PoolSample, error) {
+	status, err := m.txpool.Status(ctx)
+	if err != nil {
+		return PoolSample{}, err
+	}
+	sample := PoolSample{Time: time.Now(), Pending: int(status["pending"]), Queued: int(status["queued"])}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.samples = append(m.samples, sample)
+	if len(m.samples) > m.config.Window {
+		m.samples = m.samples[len(m.samples)-m.config.Window:]
+	}
+	return sample, nil
+}
+
+// Samples returns the recorded samples, oldest first.
+func (m *PoolMonitor) Samples() []PoolSample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]PoolSample(nil), m.samples...)
+}
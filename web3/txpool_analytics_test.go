@@ -0,0 +1,127 @@
+package web3_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+func poolTx(nonce uint64, feeCap, tipCap int64) *web3.RPCTransaction {
+	tx := &web3.RPCTransaction{Nonce: hexutil.Uint64(nonce), GasPrice: (*hexutil.Big)(big.NewInt(feeCap))}
+	if tipCap >= 0 {
+		tx.GasFeeCap = (*hexutil.Big)(big.NewInt(feeCap))
+		tx.GasTipCap = (*hexutil.Big)(big.NewInt(tipCap))
+	}
+	return tx
+}
+
+func TestAnalyzePool(t *testing.T) {
+	a := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	b := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	content := &web3.PoolContent{
+		Pending: map[common.Address]map[uint64]*web3.RPCTransaction{
+			a: {0: poolTx(0, 200, 10), 1: poolTx(1, 120, 30)},
+			b: {5: poolTx(5, 90, 5), 6: poolTx(6, 150, -1)},
+		},
+		Queued: map[common.Address]map[uint64]*web3.RPCTransaction{
+			a: {4: poolTx(4, 200, 10), 7: poolTx(7, 200, 10)},
+		},
+	}
+	report := web3.AnalyzePool(content, big.NewInt(100))
+	if report.Pending != 4 || report.Queued != 2 || report.Senders != 2 {
+		t.Errorf("counts = %d pending, %d queued, %d senders", report.Pending, report.Queued, report.Senders)
+	}
+	wantGaps := []web3.NonceGap{{Sender: a, First: 2, Last: 3}, {Sender: a, First: 5, Last: 6}}
+	if len(report.NonceGaps) != 2 || report.NonceGaps[0] != wantGaps[0] || report.NonceGaps[1] != wantGaps[1] {
+		t.Errorf("NonceGaps = %v, want %v", report.NonceGaps, wantGaps)
+	}
+	// effective tips: 10, 20, 0 and 50 for the legacy transaction
+	if got := report.Tips.Percentile(50); got.Int64() != 10 {
+		t.Errorf("median tip = %v, want 10", got)
+	}
+	if got := report.Tips.Percentile(100); got.Int64() != 50 {
+		t.Errorf("max tip = %v, want 50", got)
+	}
+	if got := report.FeeCaps.Percentile(0); got.Int64() != 90 {
+		t.Errorf("min fee cap = %v, want 90", got)
+	}
+	if len(report.Replacements) != 1 {
+		t.Fatalf("Replacements = %v", report.Replacements)
+	}
+	if r := report.Replacements[0]; uint64(r.Tx.Nonce) != 5 || r.Reason != "fee cap below base fee" || r.MinFeeCap.Int64() != 99 || r.MinTipCap.Int64() != 6 {
+		t.Errorf("Replacement = %+v", r)
+	}
+	if web3.FeeDistribution(nil).Percentile(50) != nil {
+		t.Error("Percentile of an empty distribution is not nil")
+	}
+}
+
+// feeService is a stub node whose next base fee, 200, is above the base fee
+// of its latest block, 100.
+type feeService struct {
+	content *web3.PoolContent
+}
+
+func (s *feeService) Content() *web3.PoolContent {
+	return s.content
+}
+
+func (s *feeService) GetBlockByNumber(number rpc.BlockNumber, full bool) map[string]interface{} {
+	return map[string]interface{}{"number": "0x1", "baseFeePerGas": "0x64"}
+}
+
+func (s *feeService) FeeHistory(blockCount math.HexOrDecimal64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) *web3.FeeHistoryResult {
+	return &web3.FeeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(big.NewInt(1)),
+		BaseFee:      []*hexutil.Big{(*hexutil.Big)(big.NewInt(100)), (*hexutil.Big)(big.NewInt(200))},
+		GasUsedRatio: []float64{1},
+	}
+}
+
+func TestWeb3AnalyzePoolNextBaseFee(t *testing.T) {
+	a := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	s := &feeService{content: &web3.PoolContent{Pending: map[common.Address]map[uint64]*web3.RPCTransaction{a: {0: poolTx(0, 150, 10)}}}}
+	server := rpc.NewServer()
+	for _, namespace := range []string{"eth", "txpool"} {
+		if err := server.RegisterName(namespace, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer server.Stop()
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	// the fee cap pays the latest base fee but not the next one
+	report, err := web3.NewWeb3(client).AnalyzePool(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Replacements) != 1 || report.Replacements[0].Reason != "fee cap below base fee" || report.Tips[0].Sign() != 0 {
+		t.Errorf("AnalyzePool = %+v", report)
+	}
+}
+
+func TestPoolMonitorDevNode(t *testing.T) {
+	node := web3test.New(t)
+	ctx := context.Background()
+	monitor := web3.NewPoolMonitor(node.Web3, web3.PoolMonitorConfig{Window: 2})
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	for i := 0; i < 3; i++ {
+		node.SendTx(t, &to, big.NewInt(1), nil)
+		if _, err := monitor.Sample(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	samples := monitor.Samples()
+	if len(samples) != 2 || samples[0].Pending != 2 || samples[1].Pending != 3 || samples[1].Queued != 0 {
+		t.Errorf("Samples = %+v", samples)
+	}
+}
@@ -0,0 +1,124 @@
+package web3
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// PoolContent is the typed result of txpool_content: the pending and queued
+// transactions of the pool by sender and nonce.
+type PoolContent struct {
+	Pending map[common.Address]map[uint64]*RPCTransaction `json:"pending"`
+	Queued  map[common.Address]map[uint64]*RPCTransaction `json:"queued"`
+}
+
+// PoolAccountContent is the typed result of txpool_contentFrom: the pending
+// and queued transactions of one sender by nonce.
+type PoolAccountContent struct {
+	Pending map[uint64]*RPCTransaction `json:"pending"`
+	Queued  map[uint64]*RPCTransaction `json:"queued"`
+}
+
+// PoolSummary is a parsed txpool_inspect summary, as formatted by geth:
+// "0xTo: value wei + gas gas × price wei".
+type PoolSummary struct {
+	// To is nil for a contract creation.
+	To       *common.Address
+	Value    *big.Int
+	Gas      uint64
+	GasPrice *big.Int
+}
+
+// PoolInspect is the typed result of txpool_inspect.
+type PoolInspect struct {
+	Pending map[common.Address]map[uint64]*PoolSummary
+	Queued  map[common.Address]map[uint64]*PoolSummary
+}
+
+// TypedContent is Content with typed addresses and nonces.
+func (t *TxPool) TypedContent(ctx context.Context) (*PoolContent, error) {
+	var result PoolContent
+	if err := t.c.CallContext(ctx, &result, "txpool_content"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// TypedContentFrom is ContentFrom with typed nonces.
+func (t *TxPool) TypedContentFrom(ctx context.Context, addr common.Address) (*PoolAccountContent, error) {
+	var result PoolAccountContent
+	if err := t.c.CallContext(ctx, &result, "txpool_contentFrom", addr); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// TypedInspect is Inspect with typed addresses and nonces and parsed
+// summaries.
+func (t *TxPool) TypedInspect(ctx context.Context) (*PoolInspect, error) {
+	var result map[string]map[common.Address]map[uint64]string
+	if err := t.c.CallContext(ctx, &result, "txpool_inspect"); err != nil {
+		return nil, err
+	}
+	inspect := &PoolInspect{}
+	var err error
+	if inspect.Pending, err = parsePoolSummaries(result["pending"]); err != nil {
+		return nil, err
+	}
+	if inspect.Queued, err = parsePoolSummaries(result["queued"]); err != nil {
+		return nil, err
+	}
+	return inspect, nil
+}
+
+func parsePoolSummaries(summaries map[common.Address]map[uint64]string) (map[common.Address]map[uint64]*PoolSummary, error) {
+	parsed := make(map[common.Address]map[uint64]*PoolSummary, len(summaries))
+	for sender, txs := range summaries {
+		parsed[sender] = make(map[uint64]*PoolSummary, len(txs))
+		for nonce, summary := range txs {
+			s, err := ParsePoolSummary(summary)
+			if err != nil {
+				return nil, fmt.Errorf("%s nonce %d: %w", sender.Hex(), nonce, err)
+			}
+			parsed[sender][nonce] = s
+		}
+	}
+	return parsed, nil
+}
+
+// ParsePoolSummary parses a txpool_inspect summary such as
+// "0x00…aa: 1 wei + 21000 gas × 2000000000 wei" or
+// "contract creation: 0 wei + 53000 gas × 1 wei". An ASCII x is accepted in
+// place of the multiplication sign.
+func ParsePoolSummary(s string) (*PoolSummary, error) {
+	target, rest, ok := strings.Cut(s, ": ")
+	if !ok {
+		return nil, fmt.Errorf("invalid pool summary %q", s)
+	}
+	summary := &PoolSummary{}
+	if target != "contract creation" {
+		if !common.IsHexAddress(target) {
+			return nil, fmt.Errorf("invalid pool summary %q: bad recipient", s)
+		}
+		to := common.HexToAddress(target)
+		summary.To = &to
+	}
+	fields := strings.Fields(rest)
+	if len(fields) != 8 || fields[1] != "wei" || fields[2] != "+" || fields[4] != "gas" || (fields[5] != "×" && fields[5] != "x") || fields[7] != "wei" {
+		return nil, fmt.Errorf("invalid pool summary %q", s)
+	}
+	var ok1, ok2 bool
+	summary.Value, ok1 = new(big.Int).SetString(fields[0], 10)
+	summary.GasPrice, ok2 = new(big.Int).SetString(fields[6], 10)
+	gas, err := strconv.ParseUint(fields[3], 10, 64)
+	if !ok1 || !ok2 || err != nil {
+		return nil, fmt.Errorf("invalid pool summary %q: bad number", s)
+	}
+	summary.Gas = gas
+	return summary, nil
+}
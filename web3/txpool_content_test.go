@@ -0,0 +1,89 @@
+package web3_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/moonfdd/web3-go/web3"
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+func TestParsePoolSummary(t *testing.T) {
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	summary, err := web3.ParsePoolSummary(to.Hex() + ": 1 wei + 21000 gas × 2000000000 wei")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.To == nil || *summary.To != to || summary.Value.Int64() != 1 || summary.Gas != 21000 || summary.GasPrice.Int64() != 2000000000 {
+		t.Errorf("ParsePoolSummary = %+v", summary)
+	}
+	summary, err = web3.ParsePoolSummary("contract creation: 0 wei + 53000 gas x 1 wei")
+	if err != nil || summary.To != nil || summary.Gas != 53000 {
+		t.Errorf("ParsePoolSummary of a creation = %+v %v", summary, err)
+	}
+	for _, s := range []string{"", "contract creation", "0x01: 1 wei + 21000 gas × 1 wei", to.Hex() + ": 1 wei + 21000 gas × 1", to.Hex() + ": a wei + 21000 gas × 1 wei"} {
+		if _, err := web3.ParsePoolSummary(s); err == nil {
+			t.Errorf("ParsePoolSummary(%q) succeeded", s)
+		}
+	}
+}
+
+func TestTypedTxPoolDevNode(t *testing.T) {
+	node := web3test.New(t)
+	w := node.Web3
+	ctx := context.Background()
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	pending := node.SendTx(t, &to, big.NewInt(1), nil)
+
+	// nonce 2 is queued behind the missing nonce 1
+	config := node.Eth.BlockChain().Config()
+	queued, err := types.SignNewTx(node.Key, types.LatestSigner(config), &types.DynamicFeeTx{
+		ChainID:   config.ChainID,
+		Nonce:     2,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(1e12),
+		Gas:       21000,
+		To:        &to,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := queued.MarshalBinary()
+	if _, err := w.Eth.SendRawTransaction(ctx, raw); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := w.TxPool.TypedContent(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx := content.Pending[node.Account][0]; tx == nil || tx.Hash != pending.Hash() {
+		t.Errorf("TypedContent pending = %v", content.Pending)
+	}
+	if tx := content.Queued[node.Account][2]; tx == nil || tx.Hash != queued.Hash() {
+		t.Errorf("TypedContent queued = %v", content.Queued)
+	}
+	from, err := w.TxPool.TypedContentFrom(ctx, node.Account)
+	if err != nil || from.Pending[0] == nil || from.Queued[2] == nil {
+		t.Errorf("TypedContentFrom = %v %v", from, err)
+	}
+	inspect, err := w.TxPool.TypedInspect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary := inspect.Queued[node.Account][2]; summary == nil || *summary.To != to || summary.Gas != 21000 || summary.GasPrice.Cmp(big.NewInt(1e12)) != 0 {
+		t.Errorf("TypedInspect queued = %+v", summary)
+	}
+
+	report, err := w.AnalyzePool(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Pending != 1 || report.Queued != 1 || len(report.NonceGaps) != 1 || report.NonceGaps[0] != (web3.NonceGap{Sender: node.Account, First: 1, Last: 1}) {
+		t.Errorf("AnalyzePool = %+v", report)
+	}
+}
@@ -0,0 +1,58 @@
+package web3_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+func TestTxPoolDevNode(t *testing.T) {
+	node := web3test.New(t)
+	w := node.Web3
+	ctx := context.Background()
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	tx := node.SendTx(t, &to, big.NewInt(1), nil)
+	from := node.Account.Hex()
+
+	status, err := w.TxPool.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if status["pending"] != 1 || status["queued"] != 0 {
+		t.Errorf("Status = %v, want 1 pending", status)
+	}
+	content, err := w.TxPool.Content(ctx)
+	if err != nil {
+		t.Fatalf("Content: %v", err)
+	}
+	if got := content["pending"][from]["0"]; got == nil || got.Hash != tx.Hash() {
+		t.Errorf("Content pending = %v, want %v", content["pending"], tx.Hash())
+	}
+	contentFrom, err := w.TxPool.ContentFrom(ctx, node.Account)
+	if err != nil {
+		t.Fatalf("ContentFrom: %v", err)
+	}
+	if got := contentFrom["pending"]["0"]; got == nil || got.Hash != tx.Hash() || (*big.Int)(got.Value).Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("ContentFrom pending = %v", contentFrom["pending"])
+	}
+	inspect, err := w.TxPool.Inspect(ctx)
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if summary := inspect["pending"][from]["0"]; summary == "" {
+		t.Errorf("Inspect pending = %v", inspect["pending"])
+	}
+
+	node.Commit(t)
+	status, err = w.TxPool.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status["pending"] != 0 {
+		t.Errorf("Status after commit = %v, want empty", status)
+	}
+}
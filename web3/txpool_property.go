@@ -4,19 +4,25 @@ import (
 	"context"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/rpc"
 )
 
 type TxPool struct {
-	c *rpc.Client
+	c    Client
+	gate *capabilityGate
 }
 
-func NewTxPool(c *rpc.Client) *TxPool {
+func NewTxPool(c Client) *TxPool {
 	e := &TxPool{}
-	e.c = c
+	e.c = withCallErrors(c)
 	return e
 }
 
+// Available reports whether the node serves the txpool namespace. It is true
+// until Web3.Capabilities has probed the node.
+func (t *TxPool) Available() bool {
+	return t.gate.available("txpool")
+}
+
 // Content returns the transactions contained within the transaction pool.
 // from TxPoolAPI
 // from web3ext.go
@@ -0,0 +1,369 @@
+package web3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// StuckReason classifies why a transaction of the pool is not mined.
+type StuckReason string
+
+const (
+	// StuckNonceGap is a transaction queued behind a nonce missing from the
+	// pool.
+	StuckNonceGap StuckReason = "nonce-gap"
+	// StuckUnderpriced is a transaction whose fee cap is below the base fee
+	// of the next block.
+	StuckUnderpriced StuckReason = "underpriced"
+	// StuckLowTip is a transaction whose tip is below what recent blocks
+	// paid, see StuckDetectorConfig.TipPercentile.
+	StuckLowTip StuckReason = "low-tip"
+	// StuckExceedsBalance is a transaction whose cost, with the cost of the
+	// transactions before it, exceeds the balance of the sender.
+	StuckExceedsBalance StuckReason = "exceeds-balance"
+)
+
+// StuckTx is a transaction of the pool that is not expected to be mined, with
+// the reasons why.
+type StuckTx struct {
+	Tx      *RPCTransaction `json:"tx"`
+	Nonce   uint64          `json:"nonce"`
+	Queued  bool            `json:"queued"`
+	Reasons []StuckReason   `json:"reasons"`
+	// Explanations has one human readable sentence per reason.
+	Explanations []string `json:"explanations"`
+}
+
+// AccountStatus is the pool status of one account, see
+// StuckDetector.Check.
+type AccountStatus struct {
+	Account common.Address `json:"account"`
+	// LatestNonce is the nonce of the next transaction to be mined, and
+	// PendingNonce the one after the transactions the pool can execute.
+	LatestNonce  uint64   `json:"latestNonce"`
+	PendingNonce uint64   `json:"pendingNonce"`
+	Balance      *big.Int `json:"balance"`
+	// Gaps are the nonces missing from the pool from LatestNonce up to the
+	// highest nonce of the account.
+	Gaps  []NonceGap `json:"gaps"`
+	Stuck []StuckTx  `json:"stuck"`
+}
+
+// FeeMarket is the fee level a transaction needs to be mined soon.
+type FeeMarket struct {
+	// BaseFee is the base fee of the next block, nil before London.
+	BaseFee *big.Int `json:"baseFee"`
+	// Tip is the priority fee recent blocks paid, or the gas price before
+	// London.
+	Tip *big.Int `json:"tip"`
+}
+
+// feeCap returns the fee cap a new transaction should offer, leaving room
+// for the base fee to double.
+func (m *FeeMarket) feeCap() *big.Int {
+	if m.BaseFee == nil {
+		return new(big.Int).Set(m.Tip)
+	}
+	feeCap := new(big.Int).Mul(m.BaseFee, big.NewInt(2))
+	return feeCap.Add(feeCap, m.Tip)
+}
+
+// DiagnoseAccount explains why the transactions of an account in the pool are
+// stuck. latestNonce is the transaction count of the account at the latest
+// block and balance its balance there.
+func DiagnoseAccount(account common.Address, latestNonce uint64, balance *big.Int, content *PoolAccountContent, market *FeeMarket) *AccountStatus {
+	status := &AccountStatus{Account: account, LatestNonce: latestNonce, PendingNonce: latestNonce, Balance: balance}
+	txs := make(map[uint64]*RPCTransaction)
+	queued := make(map[uint64]bool)
+	for nonce, tx := range content.Pending {
+		txs[nonce] = tx
+	}
+	for nonce, tx := range content.Queued {
+		txs[nonce] = tx
+		queued[nonce] = true
+	}
+	nonces := make([]uint64, 0, len(txs))
+	for nonce := range txs {
+		if nonce >= latestNonce {
+			nonces = append(nonces, nonce)
+		}
+	}
+	sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+
+	next := latestNonce
+	spent := new(big.Int)
+	for _, nonce := range nonces {
+		if nonce > next {
+			status.Gaps = append(status.Gaps, NonceGap{Sender: account, First: next, Last: nonce - 1})
+		}
+		next = nonce + 1
+	}
+	if len(status.Gaps) == 0 {
+		status.PendingNonce = next
+	} else {
+		status.PendingNonce = status.Gaps[0].First
+	}
+
+	for _, nonce := range nonces {
+		tx := txs[nonce]
+		stuck := StuckTx{Tx: tx, Nonce: nonce, Queued: queued[nonce]}
+		explain := func(reason StuckReason, format string, args ...interface{}) {
+			stuck.Reasons = append(stuck.Reasons, reason)
+			stuck.Explanations = append(stuck.Explanations, fmt.Sprintf(format, args...))
+		}
+		if nonce > status.PendingNonce {
+			gap := status.Gaps[0]
+			for _, g := range status.Gaps {
+				if g.First < nonce {
+					gap = g
+				}
+			}
+			if gap.First == gap.Last {
+				explain(StuckNonceGap, "nonce %d waits for the missing nonce %d", nonce, gap.First)
+			} else {
+				explain(StuckNonceGap, "nonce %d waits for the missing nonces %d to %d", nonce, gap.First, gap.Last)
+			}
+		}
+		feeCap, tipCap := poolFees(tx)
+		if market.BaseFee != nil && feeCap.Cmp(market.BaseFee) < 0 {
+			explain(StuckUnderpriced, "fee cap %v wei is below the base fee %v wei", feeCap, market.BaseFee)
+		} else if tip := effectiveTip(feeCap, tipCap, market.BaseFee); tip.Cmp(market.Tip) < 0 {
+			explain(StuckLowTip, "tip %v wei is below the recent tip %v wei", tip, market.Tip)
+		}
+		cost := new(big.Int).Mul(feeCap, new(big.Int).SetUint64(uint64(tx.Gas)))
+		if tx.Value != nil {
+			cost.Add(cost, tx.Value.ToInt())
+		}
+		spent.Add(spent, cost)
+		if balance != nil && spent.Cmp(balance) > 0 {
+			explain(StuckExceedsBalance, "costs %v wei with the transactions before it, more than the balance %v wei", spent, balance)
+		}
+		if len(stuck.Reasons) > 0 {
+			status.Stuck = append(status.Stuck, stuck)
+		}
+	}
+	return status
+}
+
+// StuckDetectorConfig tunes a StuckDetector. Zero values get defaults.
+type StuckDetectorConfig struct {
+	// FeeHistoryBlocks is the number of recent blocks the tip is taken
+	// from, 20 by default.
+	FeeHistoryBlocks uint64
+	// TipPercentile is the reward percentile of those blocks a transaction
+	// must tip to not be reported, 50 by default.
+	TipPercentile float64
+}
+
+// StuckDetector finds stuck and underpriced transactions of accounts with
+// TxPool.ContentFrom, the latest and pending transaction counts and
+// FeeHistory, and rescues them with zero-value self-transfers.
+type StuckDetector struct {
+	eth    *Eth
+	txpool *TxPool
+	config StuckDetectorConfig
+}
+
+// NewStuckDetector returns a detector for the node of w. Rescue sends its
+// transactions with w.Eth, set a Signer with Eth.WithSigner to sign them
+// locally.
+func NewStuckDetector(w *Web3, config StuckDetectorConfig) *StuckDetector {
+	if config.FeeHistoryBlocks == 0 {
+		config.FeeHistoryBlocks = 20
+	}
+	if config.TipPercentile == 0 {
+		config.TipPercentile = 50
+	}
+	d := &StuckDetector{}
+	d.eth = w.Eth
+	d.txpool = w.TxPool
+	d.config = config
+	return d
+}
+
+// FeeMarket returns the base fee of the next block and the median of the
+// TipPercentile rewards of the last FeeHistoryBlocks blocks.
+func (d *StuckDetector) FeeMarket(ctx context.Context) (*FeeMarket, error) {
+	history, err := d.eth.FeeHistory(ctx, math.HexOrDecimal64(d.config.FeeHistoryBlocks), rpc.LatestBlockNumber, []float64{d.config.TipPercentile})
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		return nil, errors.New("no fee history")
+	}
+	market := &FeeMarket{}
+	if n := len(history.BaseFee); n > 0 && history.BaseFee[n-1] != nil && history.BaseFee[n-1].ToInt().Sign() > 0 {
+		market.BaseFee = new(big.Int).Set(history.BaseFee[n-1].ToInt())
+	}
+	if market.BaseFee == nil {
+		price, err := d.eth.GasPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+		market.Tip = price.ToInt()
+		return market, nil
+	}
+	var tips FeeDistribution
+	for _, rewards := range history.Reward {
+		if len(rewards) > 0 && rewards[0] != nil {
+			tips = append(tips, rewards[0].ToInt())
+		}
+	}
+	sort.Slice(tips, func(i, j int) bool { return tips[i].Cmp(tips[j]) < 0 })
+	if market.Tip = tips.Percentile(50); market.Tip == nil || market.Tip.Sign() == 0 {
+		tip, err := d.eth.MaxPriorityFeePerGas(ctx)
+		if err != nil {
+			return nil, err
+		}
+		market.Tip = tip.ToInt()
+	}
+	return market, nil
+}
+
+// Check returns the pool status of account with the reasons each of its
+// stuck transactions is stuck.
+func (d *StuckDetector) Check(ctx context.Context, account common.Address) (*AccountStatus, error) {
+	market, err := d.FeeMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return d.check(ctx, account, market)
+}
+
+// CheckAll is Check for several accounts, sharing one fee market.
+func (d *StuckDetector) CheckAll(ctx context.Context, accounts []common.Address) ([]*AccountStatus, error) {
+	market, err := d.FeeMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]*AccountStatus, 0, len(accounts))
+	for _, account := range accounts {
+		status, err := d.check(ctx, account, market)
+		if err != nil {
+			return statuses, fmt.Errorf("%s: %w", account.Hex(), err)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (d *StuckDetector) check(ctx context.Context, account common.Address, market *FeeMarket) (*AccountStatus, error) {
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	latestNonce, err := d.eth.GetTransactionCount(ctx, account, latest)
+	if err != nil {
+		return nil, err
+	}
+	pendingNonce, err := d.eth.GetTransactionCount(ctx, account, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber))
+	if err != nil {
+		return nil, err
+	}
+	balance, err := d.eth.GetBalance(ctx, account, latest)
+	if err != nil {
+		return nil, err
+	}
+	content, err := d.txpool.TypedContentFrom(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	status := DiagnoseAccount(account, uint64(latestNonce), balance.ToInt(), content, market)
+	status.PendingNonce = uint64(pendingNonce)
+	return status, nil
+}
+
+// RescueOptions selects what StuckDetector.Rescue does.
+type RescueOptions struct {
+	// FillGaps sends a self-transfer for every missing nonce.
+	FillGaps bool
+	// BumpFees replaces every transaction stuck for another reason than a
+	// gap by a self-transfer paying the current fee market, and at least
+	// PoolPriceBump percent more than the transaction. The replaced
+	// transaction is cancelled.
+	BumpFees bool
+}
+
+// RescueAction is a self-transfer sent by StuckDetector.Rescue.
+type RescueAction struct {
+	Nonce uint64 `json:"nonce"`
+	// Replaced is the hash of the cancelled transaction, the zero hash when
+	// a gap was filled.
+	Replaced common.Hash `json:"replaced"`
+	Hash     common.Hash `json:"hash"`
+}
+
+// Rescue sends zero-value self-transfers for the gaps and stuck transactions
+// of status, as selected by opts, in nonce order. It returns the actions
+// taken until the first failure.
+func (d *StuckDetector) Rescue(ctx context.Context, status *AccountStatus, opts RescueOptions) ([]RescueAction, error) {
+	market, err := d.FeeMarket(ctx)
+	if err != nil {
+		return nil, err
+	}
+	type rescue struct {
+		nonce             uint64
+		replaced          *RPCTransaction
+		minFeeCap, minTip *big.Int
+	}
+	var rescues []rescue
+	if opts.FillGaps {
+		for _, gap := range status.Gaps {
+			for nonce := gap.First; nonce <= gap.Last; nonce++ {
+				rescues = append(rescues, rescue{nonce: nonce})
+			}
+		}
+	}
+	if opts.BumpFees {
+		for _, stuck := range status.Stuck {
+			if len(stuck.Reasons) == 1 && stuck.Reasons[0] == StuckNonceGap {
+				continue
+			}
+			feeCap, tipCap := poolFees(stuck.Tx)
+			rescues = append(rescues, rescue{nonce: stuck.Nonce, replaced: stuck.Tx, minFeeCap: bumpFee(feeCap), minTip: bumpFee(tipCap)})
+		}
+	}
+	sort.Slice(rescues, func(i, j int) bool { return rescues[i].nonce < rescues[j].nonce })
+
+	var actions []RescueAction
+	for _, r := range rescues {
+		tip, feeCap := new(big.Int).Set(market.Tip), market.feeCap()
+		if r.replaced != nil {
+			tip = math.BigMax(tip, r.minTip)
+			feeCap = math.BigMax(feeCap, r.minFeeCap)
+		}
+		if feeCap.Cmp(tip) < 0 {
+			feeCap.Set(tip)
+		}
+		nonce := hexutil.Uint64(r.nonce)
+		gas := hexutil.Uint64(21000)
+		args := TransactionArgs{
+			From:  &status.Account,
+			To:    &status.Account,
+			Value: new(hexutil.Big),
+			Nonce: &nonce,
+			Gas:   &gas,
+		}
+		if market.BaseFee != nil {
+			args.MaxFeePerGas = (*hexutil.Big)(feeCap)
+			args.MaxPriorityFeePerGas = (*hexutil.Big)(tip)
+		} else {
+			args.GasPrice = (*hexutil.Big)(feeCap)
+		}
+		hash, err := d.eth.SendTransaction(ctx, args)
+		if err != nil {
+			return actions, fmt.Errorf("nonce %d: %w", r.nonce, err)
+		}
+		action := RescueAction{Nonce: r.nonce, Hash: hash}
+		if r.replaced != nil {
+			action.Replaced = r.replaced.Hash
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
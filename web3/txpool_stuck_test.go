@@ -0,0 +1,110 @@
+package web3_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+func TestDiagnoseAccount(t *testing.T) {
+	account := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	expensive := poolTx(9, 200, 10)
+	expensive.Gas = 21000
+	expensive.Value = (*hexutil.Big)(big.NewInt(1e6))
+	content := &web3.PoolAccountContent{
+		Pending: map[uint64]*web3.RPCTransaction{3: poolTx(3, 200, 10), 4: poolTx(4, 90, 10)},
+		Queued:  map[uint64]*web3.RPCTransaction{6: poolTx(6, 200, 10), 9: expensive},
+	}
+	status := web3.DiagnoseAccount(account, 3, big.NewInt(1e6), content, &web3.FeeMarket{BaseFee: big.NewInt(100), Tip: big.NewInt(10)})
+	wantGaps := []web3.NonceGap{{Sender: account, First: 5, Last: 5}, {Sender: account, First: 7, Last: 8}}
+	if len(status.Gaps) != 2 || status.Gaps[0] != wantGaps[0] || status.Gaps[1] != wantGaps[1] || status.PendingNonce != 5 {
+		t.Errorf("Gaps = %v, pending nonce %d, want %v and 5", status.Gaps, status.PendingNonce, wantGaps)
+	}
+	want := map[uint64][]web3.StuckReason{
+		4: {web3.StuckUnderpriced},
+		6: {web3.StuckNonceGap},
+		9: {web3.StuckNonceGap, web3.StuckExceedsBalance},
+	}
+	if len(status.Stuck) != len(want) {
+		t.Fatalf("Stuck = %+v", status.Stuck)
+	}
+	for _, stuck := range status.Stuck {
+		reasons := want[stuck.Nonce]
+		if len(stuck.Reasons) != len(reasons) || len(stuck.Explanations) != len(reasons) {
+			t.Errorf("nonce %d: reasons %v, want %v", stuck.Nonce, stuck.Reasons, reasons)
+			continue
+		}
+		for i := range reasons {
+			if stuck.Reasons[i] != reasons[i] {
+				t.Errorf("nonce %d: reasons %v, want %v", stuck.Nonce, stuck.Reasons, reasons)
+			}
+		}
+	}
+	if got := status.Stuck[2].Explanations[0]; got != "nonce 9 waits for the missing nonces 7 to 8" {
+		t.Errorf("explanation = %q", got)
+	}
+	if !status.Stuck[1].Queued || status.Stuck[0].Queued {
+		t.Errorf("Queued = %v %v", status.Stuck[0].Queued, status.Stuck[1].Queued)
+	}
+}
+
+func TestStuckDetectorDevNode(t *testing.T) {
+	node := web3test.New(t)
+	w := node.Web3
+	ctx := context.Background()
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	node.SendTx(t, &to, big.NewInt(1), nil)
+	config := node.Eth.BlockChain().Config()
+	send := func(nonce uint64, feeCap, tipCap *big.Int) *types.Transaction {
+		tx, err := types.SignNewTx(node.Key, types.LatestSigner(config), &types.DynamicFeeTx{
+			ChainID:   config.ChainID,
+			Nonce:     nonce,
+			GasTipCap: tipCap,
+			GasFeeCap: feeCap,
+			Gas:       21000,
+			To:        &to,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, _ := tx.MarshalBinary()
+		if _, err := w.Eth.SendRawTransaction(ctx, raw); err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	send(2, big.NewInt(1e12), big.NewInt(1e9))
+	underpriced := send(3, big.NewInt(2), big.NewInt(1))
+
+	detector := web3.NewStuckDetector(w, web3.StuckDetectorConfig{})
+	status, err := detector.Check(ctx, node.Account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.LatestNonce != 0 || status.PendingNonce != 1 || len(status.Gaps) != 1 || status.Gaps[0].First != 1 || status.Gaps[0].Last != 1 {
+		t.Errorf("Check = nonces %d %d, gaps %v", status.LatestNonce, status.PendingNonce, status.Gaps)
+	}
+	if len(status.Stuck) != 2 || status.Stuck[0].Nonce != 2 || status.Stuck[1].Tx.Hash != underpriced.Hash() || status.Stuck[1].Reasons[1] != web3.StuckUnderpriced {
+		t.Fatalf("Stuck = %+v", status.Stuck)
+	}
+
+	actions, err := detector.Rescue(ctx, status, web3.RescueOptions{FillGaps: true, BumpFees: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 2 || actions[0].Nonce != 1 || actions[0].Replaced != (common.Hash{}) || actions[1].Nonce != 3 || actions[1].Replaced != underpriced.Hash() {
+		t.Errorf("Rescue = %+v", actions)
+	}
+	node.Commit(t)
+	if nonce, err := w.Eth.GetTransactionCount(ctx, node.Account, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)); err != nil || nonce != 4 {
+		t.Errorf("nonce after rescue = %d %v, want 4", nonce, err)
+	}
+}
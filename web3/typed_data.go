@@ -0,0 +1,533 @@
+package web3
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+const eip712Domain = "EIP712Domain"
+
+// TypedDataField is a member of an EIP-712 struct type.
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TypedDataTypes are the struct types of typed data, by name.
+type TypedDataTypes map[string][]TypedDataField
+
+// TypedDataDomain is the domain of typed data. If the types do not list the
+// fields of EIP712Domain, the set fields make up the domain.
+type TypedDataDomain struct {
+	Name              string                `json:"name,omitempty"`
+	Version           string                `json:"version,omitempty"`
+	ChainID           *math.HexOrDecimal256 `json:"chainId,omitempty"`
+	VerifyingContract *common.Address       `json:"verifyingContract,omitempty"`
+	Salt              *common.Hash          `json:"salt,omitempty"`
+}
+
+// fields returns the EIP712Domain type of the set fields, in the order of
+// the specification.
+func (d *TypedDataDomain) fields() []TypedDataField {
+	var fields []TypedDataField
+	if d.Name != "" {
+		fields = append(fields, TypedDataField{"name", "string"})
+	}
+	if d.Version != "" {
+		fields = append(fields, TypedDataField{"version", "string"})
+	}
+	if d.ChainID != nil {
+		fields = append(fields, TypedDataField{"chainId", "uint256"})
+	}
+	if d.VerifyingContract != nil {
+		fields = append(fields, TypedDataField{"verifyingContract", "address"})
+	}
+	if d.Salt != nil {
+		fields = append(fields, TypedDataField{"salt", "bytes32"})
+	}
+	return fields
+}
+
+// TypedData is an EIP-712 typed data document, as taken by
+// eth_signTypedData_v4. Decoded from JSON, the numbers of the message are
+// json.Number values, so large integers keep their precision. Message values
+// may also be Go values that marshal to the JSON form, such as *big.Int or
+// common.Address. Integers are decimal or 0x prefixed hexadecimal and must
+// fit their type. Arrays may be fixed or dynamic and nested. The atomic and
+// dynamic values are encoded by go-ethereum's signer/core/apitypes, the
+// encoder of Clef, which does not know fixed or nested arrays.
+type TypedData struct {
+	Types       TypedDataTypes         `json:"types"`
+	PrimaryType string                 `json:"primaryType"`
+	Domain      TypedDataDomain        `json:"domain"`
+	Message     map[string]interface{} `json:"message"`
+}
+
+// ParseTypedData decodes a typed data JSON document.
+func ParseTypedData(input []byte) (*TypedData, error) {
+	var data TypedData
+	if err := json.Unmarshal(input, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+func (d *TypedData) UnmarshalJSON(input []byte) error {
+	type typedData TypedData
+	dec := json.NewDecoder(bytes.NewReader(input))
+	dec.UseNumber()
+	return dec.Decode((*typedData)(d))
+}
+
+// MarshalJSON adds the EIP712Domain type if it is missing, as wallets
+// require it.
+func (d TypedData) MarshalJSON() ([]byte, error) {
+	type typedData TypedData
+	if _, ok := d.Types[eip712Domain]; !ok {
+		types := make(TypedDataTypes, len(d.Types)+1)
+		for name, fields := range d.Types {
+			types[name] = fields
+		}
+		types[eip712Domain] = d.Domain.fields()
+		d.Types = types
+	}
+	message, err := normalizeTypedValue(d.Message)
+	if err != nil {
+		return nil, err
+	}
+	d.Message, _ = message.(map[string]interface{})
+	return json.Marshal(typedData(d))
+}
+
+func (d *TypedData) fields(typ string) ([]TypedDataField, bool) {
+	if fields, ok := d.Types[typ]; ok {
+		return fields, true
+	}
+	if typ == eip712Domain {
+		return d.Domain.fields(), true
+	}
+	return nil, false
+}
+
+// message returns the domain as a message of the EIP712Domain type.
+func (d *TypedDataDomain) message() map[string]interface{} {
+	message := make(map[string]interface{})
+	if d.Name != "" {
+		message["name"] = d.Name
+	}
+	if d.Version != "" {
+		message["version"] = d.Version
+	}
+	if d.ChainID != nil {
+		message["chainId"] = (*big.Int)(d.ChainID)
+	}
+	if d.VerifyingContract != nil {
+		message["verifyingContract"] = d.VerifyingContract.Hex()
+	}
+	if d.Salt != nil {
+		message["salt"] = d.Salt.Hex()
+	}
+	return message
+}
+
+// typedPrimitives encodes the atomic and dynamic values with the encoder of
+// Clef.
+var typedPrimitives apitypes.TypedData
+
+// typedArray splits an array type into the type of its elements and its
+// length, -1 for a dynamic array.
+func typedArray(typ string) (elem string, length int, ok bool) {
+	i := strings.LastIndexByte(typ, '[')
+	if i <= 0 || !strings.HasSuffix(typ, "]") {
+		return "", 0, false
+	}
+	size := typ[i+1 : len(typ)-1]
+	if size == "" {
+		return typ[:i], -1, true
+	}
+	if !isDecimal(size) {
+		return "", 0, false
+	}
+	length, err := strconv.Atoi(size)
+	if err != nil || length == 0 {
+		return "", 0, false
+	}
+	return typ[:i], length, true
+}
+
+// typedBaseType strips the array dimensions of typ.
+func typedBaseType(typ string) string {
+	for {
+		elem, _, ok := typedArray(typ)
+		if !ok {
+			return typ
+		}
+		typ = elem
+	}
+}
+
+// isDecimal reports whether s is a decimal number without sign or leading
+// zeros.
+func isDecimal(s string) bool {
+	if s == "" || s[0] == '0' {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// typedIntegerBits returns the size of an integer type and whether it is
+// signed.
+func typedIntegerBits(typ string) (bits int, signed, ok bool) {
+	size := strings.TrimPrefix(typ, "uint")
+	if size == typ {
+		size, signed = strings.TrimPrefix(typ, "int"), true
+		if size == typ {
+			return 0, false, false
+		}
+	}
+	if size == "" {
+		return 256, signed, true
+	}
+	if !isDecimal(size) {
+		return 0, false, false
+	}
+	bits, _ = strconv.Atoi(size)
+	if bits > 256 || bits%8 != 0 {
+		return 0, false, false
+	}
+	return bits, signed, true
+}
+
+// isTypedAtomic reports whether typ is an atomic or dynamic type of EIP-712.
+func isTypedAtomic(typ string) bool {
+	switch typ {
+	case "address", "bool", "string", "bytes":
+		return true
+	}
+	if size := strings.TrimPrefix(typ, "bytes"); size != typ {
+		n, _ := strconv.Atoi(size)
+		return isDecimal(size) && n <= 32
+	}
+	_, _, ok := typedIntegerBits(typ)
+	return ok
+}
+
+// dependencies returns typ followed by the struct types it references, at
+// any depth.
+func (d *TypedData) dependencies(typ string, found []string) ([]string, error) {
+	typ = typedBaseType(typ)
+	for _, name := range found {
+		if name == typ {
+			return found, nil
+		}
+	}
+	fields, ok := d.fields(typ)
+	if !ok {
+		if isTypedAtomic(typ) {
+			return found, nil
+		}
+		return nil, fmt.Errorf("unknown type %q", typ)
+	}
+	found = append(found, typ)
+	for _, field := range fields {
+		var err error
+		if found, err = d.dependencies(field.Type, found); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// typedInteger parses a JSON number. JSON numbers are decimal, but may use
+// an exponent, such as 1e18.
+func typedInteger(number json.Number) (*big.Int, error) {
+	if n, ok := new(big.Int).SetString(number.String(), 10); ok {
+		return n, nil
+	}
+	f, ok := new(big.Float).SetPrec(512).SetString(number.String())
+	if !ok || !f.IsInt() {
+		return nil, fmt.Errorf("%s is not an integer", number)
+	}
+	n, _ := f.Int(nil)
+	return n, nil
+}
+
+// encodeInteger encodes an integer of a normalized message, a JSON number or
+// a decimal or 0x prefixed hexadecimal string, after checking that it fits
+// typ.
+func encodeInteger(typ string, value interface{}) ([]byte, error) {
+	bits, signed, _ := typedIntegerBits(typ)
+	var n *big.Int
+	switch v := value.(type) {
+	case json.Number:
+		var err error
+		if n, err = typedInteger(v); err != nil {
+			return nil, err
+		}
+	case float64:
+		f := big.NewFloat(v)
+		if !f.IsInt() {
+			return nil, fmt.Errorf("%v is not an integer", v)
+		}
+		n, _ = f.Int(nil)
+	case *big.Int:
+		n = v
+	case string:
+		digits, base := v, 10
+		if hex := strings.TrimPrefix(v, "0x"); hex != v {
+			digits, base = hex, 16
+		}
+		var ok bool
+		n, ok = new(big.Int).SetString(digits, base)
+		if !ok || digits[0] == '+' || base == 16 && digits[0] == '-' {
+			return nil, fmt.Errorf("%q is not a decimal or 0x prefixed hexadecimal integer", v)
+		}
+	default:
+		return nil, fmt.Errorf("%v is not an integer", value)
+	}
+	limit := new(big.Int).Lsh(common.Big1, uint(bits))
+	low := new(big.Int)
+	if signed {
+		limit.Rsh(limit, 1)
+		low.Neg(limit)
+	}
+	if n.Cmp(low) < 0 || n.Cmp(limit) >= 0 {
+		return nil, fmt.Errorf("%v out of range of %s", n, typ)
+	}
+	return math.U256Bytes(new(big.Int).Set(n)), nil
+}
+
+// encodeValue encodes a value of a normalized message into 32 bytes. Arrays,
+// fixed or dynamic, are the hash of the encodings of their elements and
+// structs the hash of their encoding.
+func (d *TypedData) encodeValue(typ string, value interface{}) ([]byte, error) {
+	if elem, length, ok := typedArray(typ); ok {
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%v is not a %s array", value, typ)
+		}
+		if length >= 0 && len(items) != length {
+			return nil, fmt.Errorf("%d elements for %s", len(items), typ)
+		}
+		var encoded []byte
+		for _, item := range items {
+			enc, err := d.encodeValue(elem, item)
+			if err != nil {
+				return nil, err
+			}
+			encoded = append(encoded, enc...)
+		}
+		return crypto.Keccak256(encoded), nil
+	}
+	if _, ok := d.fields(typ); ok {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%v is not a %s", value, typ)
+		}
+		encoded, err := d.encodeData(typ, fields)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(encoded), nil
+	}
+	if _, _, ok := typedIntegerBits(typ); ok {
+		return encodeInteger(typ, value)
+	}
+	if !isTypedAtomic(typ) {
+		return nil, fmt.Errorf("unknown type %q", typ)
+	}
+	return typedPrimitives.EncodePrimitiveValue(typ, value, 1)
+}
+
+// encodeData encodes a struct of a normalized message.
+func (d *TypedData) encodeData(typ string, data map[string]interface{}) ([]byte, error) {
+	fields, _ := d.fields(typ)
+	for name := range data {
+		if !containsTypedField(fields, name) {
+			return nil, fmt.Errorf("%s has no field %q", typ, name)
+		}
+	}
+	typeHash, err := d.TypeHash(typ)
+	if err != nil {
+		return nil, err
+	}
+	encoded := typeHash.Bytes()
+	for _, field := range fields {
+		enc, err := d.encodeValue(field.Type, data[field.Name])
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", typ, field.Name, err)
+		}
+		encoded = append(encoded, enc...)
+	}
+	return encoded, nil
+}
+
+func containsTypedField(fields []TypedDataField, name string) bool {
+	for _, field := range fields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// EncodeType returns the encoding of a struct type followed by the types it
+// references, such as
+// "Mail(Person from,Person to,string contents)Person(string name,address wallet)".
+func (d *TypedData) EncodeType(primaryType string) (string, error) {
+	if _, ok := d.fields(primaryType); !ok {
+		return "", fmt.Errorf("unknown type %q", primaryType)
+	}
+	deps, err := d.dependencies(primaryType, nil)
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(deps[1:])
+	var b strings.Builder
+	for _, dep := range deps {
+		fields, _ := d.fields(dep)
+		b.WriteString(dep)
+		b.WriteByte('(')
+		for i, field := range fields {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(field.Type)
+			b.WriteByte(' ')
+			b.WriteString(field.Name)
+		}
+		b.WriteByte(')')
+	}
+	return b.String(), nil
+}
+
+// TypeHash returns the hash of the encoding of a struct type.
+func (d *TypedData) TypeHash(primaryType string) (common.Hash, error) {
+	encoded, err := d.EncodeType(primaryType)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash([]byte(encoded)), nil
+}
+
+// EncodeData returns the type hash of a struct followed by the encoding of
+// its fields, 32 bytes each.
+func (d *TypedData) EncodeData(primaryType string, data map[string]interface{}) ([]byte, error) {
+	if _, ok := d.fields(primaryType); !ok {
+		return nil, fmt.Errorf("unknown type %q", primaryType)
+	}
+	normalized, err := normalizeTypedValue(data)
+	if err != nil {
+		return nil, err
+	}
+	fields, _ := normalized.(map[string]interface{})
+	return d.encodeData(primaryType, fields)
+}
+
+// HashStruct returns the hash of the encoding of a struct.
+func (d *TypedData) HashStruct(primaryType string, data map[string]interface{}) (common.Hash, error) {
+	encoded, err := d.EncodeData(primaryType, data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// DomainSeparator returns the hash of the domain.
+func (d *TypedData) DomainSeparator() (common.Hash, error) {
+	return d.HashStruct(eip712Domain, d.Domain.message())
+}
+
+// Hash returns the hash that is signed: keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message)).
+func (d *TypedData) Hash() (common.Hash, error) {
+	separator, err := d.DomainSeparator()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if d.PrimaryType == eip712Domain {
+		return crypto.Keccak256Hash([]byte{0x19, 0x01}, separator[:]), nil
+	}
+	message, err := d.HashStruct(d.PrimaryType, d.Message)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash([]byte{0x19, 0x01}, separator[:], message[:]), nil
+}
+
+// SignTypedData signs typed data with a local key. The signature has a v
+// value of 27 or 28, like the one of eth_signTypedData_v4.
+func SignTypedData(key *ecdsa.PrivateKey, data *TypedData) ([]byte, error) {
+	hash, err := data.Hash()
+	if err != nil {
+		return nil, err
+	}
+	return signHash(key, hash)
+}
+
+// RecoverTypedData returns the address that signed typed data.
+func RecoverTypedData(data *TypedData, sig []byte) (common.Address, error) {
+	hash, err := data.Hash()
+	if err != nil {
+		return common.Address{}, err
+	}
+	return recoverHash(hash, sig)
+}
+
+// normalizeTypedValue turns a Go value into the form it has in a decoded
+// JSON document. Byte slices become hex strings rather than base64 ones.
+func normalizeTypedValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil, string, bool, json.Number, float64:
+		return v, nil
+	case []byte:
+		return hexutil.Encode(v), nil
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i := range v {
+			item, err := normalizeTypedValue(v[i])
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	case map[string]interface{}:
+		fields := make(map[string]interface{}, len(v))
+		for name := range v {
+			field, err := normalizeTypedValue(v[name])
+			if err != nil {
+				return nil, err
+			}
+			fields[name] = field
+		}
+		return fields, nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var normalized interface{}
+	if err := dec.Decode(&normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
@@ -0,0 +1,263 @@
+package web3
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+// Permit2Address is the address of the Uniswap Permit2 contract, the same on
+// every chain it is deployed to.
+var Permit2Address = common.HexToAddress("0x000000000022D473030F116dDEE9F6B43aC78BA3")
+
+// PermitTypes are the types of an EIP-2612 permit.
+var PermitTypes = TypedDataTypes{
+	"Permit": {
+		{Name: "owner", Type: "address"},
+		{Name: "spender", Type: "address"},
+		{Name: "value", Type: "uint256"},
+		{Name: "nonce", Type: "uint256"},
+		{Name: "deadline", Type: "uint256"},
+	},
+}
+
+var (
+	permit2Details = []TypedDataField{
+		{Name: "token", Type: "address"},
+		{Name: "amount", Type: "uint160"},
+		{Name: "expiration", Type: "uint48"},
+		{Name: "nonce", Type: "uint48"},
+	}
+	permit2TokenPermissions = []TypedDataField{
+		{Name: "token", Type: "address"},
+		{Name: "amount", Type: "uint256"},
+	}
+)
+
+// Permit2 types of the allowance transfer and signature transfer permits.
+var (
+	PermitSingleTypes = TypedDataTypes{
+		"PermitSingle": {
+			{Name: "details", Type: "PermitDetails"},
+			{Name: "spender", Type: "address"},
+			{Name: "sigDeadline", Type: "uint256"},
+		},
+		"PermitDetails": permit2Details,
+	}
+	PermitBatchTypes = TypedDataTypes{
+		"PermitBatch": {
+			{Name: "details", Type: "PermitDetails[]"},
+			{Name: "spender", Type: "address"},
+			{Name: "sigDeadline", Type: "uint256"},
+		},
+		"PermitDetails": permit2Details,
+	}
+	PermitTransferFromTypes = TypedDataTypes{
+		"PermitTransferFrom": {
+			{Name: "permitted", Type: "TokenPermissions"},
+			{Name: "spender", Type: "address"},
+			{Name: "nonce", Type: "uint256"},
+			{Name: "deadline", Type: "uint256"},
+		},
+		"TokenPermissions": permit2TokenPermissions,
+	}
+	PermitBatchTransferFromTypes = TypedDataTypes{
+		"PermitBatchTransferFrom": {
+			{Name: "permitted", Type: "TokenPermissions[]"},
+			{Name: "spender", Type: "address"},
+			{Name: "nonce", Type: "uint256"},
+			{Name: "deadline", Type: "uint256"},
+		},
+		"TokenPermissions": permit2TokenPermissions,
+	}
+)
+
+// PermitDomain returns the domain of the EIP-2612 permits of a token. Name is
+// the name of the token and version usually "1"; both must match what the
+// token hashes, see its DOMAIN_SEPARATOR.
+func PermitDomain(name, version string, chainID *big.Int, token common.Address) TypedDataDomain {
+	return TypedDataDomain{
+		Name:              name,
+		Version:           version,
+		ChainID:           (*math.HexOrDecimal256)(chainID),
+		VerifyingContract: &token,
+	}
+}
+
+// Permit2Domain returns the domain of Permit2 on a chain.
+func Permit2Domain(chainID *big.Int) TypedDataDomain {
+	permit2 := Permit2Address
+	return TypedDataDomain{
+		Name:              "Permit2",
+		ChainID:           (*math.HexOrDecimal256)(chainID),
+		VerifyingContract: &permit2,
+	}
+}
+
+// Permit is an EIP-2612 approval of value tokens of owner to spender, valid
+// until the deadline timestamp. Nonce is the nonces(owner) of the token.
+type Permit struct {
+	Owner    common.Address
+	Spender  common.Address
+	Value    *big.Int
+	Nonce    *big.Int
+	Deadline *big.Int
+}
+
+// TypedData returns the permit as typed data of the given domain, see
+// PermitDomain.
+func (p *Permit) TypedData(domain TypedDataDomain) *TypedData {
+	return &TypedData{
+		Types:       PermitTypes,
+		PrimaryType: "Permit",
+		Domain:      domain,
+		Message: map[string]interface{}{
+			"owner":    p.Owner.Hex(),
+			"spender":  p.Spender.Hex(),
+			"value":    bigString(p.Value),
+			"nonce":    bigString(p.Nonce),
+			"deadline": bigString(p.Deadline),
+		},
+	}
+}
+
+// PermitDetails is the allowance of a token granted by a Permit2 allowance
+// transfer permit. Expiration is a timestamp and Nonce the nonce of the
+// owner, token and spender in Permit2.
+type PermitDetails struct {
+	Token      common.Address
+	Amount     *big.Int
+	Expiration uint64
+	Nonce      uint64
+}
+
+func (d *PermitDetails) message() map[string]interface{} {
+	return map[string]interface{}{
+		"token":      d.Token.Hex(),
+		"amount":     bigString(d.Amount),
+		"expiration": new(big.Int).SetUint64(d.Expiration).String(),
+		"nonce":      new(big.Int).SetUint64(d.Nonce).String(),
+	}
+}
+
+// PermitSingle is a Permit2 allowance transfer permit for one token, whose
+// signature is valid until SigDeadline.
+type PermitSingle struct {
+	Details     PermitDetails
+	Spender     common.Address
+	SigDeadline *big.Int
+}
+
+// TypedData returns the permit as typed data of Permit2 on a chain.
+func (p *PermitSingle) TypedData(chainID *big.Int) *TypedData {
+	return &TypedData{
+		Types:       PermitSingleTypes,
+		PrimaryType: "PermitSingle",
+		Domain:      Permit2Domain(chainID),
+		Message: map[string]interface{}{
+			"details":     p.Details.message(),
+			"spender":     p.Spender.Hex(),
+			"sigDeadline": bigString(p.SigDeadline),
+		},
+	}
+}
+
+// PermitBatch is a Permit2 allowance transfer permit for several tokens.
+type PermitBatch struct {
+	Details     []PermitDetails
+	Spender     common.Address
+	SigDeadline *big.Int
+}
+
+// TypedData returns the permit as typed data of Permit2 on a chain.
+func (p *PermitBatch) TypedData(chainID *big.Int) *TypedData {
+	details := make([]interface{}, len(p.Details))
+	for i := range p.Details {
+		details[i] = p.Details[i].message()
+	}
+	return &TypedData{
+		Types:       PermitBatchTypes,
+		PrimaryType: "PermitBatch",
+		Domain:      Permit2Domain(chainID),
+		Message: map[string]interface{}{
+			"details":     details,
+			"spender":     p.Spender.Hex(),
+			"sigDeadline": bigString(p.SigDeadline),
+		},
+	}
+}
+
+// TokenPermissions is an amount of a token that a Permit2 signature transfer
+// permit lets the spender transfer.
+type TokenPermissions struct {
+	Token  common.Address
+	Amount *big.Int
+}
+
+func (t *TokenPermissions) message() map[string]interface{} {
+	return map[string]interface{}{
+		"token":  t.Token.Hex(),
+		"amount": bigString(t.Amount),
+	}
+}
+
+// PermitTransferFrom is a Permit2 signature transfer permit for one token.
+// Nonce is any unused value of the unordered nonces of the owner.
+type PermitTransferFrom struct {
+	Permitted TokenPermissions
+	Spender   common.Address
+	Nonce     *big.Int
+	Deadline  *big.Int
+}
+
+// TypedData returns the permit as typed data of Permit2 on a chain.
+func (p *PermitTransferFrom) TypedData(chainID *big.Int) *TypedData {
+	return &TypedData{
+		Types:       PermitTransferFromTypes,
+		PrimaryType: "PermitTransferFrom",
+		Domain:      Permit2Domain(chainID),
+		Message: map[string]interface{}{
+			"permitted": p.Permitted.message(),
+			"spender":   p.Spender.Hex(),
+			"nonce":     bigString(p.Nonce),
+			"deadline":  bigString(p.Deadline),
+		},
+	}
+}
+
+// PermitBatchTransferFrom is a Permit2 signature transfer permit for several
+// tokens.
+type PermitBatchTransferFrom struct {
+	Permitted []TokenPermissions
+	Spender   common.Address
+	Nonce     *big.Int
+	Deadline  *big.Int
+}
+
+// TypedData returns the permit as typed data of Permit2 on a chain.
+func (p *PermitBatchTransferFrom) TypedData(chainID *big.Int) *TypedData {
+	permitted := make([]interface{}, len(p.Permitted))
+	for i := range p.Permitted {
+		permitted[i] = p.Permitted[i].message()
+	}
+	return &TypedData{
+		Types:       PermitBatchTransferFromTypes,
+		PrimaryType: "PermitBatchTransferFrom",
+		Domain:      Permit2Domain(chainID),
+		Message: map[string]interface{}{
+			"permitted": permitted,
+			"spender":   p.Spender.Hex(),
+			"nonce":     bigString(p.Nonce),
+			"deadline":  bigString(p.Deadline),
+		},
+	}
+}
+
+// bigString formats an integer of a typed data message, nil being zero.
+func bigString(n *big.Int) string {
+	if n == nil {
+		return "0"
+	}
+	return n.String()
+}
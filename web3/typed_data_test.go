@@ -0,0 +1,275 @@
+package web3_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+)
+
+// mailTypedData is the example of EIP-712.
+const mailTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func TestTypedDataMail(t *testing.T) {
+	data, err := web3.ParseTypedData([]byte(mailTypedData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoded, err := data.EncodeType("Mail"); err != nil || encoded != "Mail(Person from,Person to,string contents)Person(string name,address wallet)" {
+		t.Errorf("EncodeType = %q %v", encoded, err)
+	}
+	for name, check := range map[string]struct {
+		hash func() (common.Hash, error)
+		want string
+	}{
+		"DomainSeparator": {data.DomainSeparator, "0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"},
+		"HashStruct": {func() (common.Hash, error) {
+			return data.HashStruct("Mail", data.Message)
+		}, "0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"},
+		"Hash": {data.Hash, "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"},
+	} {
+		if hash, err := check.hash(); err != nil || hash != common.HexToHash(check.want) {
+			t.Errorf("%s = %v %v, want %s", name, hash, err, check.want)
+		}
+	}
+
+	cow, _ := crypto.ToECDSA(crypto.Keccak256([]byte("cow")))
+	sig, err := web3.SignTypedData(cow, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b915621c"
+	if hexutil.Encode(sig) != want {
+		t.Errorf("SignTypedData = %x, want %s", sig, want)
+	}
+	from := common.HexToAddress("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826")
+	if signer, err := web3.RecoverTypedData(data, sig); err != nil || signer != from {
+		t.Errorf("RecoverTypedData = %v %v, want %v", signer, err, from)
+	}
+	sig[64] -= 27
+	if signer, err := web3.RecoverTypedData(data, sig); err != nil || signer != from {
+		t.Errorf("RecoverTypedData with v of 0 or 1 = %v %v, want %v", signer, err, from)
+	}
+
+	// the domain type is derived from the domain when it is missing
+	delete(data.Types, "EIP712Domain")
+	if hash, err := data.Hash(); err != nil || hash != common.HexToHash("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2") {
+		t.Errorf("Hash without EIP712Domain = %v %v", hash, err)
+	}
+}
+
+func TestTypedDataValues(t *testing.T) {
+	data := &web3.TypedData{
+		Types: web3.TypedDataTypes{
+			"Values": {
+				{Name: "small", Type: "int8"},
+				{Name: "large", Type: "uint256"},
+				{Name: "tag", Type: "bytes4"},
+				{Name: "blob", Type: "bytes"},
+				{Name: "flags", Type: "bool[2]"},
+				{Name: "grid", Type: "uint8[][]"},
+			},
+		},
+		PrimaryType: "Values",
+		Domain:      web3.TypedDataDomain{Name: "Values"},
+		Message: map[string]interface{}{
+			"small": -128,
+			"large": "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+			"tag":   hexutil.Bytes{1, 2, 3, 4},
+			"blob":  []byte("hello"),
+			"flags": []bool{true, false},
+			"grid":  []interface{}{[]interface{}{1, "0x2"}, []interface{}{json.Number("3e0")}},
+		},
+	}
+	if encoded, err := data.EncodeType("Values"); err != nil || encoded != "Values(int8 small,uint256 large,bytes4 tag,bytes blob,bool[2] flags,uint8[][] grid)" {
+		t.Errorf("EncodeType = %q %v", encoded, err)
+	}
+	hash, err := data.Hash()
+	if err != nil || hash != common.HexToHash("0x689ac7de0dfc0905401a705fd1028cb9824b7f6b29dc0eda92ee73f8e4fe90bb") {
+		t.Fatalf("Hash = %v %v", hash, err)
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// numbers decoded from JSON keep their precision
+	large := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	raw = []byte(strings.Replace(string(raw), `"0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"`, large.String(), 1))
+	decoded, err := web3.ParseTypedData(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.Message["large"].(json.Number); !ok {
+		t.Errorf("large decoded as %T, want json.Number", decoded.Message["large"])
+	}
+	if again, err := decoded.Hash(); err != nil || again != hash {
+		t.Errorf("Hash of the decoded document = %v %v, want %v", again, err, hash)
+	}
+
+	for _, test := range []struct {
+		field string
+		value interface{}
+	}{
+		{"small", -129},
+		{"small", 128},
+		{"large", new(big.Int).Lsh(big.NewInt(1), 256)},
+		{"large", -1},
+		{"tag", "0x0102"},
+		{"flags", []bool{true}},
+		{"flags", true},
+		{"grid", [][]int{{256}}},
+		// integer strings are decimal or 0x prefixed hexadecimal
+		{"grid", [][]string{{"0b1"}}},
+	} {
+		bad := *data
+		bad.Message = make(map[string]interface{})
+		for k, v := range data.Message {
+			bad.Message[k] = v
+		}
+		bad.Message[test.field] = test.value
+		if _, err := bad.Hash(); err == nil {
+			t.Errorf("Hash with %s = %v succeeded", test.field, test.value)
+		}
+	}
+	data.Message["extra"] = 1
+	if _, err := data.Hash(); err == nil {
+		t.Error("Hash with a field out of the type succeeded")
+	}
+	delete(data.Message, "extra")
+	for _, value := range []string{"0o7", "1_000", "0x"} {
+		data.Message["large"] = value
+		if _, err := data.Hash(); err == nil {
+			t.Errorf("Hash with large = %q succeeded", value)
+		}
+	}
+}
+
+func TestPermitTypedData(t *testing.T) {
+	chainID := big.NewInt(1)
+	owner := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	spender := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	usdc := common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	weth := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	deadline := big.NewInt(1_700_000_000)
+
+	permit := &web3.Permit{Owner: owner, Spender: spender, Value: big.NewInt(1_000_000), Nonce: big.NewInt(0), Deadline: deadline}
+	data := permit.TypedData(web3.PermitDomain("USD Coin", "2", chainID, usdc))
+	// DOMAIN_SEPARATOR() of USDC and of Permit2 on mainnet
+	if separator, err := data.DomainSeparator(); err != nil || separator != common.HexToHash("0x06c37168a7db5138defc7866392bb87a741f9b3d104deb5094588ce041cae335") {
+		t.Errorf("USDC domain separator = %v %v", separator, err)
+	}
+
+	details := web3.PermitDetails{Token: usdc, Amount: big.NewInt(5), Expiration: 1_700_000_000, Nonce: 3}
+	single := (&web3.PermitSingle{Details: details, Spender: spender, SigDeadline: deadline}).TypedData(chainID)
+	if encoded, err := single.EncodeType("PermitSingle"); err != nil || encoded != "PermitSingle(PermitDetails details,address spender,uint256 sigDeadline)PermitDetails(address token,uint160 amount,uint48 expiration,uint48 nonce)" {
+		t.Errorf("PermitSingle type = %q %v", encoded, err)
+	}
+	batch := (&web3.PermitBatch{Details: []web3.PermitDetails{details, {Token: weth, Amount: big.NewInt(7)}}, Spender: spender, SigDeadline: deadline}).TypedData(chainID)
+	permitted := web3.TokenPermissions{Token: weth, Amount: big.NewInt(9)}
+	transfer := (&web3.PermitTransferFrom{Permitted: permitted, Spender: spender, Nonce: big.NewInt(42), Deadline: deadline}).TypedData(chainID)
+	batchTransfer := (&web3.PermitBatchTransferFrom{Permitted: []web3.TokenPermissions{permitted, {Token: usdc}}, Spender: spender, Nonce: big.NewInt(43), Deadline: deadline}).TypedData(chainID)
+
+	if separator, err := single.DomainSeparator(); err != nil || separator != common.HexToHash("0x866a5aba21966af95d6c7ab78eb2b2fc913915c28be3b9aa07cc04ff903e3f28") {
+		t.Errorf("Permit2 domain separator = %v %v", separator, err)
+	}
+
+	key, _ := crypto.GenerateKey()
+	for _, test := range []struct {
+		data     *web3.TypedData
+		typeHash string // the type hash constant of the contract
+		hash     string
+	}{
+		{data, "0x6e71edae12b1b97f4d1f60370fef10105fa2faae0126114a169c64845d6126c9", "0xd62612a74861d91bc940dbc279a52e0b51c6a0177e5c1c58cb0077db11d26573"},
+		{single, "0xf3841cd1ff0085026a6327b620b67997ce40f282c88a8e905a7a5626e310f3d0", "0x4942f015cb4790fc32b5d6a287deaf238e37f81ebb33ab18e595c96228a94aad"},
+		{batch, "0xaf1b0d30d2cab0380e68f0689007e3254993c596f2fdd0aaa7f4d04f79440863", "0x233280f5f60577270e9a73a9ee9b71a27de6cc34ac83e5a8dfbe581a62488f2a"},
+		{transfer, "0x939c21a48a8dbe3a9a2404a1d46691e4d39f6583d6ec6b35714604c986d80106", "0x8131ab3dee949a95d016bec1b84f8106889fe798b36f449330354e814158c7c2"},
+		{batchTransfer, "0xfcf35f5ac6a2c28868dc44c302166470266239195f02b0ee408334829333b766", "0x89d44e86199fc69dc95003a65b925fdce7962ac67197ea0fb9b144e510cfc41f"},
+	} {
+		data := test.data
+		if typeHash, err := data.TypeHash(data.PrimaryType); err != nil || typeHash != common.HexToHash(test.typeHash) {
+			t.Errorf("%s type hash = %v %v, want %s", data.PrimaryType, typeHash, err, test.typeHash)
+		}
+		hash, err := data.Hash()
+		if err != nil || hash != common.HexToHash(test.hash) {
+			t.Errorf("%s hash = %v %v, want %s", data.PrimaryType, hash, err, test.hash)
+		}
+		sig, err := web3.SignTypedData(key, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signer, err := web3.RecoverTypedData(data, sig); err != nil || signer != crypto.PubkeyToAddress(key.PublicKey) {
+			t.Errorf("%s signer = %v %v", data.PrimaryType, signer, err)
+		}
+	}
+}
+
+// typedDataService is a stub wallet serving eth_signTypedData_v4.
+type typedDataService struct{ key *ecdsa.PrivateKey }
+
+func (s *typedDataService) SignTypedData_v4(addr common.Address, data *web3.TypedData) (hexutil.Bytes, error) {
+	return web3.SignTypedData(s.key, data)
+}
+
+func TestEthSignTypedDataV4(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &typedDataService{key}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	client := rpc.DialInProc(server)
+	t.Cleanup(client.Close)
+	w := web3.NewWeb3(client)
+
+	data, err := web3.ParseTypedData([]byte(mailTypedData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	sig, err := w.Eth.SignTypedDataV4(context.Background(), addr, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer, err := web3.RecoverTypedData(data, sig); err != nil || signer != addr {
+		t.Errorf("signer = %v %v, want %v", signer, err, addr)
+	}
+}
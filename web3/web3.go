@@ -1,31 +1,56 @@
 package web3
 
-import "github.com/ethereum/go-ethereum/rpc"
-
 type Web3 struct {
-	c        *rpc.Client
+	c        Client
+	gate     *capabilityGate
 	Admin    *Admin
+	Anvil    *Anvil
 	Clique   *Clique
 	Debug    *Debug
 	Eth      *Eth
+	Evm      *Evm
+	Hardhat  *Hardhat
 	Miner    *Miner
 	Net      *Net
+	Ots      *Ots
 	Personal *Personal
 	Rpc      *Rpc
+	Trace    *Trace
 	TxPool   *TxPool
 }
 
-func NewWeb3(c *rpc.Client) *Web3 {
+func NewWeb3(c Client) *Web3 {
 	web3 := &Web3{}
 	web3.c = c
-	web3.Admin = NewAdmin(c)
-	web3.Clique = NewClique(c)
-	web3.Debug = NewDebug(c)
-	web3.Eth = NewEth(c)
-	web3.Miner = NewMiner(c)
-	web3.Net = NewNet(c)
-	web3.Personal = NewPersonal(c)
-	web3.Rpc = NewRpc(c)
-	web3.TxPool = NewTxPool(c)
+	web3.gate = newCapabilityGate()
+	gc := &gatedClient{c, web3.gate}
+	web3.Admin = NewAdmin(gc)
+	web3.Admin.gate = web3.gate
+	web3.Anvil = NewAnvil(gc)
+	web3.Anvil.gate = web3.gate
+	web3.Clique = NewClique(gc)
+	web3.Clique.gate = web3.gate
+	web3.Debug = NewDebug(gc)
+	web3.Debug.gate = web3.gate
+	web3.Eth = NewEth(gc)
+	web3.Eth.gate = web3.gate
+	web3.Evm = NewEvm(gc)
+	web3.Evm.gate = web3.gate
+	web3.Hardhat = NewHardhat(gc)
+	web3.Hardhat.gate = web3.gate
+	web3.Miner = NewMiner(gc)
+	web3.Miner.gate = web3.gate
+	web3.Net = NewNet(gc)
+	web3.Net.gate = web3.gate
+	web3.Ots = NewOts(gc)
+	web3.Ots.gate = web3.gate
+	web3.Personal = NewPersonal(gc)
+	web3.Personal.gate = web3.gate
+	web3.Rpc = NewRpc(gc)
+	web3.Rpc.gate = web3.gate
+	web3.Trace = NewTrace(gc)
+	web3.Trace.gate = web3.gate
+	web3.TxPool = NewTxPool(gc)
+	web3.TxPool.gate = web3.gate
 	return web3
 }
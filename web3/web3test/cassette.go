@@ -0,0 +1,392 @@
+package web3test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+)
+
+// RecordEnv is the environment variable read by FromEnv. "all" records a new
+// cassette, "missing" records the requests the cassette lacks and a comma
+// separated list of methods, e.g. "eth_getLogs,eth_call", re-records those.
+const RecordEnv = "WEB3TEST_RECORD"
+
+// ErrNotRecorded is returned in replay mode for requests the cassette has no
+// response for. The test is failed as well.
+var ErrNotRecorded = errors.New("web3test: request not recorded")
+
+// Mode selects what a Cassette does with requests.
+type Mode int
+
+const (
+	// Replay serves every request from the cassette and fails the test on
+	// requests it has no response for. No upstream is needed.
+	Replay Mode = iota
+	// Record sends every request upstream and writes a new cassette.
+	Record
+	// RecordMissing serves recorded requests from the cassette, sends the
+	// others upstream and adds them to the cassette.
+	RecordMissing
+)
+
+// Interaction is a request/response pair, one line of a cassette file.
+type Interaction struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *RecordedError  `json:"error,omitempty"`
+}
+
+// RecordedError is a JSON-RPC error response kept in a cassette. It is
+// returned as is on replay, so that web3.ClassifyError sees the same code and
+// message as when it was recorded.
+type RecordedError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *RecordedError) Error() string          { return e.Message }
+func (e *RecordedError) ErrorCode() int         { return e.Code }
+func (e *RecordedError) ErrorData() interface{} { return e.Data }
+
+// Cassette records the traffic of a web3.Client into a JSONL file and replays
+// it offline. Requests are matched on their method and normalized params; a
+// request made several times is answered with its recorded responses in
+// order, the last one being repeated. It implements web3.Client.
+type Cassette struct {
+	t        testing.TB
+	path     string
+	upstream web3.Client
+	mode     Mode
+	rerecord map[string]bool
+
+	mu      sync.Mutex
+	entries []*Interaction            // in file order
+	byKey   map[string][]*Interaction // recorded responses per request
+	served  map[string]int            // responses handed out per request
+	dirty   bool
+}
+
+// CassetteOption configures NewCassette.
+type CassetteOption func(*Cassette)
+
+// WithMode sets the mode of the cassette, Replay by default.
+func WithMode(mode Mode) CassetteOption {
+	return func(c *Cassette) {
+		c.mode = mode
+	}
+}
+
+// WithRerecord drops the recorded responses to the given methods and records
+// them again, while everything else keeps being replayed.
+func WithRerecord(methods ...string) CassetteOption {
+	return func(c *Cassette) {
+		c.mode = RecordMissing
+		for _, method := range methods {
+			c.rerecord[method] = true
+		}
+	}
+}
+
+// FromEnv picks the mode from the RecordEnv environment variable, so that a
+// test replays in CI and is re-recorded with WEB3TEST_RECORD=all go test.
+func FromEnv() CassetteOption {
+	return func(c *Cassette) {
+		switch value := strings.TrimSpace(os.Getenv(RecordEnv)); value {
+		case "":
+		case "all":
+			WithMode(Record)(c)
+		case "missing":
+			WithMode(RecordMissing)(c)
+		default:
+			WithRerecord(strings.Split(value, ",")...)(c)
+		}
+	}
+}
+
+// NewCassette loads the cassette at path and returns a client serving the
+// requests from it. upstream is only used when recording and may be nil in
+// Replay mode. A recorded cassette is written when the test finishes.
+func NewCassette(t testing.TB, path string, upstream web3.Client, opts ...CassetteOption) *Cassette {
+	t.Helper()
+	c := &Cassette{
+		t:        t,
+		path:     path,
+		upstream: upstream,
+		rerecord: make(map[string]bool),
+		byKey:    make(map[string][]*Interaction),
+		served:   make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.mode != Replay && upstream == nil {
+		t.Fatalf("web3test: recording %s needs an upstream client", path)
+	}
+	if c.mode != Record {
+		if err := c.load(); err != nil {
+			t.Fatalf("web3test: load cassette: %v", err)
+		}
+	}
+	if c.mode != Replay {
+		t.Cleanup(func() {
+			if err := c.Save(); err != nil {
+				t.Errorf("web3test: save cassette: %v", err)
+			}
+		})
+	}
+	return c
+}
+
+// ReplayWeb3 returns a Web3 that answers every request from the cassette at
+// path, honouring RecordEnv. upstream may be nil when only replaying.
+func ReplayWeb3(t testing.TB, path string, upstream web3.Client) *web3.Web3 {
+	t.Helper()
+	return web3.NewWeb3(NewCassette(t, path, upstream, FromEnv()))
+}
+
+func (c *Cassette) load() error {
+	f, err := os.Open(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("%s:%d: %w", c.path, line, err)
+		}
+		if c.rerecord[entry.Method] {
+			c.dirty = true
+			continue
+		}
+		c.add(&entry)
+	}
+	return scanner.Err()
+}
+
+func (c *Cassette) add(entry *Interaction) {
+	key := entry.Method + " " + string(entry.Params)
+	c.entries = append(c.entries, entry)
+	c.byKey[key] = append(c.byKey[key], entry)
+}
+
+// Interactions returns the recorded request/response pairs in file order.
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := make([]Interaction, len(c.entries))
+	for i, entry := range c.entries {
+		result[i] = *entry
+	}
+	return result
+}
+
+// Save writes the cassette if anything was recorded. It is called when the
+// test finishes.
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, entry := range c.entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// lookup returns the next recorded response to the request, nil if there is
+// none.
+func (c *Cassette) lookup(method string, params json.RawMessage) *Interaction {
+	key := method + " " + string(params)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	recorded := c.byKey[key]
+	if len(recorded) == 0 {
+		return nil
+	}
+	i := c.served[key]
+	if i < len(recorded) {
+		c.served[key]++
+	} else {
+		i = len(recorded) - 1
+	}
+	return recorded[i]
+}
+
+func (c *Cassette) record(method string, params json.RawMessage, result json.RawMessage, err error) {
+	entry := &Interaction{Method: method, Params: params}
+	var rpcErr rpc.Error
+	switch {
+	case err == nil:
+		entry.Result = result
+		if len(entry.Result) == 0 {
+			entry.Result = json.RawMessage("null")
+		}
+	case errors.As(err, &rpcErr):
+		entry.Error = &RecordedError{Code: rpcErr.ErrorCode(), Message: rpcErr.Error()}
+		var dataErr rpc.DataError
+		if errors.As(err, &dataErr) {
+			entry.Error.Data = dataErr.ErrorData()
+		}
+	default:
+		// transport failures are not part of the node's answer
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := method + " " + string(params)
+	c.served[key]++
+	c.add(entry)
+	c.dirty = true
+}
+
+// answer decodes a recorded response into result.
+func answer(entry *Interaction, result interface{}) error {
+	if entry.Error != nil {
+		return entry.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(entry.Result, result)
+}
+
+func (c *Cassette) notRecorded(method string, params json.RawMessage) error {
+	c.t.Errorf("web3test: %s has no response in %s for %s %s", c.t.Name(), c.path, method, params)
+	return fmt.Errorf("%w: %s %s", ErrNotRecorded, method, params)
+}
+
+// CallContext answers the request from the cassette, or records it.
+func (c *Cassette) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	params, err := NormalizeParams(args)
+	if err != nil {
+		return err
+	}
+	if c.mode != Record {
+		if entry := c.lookup(method, params); entry != nil {
+			return answer(entry, result)
+		}
+		if c.mode == Replay {
+			return c.notRecorded(method, params)
+		}
+	}
+	var raw json.RawMessage
+	err = c.upstream.CallContext(ctx, &raw, method, args...)
+	c.record(method, params, raw, err)
+	if err != nil {
+		return err
+	}
+	if result == nil || len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, result)
+}
+
+// BatchCallContext answers every element from the cassette. Elements without
+// a recorded response are sent upstream as one batch when recording.
+func (c *Cassette) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	var missing []rpc.BatchElem
+	var index []int
+	params := make([]json.RawMessage, len(b))
+	for i := range b {
+		p, err := NormalizeParams(b[i].Args)
+		if err != nil {
+			return err
+		}
+		params[i] = p
+		if c.mode != Record {
+			if entry := c.lookup(b[i].Method, p); entry != nil {
+				b[i].Error = answer(entry, b[i].Result)
+				continue
+			}
+			if c.mode == Replay {
+				b[i].Error = c.notRecorded(b[i].Method, p)
+				continue
+			}
+		}
+		missing = append(missing, rpc.BatchElem{Method: b[i].Method, Args: b[i].Args, Result: new(json.RawMessage)})
+		index = append(index, i)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if err := c.upstream.BatchCallContext(ctx, missing); err != nil {
+		return err
+	}
+	for j, i := range index {
+		raw := *missing[j].Result.(*json.RawMessage)
+		c.record(b[i].Method, params[i], raw, missing[j].Error)
+		b[i].Error = missing[j].Error
+		if b[i].Error == nil && b[i].Result != nil && len(raw) > 0 {
+			b[i].Error = json.Unmarshal(raw, b[i].Result)
+		}
+	}
+	return nil
+}
+
+// NormalizeParams encodes request params the way a cassette matches them:
+// as a JSON array with sorted object keys, lower-cased hex strings and no
+// trailing nulls, which the node treats like omitted optional arguments.
+func NormalizeParams(args []interface{}) (json.RawMessage, error) {
+	if args == nil {
+		args = []interface{}{}
+	}
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var params []interface{}
+	if err := dec.Decode(&params); err != nil {
+		return nil, err
+	}
+	for len(params) > 0 && params[len(params)-1] == nil {
+		params = params[:len(params)-1]
+	}
+	trimmed, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	return web3.CanonicalJSON(trimmed)
+}
@@ -0,0 +1,128 @@
+package web3test_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/moonfdd/web3-go/web3"
+	"github.com/moonfdd/web3-go/web3/web3test"
+)
+
+// recordingTB records the failures of a cassette instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *recordingTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func TestCassetteRecordReplay(t *testing.T) {
+	node := web3test.New(t)
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	node.SendTx(t, &to, nil, nil)
+	node.Commit(t)
+	path := filepath.Join(t.TempDir(), "testdata", "dev.jsonl")
+	ctx := context.Background()
+
+	query := func(w *web3.Web3) (uint64, map[string]interface{}, error) {
+		number, err := w.Eth.BlockNumber(ctx)
+		if err != nil {
+			return 0, nil, err
+		}
+		block, err := w.Eth.GetBlockByNumber(ctx, rpc.BlockNumber(number), false)
+		if err != nil {
+			return 0, nil, err
+		}
+		_, err = w.Eth.GetFilterLogs(ctx, "0x1")
+		return number, block, err
+	}
+
+	t.Run("record", func(t *testing.T) {
+		cassette := web3test.NewCassette(t, path, node.Client, web3test.WithMode(web3test.Record))
+		number, block, err := query(web3.NewWeb3(cassette))
+		if number != 1 || block["number"] != "0x1" || !errors.Is(err, web3.ErrFilterNotFound) {
+			t.Fatalf("query = %d %v %v", number, block["number"], err)
+		}
+		if n := len(cassette.Interactions()); n != 3 {
+			t.Fatalf("recorded %d interactions, want 3", n)
+		}
+	})
+
+	node.SendTx(t, &to, nil, nil)
+	node.Commit(t)
+
+	t.Run("replay", func(t *testing.T) {
+		w := web3.NewWeb3(web3test.NewCassette(t, path, nil))
+		number, block, err := query(w)
+		if number != 1 || block["number"] != "0x1" {
+			t.Fatalf("replayed %d %v, want the recorded block 1", number, block["number"])
+		}
+		if !errors.Is(err, web3.ErrFilterNotFound) {
+			t.Errorf("replayed error = %v, want ErrFilterNotFound", err)
+		}
+	})
+
+	t.Run("unmatched", func(t *testing.T) {
+		tb := &recordingTB{TB: t}
+		w := web3.NewWeb3(web3test.NewCassette(tb, path, nil))
+		if _, err := w.Eth.ChainID(ctx); !errors.Is(err, web3test.ErrNotRecorded) {
+			t.Errorf("ChainID error = %v, want ErrNotRecorded", err)
+		}
+		if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], "eth_chainId") {
+			t.Errorf("test failures = %q", tb.errors)
+		}
+	})
+
+	t.Run("rerecord", func(t *testing.T) {
+		cassette := web3test.NewCassette(t, path, node.Client, web3test.WithRerecord("eth_blockNumber"))
+		w := web3.NewWeb3(cassette)
+		if number, err := w.Eth.BlockNumber(ctx); err != nil || number != 2 {
+			t.Fatalf("re-recorded BlockNumber = %d %v, want 2", number, err)
+		}
+		// block 1 is still served from the cassette, block 2 is recorded
+		for _, n := range []rpc.BlockNumber{1, 2} {
+			if block, err := w.Eth.GetBlockByNumber(ctx, n, false); err != nil || block["number"] != fmt.Sprintf("0x%d", n) {
+				t.Fatalf("GetBlockByNumber(%d) = %v %v", n, block["number"], err)
+			}
+		}
+		if n := len(cassette.Interactions()); n != 4 {
+			t.Errorf("cassette has %d interactions, want 4", n)
+		}
+	})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 4 {
+		t.Errorf("cassette file has %d lines, want 4:\n%s", lines, data)
+	}
+}
+
+func TestNormalizeParams(t *testing.T) {
+	addr := common.HexToAddress("0x00000000000000000000000000000000000000AA")
+	a, err := web3test.NormalizeParams([]interface{}{map[string]interface{}{"to": addr, "data": "0xABCD"}, "latest", nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := web3test.NormalizeParams([]interface{}{map[string]interface{}{"data": "0xabcd", "to": strings.ToLower(addr.Hex())}, "latest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a) != string(b) {
+		t.Errorf("NormalizeParams differs: %s != %s", a, b)
+	}
+	if empty, _ := web3test.NormalizeParams(nil); string(empty) != "[]" {
+		t.Errorf("NormalizeParams(nil) = %s, want []", empty)
+	}
+}
@@ -0,0 +1,297 @@
+// Package web3test starts in-process go-ethereum nodes for tests of code
+// built on package web3. The nodes need no network access: the chain lives in
+// a temporary directory and the Web3 talks to the node over an in-process
+// RPC connection with every namespace enabled.
+package web3test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/catalyst"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	// register the native and js tracers for debug_trace*
+	_ "github.com/ethereum/go-ethereum/eth/tracers/js"
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
+
+	"github.com/moonfdd/web3-go/web3"
+)
+
+// Password unlocks the funded developer account in the node keystore.
+const Password = "web3test"
+
+// Node is an in-process go-ethereum node.
+type Node struct {
+	Stack    *node.Node
+	Eth      *eth.Ethereum
+	Client   *rpc.Client
+	Web3     *web3.Web3
+	KeyStore *keystore.KeyStore
+
+	// Key and Account are the developer account. It is funded in the genesis
+	// block, stored in the keystore, unlocked and used as etherbase.
+	Key     *ecdsa.PrivateKey
+	Account common.Address
+
+	beacon *catalyst.SimulatedBeacon // nil for clique nodes
+}
+
+type config struct {
+	clique bool
+	period uint64
+	alloc  types.GenesisAlloc
+}
+
+// Option configures New.
+type Option func(*config)
+
+// WithClique starts a proof-of-authority chain sealed by the developer
+// account every period seconds instead of a proof-of-stake dev chain. The
+// clique namespace is only served by such nodes.
+func WithClique(period uint64) Option {
+	return func(c *config) {
+		c.clique = true
+		c.period = period
+	}
+}
+
+// WithAlloc adds accounts to the genesis block.
+func WithAlloc(alloc types.GenesisAlloc) Option {
+	return func(c *config) {
+		for addr, account := range alloc {
+			c.alloc[addr] = account
+		}
+	}
+}
+
+// New starts a node with admin, clique (see WithClique), debug, eth, miner,
+// net, personal, rpc, txpool and web3 enabled. The node is stopped when the
+// test finishes. By default it runs a proof-of-stake dev chain that only
+// produces blocks on Commit.
+func New(t testing.TB, opts ...Option) *Node {
+	t.Helper()
+	cfg := &config{alloc: make(types.GenesisAlloc)}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	stack, err := node.New(&node.Config{
+		DataDir:        t.TempDir(),
+		P2P:            p2p.Config{NoDiscovery: true, MaxPeers: 0, ListenAddr: ""},
+		EnablePersonal: true,
+	})
+	if err != nil {
+		t.Fatalf("web3test: create node: %v", err)
+	}
+	t.Cleanup(func() { stack.Close() })
+
+	ks := keystore.NewKeyStore(stack.KeyStoreDir(), keystore.LightScryptN, keystore.LightScryptP)
+	stack.AccountManager().AddBackend(ks)
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := ks.ImportECDSA(key, Password)
+	if err != nil {
+		t.Fatalf("web3test: import developer key: %v", err)
+	}
+	if err := ks.Unlock(account, Password); err != nil {
+		t.Fatal(err)
+	}
+
+	ethcfg := ethconfig.Defaults
+	ethcfg.SyncMode = downloader.FullSync
+	ethcfg.Preimages = true // debug_dumpBlock and friends need them
+	ethcfg.Miner.Etherbase = account.Address
+	ethcfg.Miner.GasPrice = big.NewInt(1)
+	if cfg.clique {
+		chainConfig := *params.AllCliqueProtocolChanges
+		chainConfig.Clique = &params.CliqueConfig{Period: cfg.period, Epoch: 30000}
+		extra := make([]byte, 32+common.AddressLength+crypto.SignatureLength)
+		copy(extra[32:], account.Address.Bytes())
+		ethcfg.Genesis = &core.Genesis{
+			Config:     &chainConfig,
+			ExtraData:  extra,
+			GasLimit:   ethconfig.Defaults.Miner.GasCeil,
+			Difficulty: big.NewInt(1),
+			BaseFee:    big.NewInt(params.InitialBaseFee),
+			Alloc:      cfg.alloc,
+		}
+	} else {
+		ethcfg.Genesis = core.DeveloperGenesisBlock(ethconfig.Defaults.Miner.GasCeil, nil)
+		for addr, alloc := range cfg.alloc {
+			ethcfg.Genesis.Alloc[addr] = alloc
+		}
+	}
+	ethcfg.Genesis.Alloc[account.Address] = types.Account{Balance: new(big.Int).Mul(big.NewInt(1_000_000), big.NewInt(params.Ether))}
+
+	backend, err := eth.New(stack, &ethcfg)
+	if err != nil {
+		t.Fatalf("web3test: create eth service: %v", err)
+	}
+	stack.RegisterAPIs(tracers.APIs(backend.APIBackend))
+	filterSystem := filters.NewFilterSystem(backend.APIBackend, filters.Config{})
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "eth",
+		Service:   filters.NewFilterAPI(filterSystem, false),
+	}})
+	if err := stack.Start(); err != nil {
+		t.Fatalf("web3test: start node: %v", err)
+	}
+
+	n := &Node{
+		Stack:    stack,
+		Eth:      backend,
+		KeyStore: ks,
+		Key:      key,
+		Account:  account.Address,
+	}
+	if cfg.clique {
+		if err := backend.StartMining(); err != nil {
+			t.Fatalf("web3test: start sealing: %v", err)
+		}
+	} else {
+		beacon, err := catalyst.NewSimulatedBeacon(0, backend)
+		if err != nil {
+			t.Fatalf("web3test: create beacon: %v", err)
+		}
+		if err := beacon.Fork(backend.BlockChain().GetCanonicalHash(0)); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { beacon.Stop() })
+		n.beacon = beacon
+	}
+	n.Client = stack.Attach()
+	t.Cleanup(n.Client.Close)
+	n.Web3 = web3.NewWeb3(n.Client)
+	return n
+}
+
+// NewWeb3 starts a node with New and returns its Web3.
+func NewWeb3(t testing.TB, opts ...Option) *web3.Web3 {
+	t.Helper()
+	return New(t, opts...).Web3
+}
+
+// Commit makes the node produce a block containing the pending transactions
+// and returns its number once the transaction pool and the transaction index
+// have caught up with it. Proof-of-stake nodes seal the block right away,
+// clique nodes are waited for.
+func (n *Node) Commit(t testing.TB) uint64 {
+	t.Helper()
+	head := n.Eth.BlockChain().CurrentBlock().Number.Uint64()
+	if n.beacon != nil {
+		n.beacon.Commit()
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		if number := n.Eth.BlockChain().CurrentBlock().Number.Uint64(); number > head {
+			// let the pool drop the included transactions before returning
+			if err := n.Eth.TxPool().Sync(); err != nil {
+				t.Fatalf("web3test: sync transaction pool: %v", err)
+			}
+			n.waitTxIndex(t, deadline)
+			return number
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("web3test: no block after %d", head)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitTxIndex waits for the transaction indexer to finish. Until it does,
+// the node refuses to look up transactions it does not find.
+func (n *Node) waitTxIndex(t testing.TB, deadline time.Time) {
+	t.Helper()
+	for {
+		progress, err := n.Eth.BlockChain().TxIndexProgress()
+		if err != nil {
+			t.Fatalf("web3test: transaction index progress: %v", err)
+		}
+		if progress.Done() {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("web3test: transaction index behind by %d blocks", progress.Remaining)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Wallet returns the keystore wallet of the developer account.
+func (n *Node) Wallet() accounts.Wallet {
+	wallets := n.KeyStore.Wallets()
+	for _, w := range wallets {
+		if w.Contains(accounts.Account{Address: n.Account}) {
+			return w
+		}
+	}
+	return nil
+}
+
+// SendTx signs a dynamic fee transaction from the developer account with the
+// next pending nonce, submits it and returns it. It is not committed.
+func (n *Node) SendTx(t testing.TB, to *common.Address, value *big.Int, data []byte) *types.Transaction {
+	t.Helper()
+	nonce := n.Eth.TxPool().Nonce(n.Account)
+	head := n.Eth.BlockChain().CurrentBlock()
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   n.Eth.BlockChain().Config().ChainID,
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(params.GWei),
+		GasFeeCap: new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), big.NewInt(params.GWei)),
+		Gas:       1_000_000,
+		To:        to,
+		Value:     value,
+		Data:      data,
+	})
+	signed, err := types.SignTx(tx, types.LatestSigner(n.Eth.BlockChain().Config()), n.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.Web3.Eth.SendRawTransaction(context.Background(), raw); err != nil {
+		t.Fatalf("web3test: send transaction: %v", err)
+	}
+	return signed
+}
+
+// WithSnapshot runs fn against an evm_snapshot of the dev chain behind w, such
+// as Anvil or Hardhat, and always reverts to it afterwards, also when fn fails
+// the test.
+func WithSnapshot(t testing.TB, w *web3.Web3, fn func()) {
+	t.Helper()
+	ctx := context.Background()
+	id, err := w.Evm.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("web3test: snapshot: %v", err)
+	}
+	defer func() {
+		if reverted, err := w.Evm.Revert(ctx, id); err != nil || !reverted {
+			t.Errorf("web3test: revert to snapshot %v: %v %v", id, reverted, err)
+		}
+	}()
+	fn()
+}